@@ -4,9 +4,13 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/json"
+	"io"
+	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 )
 
 const (
@@ -19,6 +23,12 @@ const (
 	treeInsertSQL = "INSERT INTO " + treeTableName + "(id, name, pid, depth, lft, rgt) VALUES("
 )
 
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
 type category struct {
 	Status int32  `json:"status,omitempty"`
 	Leaf   int32  `json:"leaf,omitempty"`
@@ -34,36 +44,107 @@ type category struct {
 }
 
 func loadTree() *category {
-	file, err := os.Open(dataFile)
+	return loadTreeFile(dataFile)
+}
+
+// loadTreeFile reads one category per line, tolerating a leading UTF-8 BOM. Categories are linked to their
+// parents after all are read, so children may come first; a missing parent or a cycle of categories, which
+// would recurse forever when numbering, panics naming the categories.
+func loadTreeFile(name string) *category {
+	file, err := os.Open(name)
 	if err != nil {
 		log.Fatal("os.Open error: ", err)
 	}
 	defer file.Close()
 
-	// put new node into catMap and Sub filed of its parent
+	var cats []*category
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Bytes()
+		if lineNo == 1 {
+			if bytes.HasPrefix(line, utf16LEBOM) || bytes.HasPrefix(line, utf16BEBOM) {
+				log.Fatalf("%s: file is UTF-16 encoded, re-save as UTF-8", name)
+			}
+			line = bytes.TrimPrefix(line, utf8BOM)
+		}
+		var cat category
+		err := json.Unmarshal(line, &cat)
+		if err != nil {
+			log.Printf("json.Unmarshal error: %s:%d: %v", name, lineNo, err)
+		}
+		cats = append(cats, &cat)
+	}
+
+	// put nodes into catMap and Sub filed of their parents, later ones of a sid take its children
 	catMap := make(map[int64]*category)
+	for _, cat := range cats {
+		catMap[cat.SID] = cat
+	}
 	var root category
 	root.SID = 0
 	catMap[0] = &root
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		var cat category
-		err := json.Unmarshal([]byte(scanner.Text()), &cat)
-		if err != nil {
-			log.Print("json.Unmarshal error: ", err)
+	for _, cat := range cats {
+		p, ok := catMap[cat.PID]
+		if !ok {
+			log.Panicf("%s: parent %d of category %d does not exist", name, cat.PID, cat.SID)
 		}
-		catMap[cat.SID] = &cat
-		p := catMap[cat.PID]
 		if p.Sub == nil {
 			p.Sub = make([]*category, 0)
 		}
-		p.Sub = append(p.Sub, &cat)
+		p.Sub = append(p.Sub, cat)
+	}
+	if cycle := findCycle(&root, cats, catMap); cycle != nil {
+		log.Panicf("%s: category cycle: %s", name, cycleIDs(cycle))
 	}
 	log.Printf("got %d categories", len(catMap))
 	return &root
 }
 
+// findCycle returns a cycle of categories which are not below root, each being the parent of the next and the
+// last the first again, or nil when all categories are below root
+func findCycle(root *category, cats []*category, catMap map[int64]*category) []*category {
+	below := make(map[*category]bool)
+	stack := []*category{root}
+	for len(stack) > 0 {
+		c := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if below[c] {
+			continue
+		}
+		below[c] = true
+		stack = append(stack, c.Sub...)
+	}
+	for _, cat := range cats {
+		if below[cat] {
+			continue
+		}
+		// parents of a category out of the tree never reach root, so following them runs into a cycle
+		var chain []*category
+		at := make(map[*category]int)
+		for c := cat; ; c = catMap[c.PID] {
+			if i, ok := at[c]; ok {
+				cycle := append([]*category(nil), chain[i:]...)
+				for l, r := 0, len(cycle)-1; l < r; l, r = l+1, r-1 {
+					cycle[l], cycle[r] = cycle[r], cycle[l]
+				}
+				return append(cycle, cycle[0])
+			}
+			at[c] = len(chain)
+			chain = append(chain, c)
+		}
+	}
+	return nil
+}
+
+// cycleIDs lists the sids of a cycle, e.g. "5 -> 7 -> 5"
+func cycleIDs(cycle []*category) string {
+	ids := make([]string, len(cycle))
+	for i, c := range cycle {
+		ids[i] = i64toa(c.SID)
+	}
+	return strings.Join(ids, " -> ")
+}
+
 // number the nodes according a tree traversal
 func assignKeys(tree *category) {
 	start := int32(0)
@@ -83,24 +164,59 @@ func indexTree(root *category, start int32) int32 {
 
 // generate database table initial inserting sql queries
 func genSQLFile(root *category) {
-	info, err := os.Create(infoFile)
-	if err != nil {
-		log.Panic("os.Create error: ", err)
-	}
-	defer info.Close()
+	writeFilesAtomic([]string{infoFile, treeFile}, func(w []io.Writer) {
+		for _, p := range root.Sub {
+			genSQL(w[0], w[1], p, 1)
+		}
+	})
+}
 
-	tree, err := os.Create(treeFile)
-	if err != nil {
-		log.Panic("os.Create error: ", err)
+// writeFilesAtomic writes files through temp files in their directories, which are renamed over the
+// targets only after all writes succeed. write panics on failure, which removes all temp files.
+func writeFilesAtomic(names []string, write func(w []io.Writer)) {
+	tmps := make([]*os.File, 0, len(names))
+	committed := false
+	defer func() {
+		if !committed {
+			for _, tmp := range tmps {
+				tmp.Close()
+				os.Remove(tmp.Name())
+			}
+		}
+	}()
+
+	w := make([]io.Writer, 0, len(names))
+	for _, name := range names {
+		tmp, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name)+".tmp")
+		if err != nil {
+			log.Panic("ioutil.TempFile error: ", err)
+		}
+		tmps = append(tmps, tmp)
+		if err = tmp.Chmod(0644); err != nil {
+			log.Panic("Chmod error: ", err)
+		}
+		w = append(w, tmp)
 	}
-	defer tree.Close()
 
-	for _, p := range root.Sub {
-		genSQL(info, tree, p, 1)
+	write(w)
+
+	for _, tmp := range tmps {
+		if err := tmp.Sync(); err != nil {
+			log.Panic("Sync error: ", err)
+		}
+		if err := tmp.Close(); err != nil {
+			log.Panic("Close error: ", err)
+		}
+	}
+	for i, tmp := range tmps {
+		if err := os.Rename(tmp.Name(), names[i]); err != nil {
+			log.Panic("os.Rename error: ", err)
+		}
 	}
+	committed = true
 }
 
-func genSQL(info, tree *os.File, cat *category, depth int32) {
+func genSQL(info, tree io.Writer, cat *category, depth int32) {
 	sql := bytes.NewBufferString(infoInsertSQL)
 	sql.WriteString(i64toa(cat.SID))
 	sql.WriteString(", '")
@@ -113,7 +229,7 @@ func genSQL(info, tree *os.File, cat *category, depth int32) {
 
 	_, err := info.Write(sql.Bytes())
 	if err != nil {
-		log.Fatal("info.Write error: ", err, " when writting category: ", *cat)
+		log.Panic("info.Write error: ", err, " when writting category: ", *cat)
 	}
 
 	sql.Reset()
@@ -133,7 +249,7 @@ func genSQL(info, tree *os.File, cat *category, depth int32) {
 
 	_, err = tree.Write(sql.Bytes())
 	if err != nil {
-		log.Fatal("tree.Write error: ", err, " when writting category: ", *cat)
+		log.Panic("tree.Write error: ", err, " when writting category: ", *cat)
 	}
 
 	for _, sub := range cat.Sub {
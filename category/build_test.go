@@ -1,6 +1,11 @@
 package category
 
 import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -9,3 +14,49 @@ func TestBuild(t *testing.T) {
 	assignKeys(tree)
 	genSQLFile(tree)
 }
+
+func TestLoadBOM(t *testing.T) {
+	tree := loadTreeFile("./testdata/bom.json")
+	if len(tree.Sub) != 1 || tree.Sub[0].SID != 40 || len(tree.Sub[0].Sub) != 1 {
+		t.Error(tree.Sub)
+	}
+}
+
+func TestWriteFilesAtomicFailure(t *testing.T) {
+	dir := t.TempDir()
+	names := []string{filepath.Join(dir, "info.sql"), filepath.Join(dir, "tree.sql")}
+	func() {
+		defer func() { recover() }()
+		writeFilesAtomic(names, func(w []io.Writer) {
+			io.WriteString(w[0], "INSERT INTO category_info")
+			panic("write error")
+		})
+	}()
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Error("files left:", len(files))
+	}
+
+	writeFilesAtomic(names, func(w []io.Writer) {
+		io.WriteString(w[0], "info")
+		io.WriteString(w[1], "tree")
+	})
+	if data, err := ioutil.ReadFile(names[1]); err != nil || string(data) != "tree" {
+		t.Error(string(data), err)
+	}
+}
+
+func TestLoadCycle(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "category cycle: 9 -> 8 -> 9") {
+			t.Error(r)
+		}
+	}()
+	loadTreeFile("./testdata/cycle.json")
+}
+
+func TestLoadUnordered(t *testing.T) {
+	tree := loadTreeFile("./testdata/unordered.json")
+	if len(tree.Sub) != 1 || tree.Sub[0].SID != 5 || len(tree.Sub[0].Sub) != 1 || tree.Sub[0].Sub[0].SID != 7 {
+		t.Error(tree.Sub)
+	}
+}
@@ -0,0 +1,108 @@
+package nested
+
+import (
+	"database/sql"
+	"strconv"
+)
+
+// Accessors of the optional columns generated with -columns of division, by the ID of the node, its code. A
+// node which does not exist is sql.ErrNoRows, a column not generated is the error of the database, and NULL is
+// "" or not ok.
+
+// nodeColumn reads one column of node, "" for NULL
+func nodeColumn(db *sql.DB, id int64, column string) (string, error) {
+	values, err := GetNodeColumns(db, id, column)
+	if err != nil {
+		return "", err
+	}
+	if values == nil {
+		return "", sql.ErrNoRows
+	}
+	return values[column], nil
+}
+
+// ShortName of node without the suffix of its level, e.g. 北京 of 北京市, from short_name
+func ShortName(db *sql.DB, id int64) (string, error) {
+	return nodeColumn(db, id, "short_name")
+}
+
+// Postcode of node from postcode
+func Postcode(db *sql.DB, id int64) (string, error) {
+	return nodeColumn(db, id, "postcode")
+}
+
+// DialingCode of node, its own or inherited from the nearest ancestor with one, e.g. 010, from dialing_code
+func DialingCode(db *sql.DB, id int64) (string, error) {
+	return nodeColumn(db, id, "dialing_code")
+}
+
+// Centroid of node from lng and lat, not ok if it has none
+func Centroid(db *sql.DB, id int64) (lng, lat float64, ok bool, err error) {
+	values, err := GetNodeColumns(db, id, "lng", "lat")
+	if err != nil {
+		return 0, 0, false, err
+	}
+	if values == nil {
+		return 0, 0, false, sql.ErrNoRows
+	}
+	if values["lng"] == "" || values["lat"] == "" {
+		return 0, 0, false, nil
+	}
+	if lng, err = strconv.ParseFloat(values["lng"], 64); err != nil {
+		return 0, 0, false, err
+	}
+	if lat, err = strconv.ParseFloat(values["lat"], 64); err != nil {
+		return 0, 0, false, err
+	}
+	return lng, lat, true, nil
+}
+
+// IsLeaf tells whether node has no children in the table, by its rows rather than its keys, which may be spaced
+// with -key-step, so it needs no is_leaf column
+func IsLeaf(db *sql.DB, id int64) (bool, error) {
+	values, err := GetNodeColumns(db, id)
+	if err != nil {
+		return false, err
+	}
+	if values == nil {
+		return false, sql.ErrNoRows
+	}
+	rows, err := query(db, "SELECT id FROM "+tblName+" WHERE pid=? LIMIT 1", id)
+	if err != nil {
+		return false, err
+	}
+	return len(rows) == 0, nil
+}
+
+// EnglishName of node, translated or in pinyin, from en_name
+func EnglishName(db *sql.DB, id int64) (string, error) {
+	return nodeColumn(db, id, "en_name")
+}
+
+// Abbreviation of the province of node, e.g. 京, from abbr
+func Abbreviation(db *sql.DB, id int64) (string, error) {
+	return nodeColumn(db, id, "abbr")
+}
+
+// DivisionType of node, e.g. province or district, from division_type
+func DivisionType(db *sql.DB, id int64) (string, error) {
+	return nodeColumn(db, id, "division_type")
+}
+
+// TraditionalName of node in traditional characters, from name_trad
+func TraditionalName(db *sql.DB, id int64) (string, error) {
+	return nodeColumn(db, id, "name_trad")
+}
+
+// AncestorCodes of node at the province, city and area levels, itself at its own level and 0 at those below, from
+// province_code, city_code and area_code
+func AncestorCodes(db *sql.DB, id int64) (province, city, area int64, err error) {
+	values, err := GetNodeColumns(db, id, "province_code", "city_code", "area_code")
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if values == nil {
+		return 0, 0, 0, sql.ErrNoRows
+	}
+	return atoi64(values["province_code"]), atoi64(values["city_code"]), atoi64(values["area_code"]), nil
+}
@@ -0,0 +1,230 @@
+package nested
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// table is a database/sql driver answering SELECT columns FROM table WHERE column=? from its rows, nil for NULL,
+// ignoring anything after the condition
+type table struct {
+	rows    []map[string]interface{}
+	queries []string
+}
+
+var testTable = &table{}
+
+func init() {
+	sql.Register("table", testTable)
+}
+
+func (t *table) Open(name string) (driver.Conn, error) {
+	return &tableConn{t}, nil
+}
+
+type tableConn struct {
+	t *table
+}
+
+func (c *tableConn) Prepare(query string) (driver.Stmt, error) {
+	return &tableStmt{c.t, query}, nil
+}
+
+func (c *tableConn) Close() error {
+	return nil
+}
+
+func (c *tableConn) Begin() (driver.Tx, error) {
+	return nil, driver.ErrSkip
+}
+
+type tableStmt struct {
+	t     *table
+	query string
+}
+
+func (s *tableStmt) Close() error {
+	return nil
+}
+
+func (s *tableStmt) NumInput() int {
+	return strings.Count(s.query, "?")
+}
+
+func (s *tableStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, driver.ErrSkip
+}
+
+func (s *tableStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.t.queries = append(s.t.queries, s.query)
+	columns := strings.Split(s.query[len("SELECT "):strings.Index(s.query, " FROM ")], ", ")
+	where := s.query[strings.Index(s.query, " WHERE ")+len(" WHERE "):]
+	where = where[:strings.Index(where, "=?")]
+	rows := &tableRows{columns: columns}
+	for _, row := range s.t.rows {
+		if fmt.Sprint(row[where]) != fmt.Sprint(args[0]) {
+			continue
+		}
+		values := make([]driver.Value, len(columns))
+		for i, c := range columns {
+			v, ok := row[c]
+			if !ok {
+				return nil, &unknownColumn{c}
+			}
+			values[i] = v
+		}
+		rows.rows = append(rows.rows, values)
+	}
+	return rows, nil
+}
+
+type unknownColumn struct {
+	name string
+}
+
+func (e *unknownColumn) Error() string {
+	return "unknown column " + e.name
+}
+
+type tableRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *tableRows) Columns() []string {
+	return r.columns
+}
+
+func (r *tableRows) Close() error {
+	return nil
+}
+
+func (r *tableRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func useTable(t *testing.T) *sql.DB {
+	old := tblName
+	SetTableName("nested")
+	t.Cleanup(func() { SetTableName(old) })
+	testTable.queries = nil
+	testTable.rows = []map[string]interface{}{
+		{"id": int64(110000), "node": "北京市", "pid": int64(0), "lft": int64(10), "rgt": int64(40),
+			"short_name": "北京", "postcode": "100000", "dialing_code": "010", "lng": 116.4, "lat": 39.9,
+			"en_name": "Beijing", "abbr": "京", "division_type": "municipality", "name_trad": "北京市",
+			"province_code": int64(110000), "city_code": nil, "area_code": nil},
+		{"id": int64(110101), "node": "东城区", "pid": int64(110000), "lft": int64(20), "rgt": int64(30),
+			"short_name": "东城", "postcode": nil, "dialing_code": "010", "lng": nil, "lat": nil,
+			"province_code": int64(110000), "city_code": int64(110100), "area_code": int64(110101)},
+	}
+	db, err := sql.Open("table", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestGetNodeColumns(t *testing.T) {
+	db := useTable(t)
+	values, err := GetNodeColumns(db, 110000, "id", "short_name", "node", "short_name")
+	if err != nil || len(values) != 3 || values["id"] != "110000" || values["short_name"] != "北京" ||
+		values["node"] != "北京市" {
+		t.Error(values, err)
+	}
+	if q := testTable.queries[0]; q != "SELECT id, short_name, node FROM nested WHERE id=?" {
+		t.Error(q)
+	}
+	if values, err := GetNodeColumns(db, 120000, "short_name"); values != nil || err != nil {
+		t.Error("missing node:", values, err)
+	}
+	if values, err := GetNodeColumns(db, 110000); err != nil || values == nil || len(values) != 0 {
+		t.Error("no columns:", values, err)
+	}
+
+	testTable.queries = nil
+	for _, c := range []string{"", "Name", "name; DROP TABLE nested", "id, node", strings.Repeat("a", 65)} {
+		if _, err := GetNodeColumns(db, 110000, "short_name", c); err == nil {
+			t.Errorf("%q read", c)
+		}
+	}
+	if len(testTable.queries) != 0 {
+		t.Error("queried with bad names:", testTable.queries)
+	}
+}
+
+func TestIsColumnName(t *testing.T) {
+	for s, want := range map[string]bool{"short_name": true, "lng": true, "id2": true, "": false, "2id": false,
+		"name; DROP TABLE nested": false, "Name": false} {
+		if isColumnName(s) != want {
+			t.Error(s)
+		}
+	}
+}
+
+func TestColumnAccessors(t *testing.T) {
+	db := useTable(t)
+	accessors := []struct {
+		get  func(*sql.DB, int64) (string, error)
+		want string
+	}{
+		{ShortName, "北京"},
+		{Postcode, "100000"},
+		{DialingCode, "010"},
+		{EnglishName, "Beijing"},
+		{Abbreviation, "京"},
+		{DivisionType, "municipality"},
+		{TraditionalName, "北京市"},
+	}
+	for _, a := range accessors {
+		if got, err := a.get(db, 110000); got != a.want || err != nil {
+			t.Error(got, err, "want", a.want)
+		}
+		if _, err := a.get(db, 120000); err != sql.ErrNoRows {
+			t.Error("missing node:", err)
+		}
+	}
+	if got, err := Postcode(db, 110101); got != "" || err != nil {
+		t.Error("NULL:", got, err)
+	}
+	if _, err := EnglishName(db, 110101); err == nil {
+		t.Error("column not generated read")
+	}
+
+	if lng, lat, ok, err := Centroid(db, 110000); lng != 116.4 || lat != 39.9 || !ok || err != nil {
+		t.Error(lng, lat, ok, err)
+	}
+	if _, _, ok, err := Centroid(db, 110101); ok || err != nil {
+		t.Error("NULL centroid:", ok, err)
+	}
+	if _, _, _, err := Centroid(db, 120000); err != sql.ErrNoRows {
+		t.Error("missing node:", err)
+	}
+
+	// keys spaced as with -key-step
+	if leaf, err := IsLeaf(db, 110000); leaf || err != nil {
+		t.Error(110000, leaf, err)
+	}
+	if leaf, err := IsLeaf(db, 110101); !leaf || err != nil {
+		t.Error(110101, leaf, err)
+	}
+	if _, err := IsLeaf(db, 120000); err != sql.ErrNoRows {
+		t.Error("missing node:", err)
+	}
+
+	if p, c, a, err := AncestorCodes(db, 110000); p != 110000 || c != 0 || a != 0 || err != nil {
+		t.Error(p, c, a, err)
+	}
+	if p, c, a, err := AncestorCodes(db, 110101); p != 110000 || c != 110100 || a != 110101 || err != nil {
+		t.Error(p, c, a, err)
+	}
+}
@@ -0,0 +1,14 @@
+-- create table
+IF OBJECT_ID(N'[nested]', N'U') IS NULL
+CREATE TABLE [nested](
+[id] BIGINT NOT NULL,
+[node] NVARCHAR(64) NOT NULL,
+[pid] BIGINT NOT NULL,
+[depth] INT NOT NULL,
+[lft] INT NOT NULL,
+[rgt] INT NOT NULL,
+  PRIMARY KEY ([id]),
+  INDEX [depth_index] ([depth]),
+  INDEX [lft_index] ([lft]),
+  INDEX [rgt_index] ([rgt]));
+GO
@@ -0,0 +1,13 @@
+-- create table
+CREATE TABLE "nested"(
+"id" NUMBER(19) NOT NULL,
+"node" NVARCHAR2(64) NOT NULL,
+"pid" NUMBER(19) NOT NULL,
+"depth" NUMBER(10) NOT NULL,
+"lft" NUMBER(10) NOT NULL,
+"rgt" NUMBER(10) NOT NULL,
+  PRIMARY KEY ("id"));
+CREATE INDEX "depth_index" ON "nested" ("depth");
+CREATE INDEX "lft_index" ON "nested" ("lft");
+CREATE INDEX "rgt_index" ON "nested" ("rgt");
+COMMENT ON TABLE "nested" IS 'nested sets model';
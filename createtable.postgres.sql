@@ -0,0 +1,13 @@
+-- create table
+CREATE TABLE IF NOT EXISTS "nested"(
+"id" BIGINT NOT NULL,
+"node" VARCHAR(64) NOT NULL,
+"pid" BIGINT NOT NULL,
+"depth" INT NOT NULL,
+"lft" INT NOT NULL,
+"rgt" INT NOT NULL,
+  PRIMARY KEY ("id"));
+CREATE INDEX IF NOT EXISTS "depth_index" ON "nested" ("depth");
+CREATE INDEX IF NOT EXISTS "lft_index" ON "nested" ("lft");
+CREATE INDEX IF NOT EXISTS "rgt_index" ON "nested" ("rgt");
+COMMENT ON TABLE "nested" IS 'nested sets model';
@@ -0,0 +1,11 @@
+-- create table
+CREATE TABLE IF NOT EXISTS "nested"(
+"id" INTEGER NOT NULL PRIMARY KEY,
+"node" TEXT NOT NULL,
+"pid" INTEGER NOT NULL,
+"depth" INTEGER NOT NULL,
+"lft" INTEGER NOT NULL,
+"rgt" INTEGER NOT NULL);
+CREATE INDEX IF NOT EXISTS "depth_index" ON "nested" ("depth");
+CREATE INDEX IF NOT EXISTS "lft_index" ON "nested" ("lft");
+CREATE INDEX IF NOT EXISTS "rgt_index" ON "nested" ("rgt");
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+)
+
+// provinceAbbrs are the standard one-character abbreviations of provinces by code, as on license plates
+var provinceAbbrs = map[string]string{
+	"110000": "京", "120000": "津", "130000": "冀", "140000": "晋", "150000": "蒙",
+	"210000": "辽", "220000": "吉", "230000": "黑",
+	"310000": "沪", "320000": "苏", "330000": "浙", "340000": "皖", "350000": "闽", "360000": "赣", "370000": "鲁",
+	"410000": "豫", "420000": "鄂", "430000": "湘", "440000": "粤", "450000": "桂", "460000": "琼",
+	"500000": "渝", "510000": "川", "520000": "贵", "530000": "云", "540000": "藏",
+	"610000": "陕", "620000": "甘", "630000": "青", "640000": "宁", "650000": "新",
+	"710000": "台", "810000": "港", "820000": "澳",
+}
+
+// abbreviation returns the abbreviation of the province of path, for the province itself or, with
+// -abbr-inherit, for any node below it too
+func abbreviation(path []*Area) string {
+	if len(path) > 1 && !abbrInherit {
+		return ""
+	}
+	return provinceAbbrs[path[0].Code]
+}
+
+// checkAbbreviations warns about provinces without a known abbreviation, which get an empty one
+func checkAbbreviations(trees []*Area) {
+	var unknown []string
+	for _, p := range trees {
+		if _, ok := provinceAbbrs[p.Code]; !ok {
+			unknown = append(unknown, p.Code)
+		}
+	}
+	if len(unknown) > 0 {
+		logger.Warn("no abbreviation of provinces", "codes", strings.Join(unknown, ", "))
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestProvinceAbbrs(t *testing.T) {
+	if len(provinceAbbrs) != 34 {
+		t.Error("abbreviations:", len(provinceAbbrs))
+	}
+	seen := make(map[string]string)
+	for code, abbr := range provinceAbbrs {
+		if codeLevel(code) != 1 || len([]rune(abbr)) != 1 {
+			t.Error(code, abbr)
+		}
+		if other, ok := seen[abbr]; ok {
+			t.Error(abbr, "of both", code, other)
+		}
+		seen[abbr] = code
+	}
+	for code, want := range map[string]string{"150000": "蒙", "310000": "沪", "440000": "粤", "520000": "贵", "820000": "澳"} {
+		if got := provinceAbbrs[code]; got != want {
+			t.Error(code, got)
+		}
+	}
+}
+
+func TestAbbrColumn(t *testing.T) {
+	cases := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"-columns", "abbr"}, []string{
+			"VALUES(110000, '北京市', 0, 1, 1, 10, '京');",
+			"VALUES(130100, '石家庄市', 130000, 2, 12, 17, '');",
+		}},
+		{[]string{"-columns", "abbr", "-abbr-inherit"}, []string{
+			"VALUES(110101001000, '东华门街道办事处', 110101, 4, 4, 5, '京');",
+			"VALUES(130100, '石家庄市', 130000, 2, 12, 17, '冀');",
+		}},
+	}
+	for _, c := range cases {
+		out := usePaths(t, "./testdata/mini")
+		var stderr bytes.Buffer
+		if code := run(c.args, &stderr); code != exitOK {
+			t.Fatal(c.args, "exit code:", code, stderr.String())
+		}
+		data, err := ioutil.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range c.want {
+			if !strings.Contains(string(data), want) {
+				t.Error(c.args, want)
+			}
+		}
+	}
+
+	if got := abbreviation([]*Area{{Code: "990000"}}); got != "" {
+		t.Error("unknown province:", got)
+	}
+}
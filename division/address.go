@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// matchName tells how many bytes at the start of text name a, by its name, or by its short name followed by
+// any administrative suffix, e.g. 杭州, 杭州市 or 广西省 for 广西壮族自治区. It returns 0 if text does not
+// start with a. Leaves need the suffix after their short name, as addresses go on with roads named like their
+// streets, e.g. 景山前街 of 景山街道.
+func matchName(a *Area, text string) int {
+	if name := nodeName(a); strings.HasPrefix(text, name) {
+		return len(name)
+	}
+	short := shortName(nodeName(a))
+	if short == nodeName(a) || !strings.HasPrefix(text, short) {
+		return 0
+	}
+	n := len(short)
+	for _, r := range suffixRules {
+		if strings.HasPrefix(text[n:], r.suffix) {
+			return n + len(r.suffix)
+		}
+	}
+	if len(a.SubAreas) == 0 {
+		return 0
+	}
+	return n
+}
+
+// matchNames returns the node of areas naming the longest start of text, the first one of the same length,
+// and the bytes it names
+func matchNames(areas []*Area, text string) (*Area, int) {
+	var best *Area
+	length := 0
+	for _, a := range areas {
+		if n := matchName(a, text); n > length {
+			best, length = a, n
+		}
+	}
+	return best, length
+}
+
+// ParseAddress segments an address against the trees: from the roots down, the start of the text names a child
+// of the node matched last, or else a grandchild, which passes over placeholders like 市辖区 and addresses
+// leaving out their province. It returns the nodes from the root down to the deepest one matched, those passed
+// over included, and the rest of the text, e.g. 文三路 for 浙江省杭州市西湖区文三路. Nothing matched returns
+// nil and the whole text.
+func (x *areaIndex) ParseAddress(address string) ([]*Area, string) {
+	text := strings.TrimSpace(address)
+	var last *Area
+	level := x.roots
+	for len(level) > 0 && text != "" {
+		a, n := matchNames(level, text)
+		if a == nil {
+			var below []*Area
+			for _, b := range level {
+				below = append(below, b.SubAreas...)
+			}
+			a, n = matchNames(below, text)
+		}
+		if a == nil {
+			break
+		}
+		last, text = a, strings.TrimLeft(text[n:], " ")
+		level = a.SubAreas
+	}
+	if last == nil {
+		return nil, text
+	}
+	return x.Path(last), text
+}
+
+// runAddress parses addresses, one an argument or a line of stdin, and prints the code and full name of the
+// deepest node matched and the rest of the address, separated by tabs
+func runAddress(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("division address", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	from := fs.String("from", dataDir, "data `directory` or generated sql file")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: division address [-from dir|file] [address...]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	addresses := fs.Args()
+	if len(addresses) == 0 {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintln(stderr, "division address:", err)
+			return exitIO
+		}
+		addresses = strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	}
+	trees, err := loadTrees(*from)
+	if err != nil {
+		fmt.Fprintln(stderr, "division address:", err)
+		return exitCode(err)
+	}
+	x := newAreaIndex(trees)
+	code := exitOK
+	for _, address := range addresses {
+		address = strings.TrimSuffix(address, "\r")
+		if !utf8.ValidString(address) {
+			fmt.Fprintf(stderr, "division address: invalid UTF-8 in %q\n", address)
+			code = exitData
+			continue
+		}
+		path, rest := x.ParseAddress(address)
+		if path == nil {
+			fmt.Fprintf(stdout, "\t\t%s\n", rest)
+			code = exitData
+			continue
+		}
+		fmt.Fprintf(stdout, "%s\t%s\t%s\n", path[len(path)-1].Code, fullName(path, nodeName), rest)
+	}
+	return code
+}
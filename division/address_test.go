@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseAddress(t *testing.T) {
+	trees, err := loadTrees("./testdata/mini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := newAreaIndex(trees)
+	for address, want := range map[string][2]string{
+		"北京市市辖区东城区东华门街道办事处南池子大街1号": {"110000 110100 110101 110101001000", "南池子大街1号"},
+		"北京市东城区景山街道景山前街4号":         {"110000 110100 110101 110101002000", "景山前街4号"},
+		"北京东城景山前街":                 {"110000 110100 110101", "景山前街"},
+		"石家庄市长安区建北街道":              {"130000 130100 130102 130102001000", ""},
+		" 河北省 石家庄 长安区 中山东路":        {"130000 130100 130102", "中山东路"},
+		"河北省唐山市":                   {"130000", "唐山市"},
+		"天津市和平区":                   {"", "天津市和平区"},
+	} {
+		path, rest := x.ParseAddress(address)
+		var codes []string
+		for _, a := range path {
+			codes = append(codes, a.Code)
+		}
+		if got := strings.Join(codes, " "); got != want[0] || rest != want[1] {
+			t.Errorf("%s: %q, %q", address, got, rest)
+		}
+	}
+
+	out := useStdout(t)
+	defer func(r io.Reader) { stdin = r }(stdin)
+	stdin = strings.NewReader("北京市东城区景山前街4号\n天津市和平区\n")
+	var stderr bytes.Buffer
+	if code := run([]string{"address", "-from", "./testdata/mini"}, &stderr); code != exitData {
+		t.Error("exit code with an address not matched:", code, stderr.String())
+	}
+	if want := "110101\t北京市市辖区东城区\t景山前街4号\n\t\t天津市和平区\n"; out.String() != want {
+		t.Error(out.String())
+	}
+	out.Reset()
+	if code := run([]string{"address", "-from", "./testdata/mini", "河北省石家庄市"}, &stderr); code != exitOK {
+		t.Error("exit code:", code, stderr.String())
+	}
+	if out.String() != "130100\t河北省石家庄市\t\n" {
+		t.Error(out.String())
+	}
+}
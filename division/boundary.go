@@ -0,0 +1,352 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// geometryFormats are the values of -geometry-format, how the boundary column is written
+var geometryFormats = []string{"geojson", "wkt", "mysql", "postgis"}
+
+func isGeometryFormat(format string) bool {
+	for _, f := range geometryFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// boundary locates the GeoJSON feature of a node in a boundary file, features are read one at a time while
+// writing so huge geometries are never held all in memory
+type boundary struct {
+	file       string
+	start, end int64
+}
+
+// boundaryFiles lists the GeoJSON files of -boundaries, a file or a directory of .json and .geojson files
+func boundaryFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if !e.IsDir() && (ext == ".json" || ext == ".geojson") {
+			names = append(names, filepath.Join(path, e.Name()))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// featureHead is what indexing decodes of a feature, the code is the id or the code or adcode property
+type featureHead struct {
+	ID         interface{}            `json:"id"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+func (f featureHead) code() string {
+	for _, v := range []interface{}{f.Properties["code"], f.Properties["adcode"], f.ID} {
+		switch v := v.(type) {
+		case string:
+			return strings.TrimSpace(v)
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	return ""
+}
+
+// indexBoundaries records where the feature of each code is in a FeatureCollection or a single Feature file
+func indexBoundaries(name string, index map[string]boundary) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	base := int64(0)
+	if b, _ := r.Peek(len(utf8BOM)); bytes.Equal(b, utf8BOM) {
+		r.Discard(len(utf8BOM))
+		base = int64(len(utf8BOM))
+	}
+
+	add := func(code string, start, end int64) error {
+		if code == "" {
+			return dataErrorf("%s: feature at offset %d without code", name, start)
+		}
+		if b, ok := index[code]; ok {
+			return dataErrorf("%s: duplicate feature %s, also in %s", name, code, b.file)
+		}
+		index[code] = boundary{name, start, end}
+		return nil
+	}
+	fail := func(err error) error {
+		return dataErrorf("%s: %v", name, err)
+	}
+
+	dec := json.NewDecoder(r)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return dataErrorf("%s: GeoJSON object expected", name)
+	}
+	var single featureHead
+	isFeature := false
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return fail(err)
+		}
+		switch key {
+		case "features":
+			if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+				return dataErrorf("%s: features array expected", name)
+			}
+			for dec.More() {
+				start := base + dec.InputOffset()
+				var head featureHead
+				if err := dec.Decode(&head); err != nil {
+					return fail(err)
+				}
+				if err := add(head.code(), start, base+dec.InputOffset()); err != nil {
+					return err
+				}
+			}
+			if _, err := dec.Token(); err != nil {
+				return fail(err)
+			}
+		case "type":
+			var typ string
+			if err := dec.Decode(&typ); err != nil {
+				return fail(err)
+			}
+			isFeature = typ == "Feature"
+		case "id":
+			err = dec.Decode(&single.ID)
+		case "properties":
+			err = dec.Decode(&single.Properties)
+		default:
+			var skip json.RawMessage
+			err = dec.Decode(&skip)
+		}
+		if err != nil {
+			return fail(err)
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return fail(err)
+	}
+	if isFeature {
+		return add(single.code(), 0, base+dec.InputOffset())
+	}
+	return nil
+}
+
+// loadBoundaries indexes the features of -boundaries and attaches them to the trees
+func loadBoundaries(trees []*Area) error {
+	names, err := boundaryFiles(boundariesPath)
+	if err != nil {
+		return err
+	}
+	index := make(map[string]boundary)
+	for _, name := range names {
+		if err := indexBoundaries(name, index); err != nil {
+			return err
+		}
+	}
+	codes := make([]string, 0, len(index))
+	for code := range index {
+		codes = append(codes, code)
+	}
+	attach(trees, "boundaries", codes, func(area *Area) bool {
+		b, ok := index[area.Code]
+		if ok {
+			area.Boundary = &b
+		}
+		return ok
+	})
+	return nil
+}
+
+// boundaryReader reads features of the boundary files, which are kept open while writing. Provinces written
+// at once with -jobs share it.
+type boundaryReader struct {
+	mu    sync.Mutex
+	files map[string]*os.File
+}
+
+var boundaries boundaryReader
+
+// geometry reads the geometry of the feature at b, which is null for features without one
+func (br *boundaryReader) geometry(b *boundary) (json.RawMessage, error) {
+	f, err := br.open(b.file)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, b.end-b.start)
+	if _, err := f.ReadAt(data, b.start); err != nil && err != io.EOF {
+		return nil, err
+	}
+	// the feature may follow the separating comma
+	if i := bytes.IndexByte(data, '{'); i > 0 {
+		data = data[i:]
+	}
+	var feature struct {
+		Geometry json.RawMessage `json:"geometry"`
+	}
+	if err := json.Unmarshal(data, &feature); err != nil {
+		return nil, dataErrorf("%s: %v", b.file, err)
+	}
+	return feature.Geometry, nil
+}
+
+// open returns the open file of name, opening it on first use
+func (br *boundaryReader) open(name string) (*os.File, error) {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	if f, ok := br.files[name]; ok {
+		return f, nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if br.files == nil {
+		br.files = make(map[string]*os.File)
+	}
+	br.files[name] = f
+	return f, nil
+}
+
+func (br *boundaryReader) close() {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	for _, f := range br.files {
+		f.Close()
+	}
+	br.files = nil
+}
+
+// boundarySQL is the boundary of a node as a SQL value in -geometry-format, or "" for nodes without one
+func boundarySQL(b *boundary) (string, error) {
+	if b == nil {
+		return "", nil
+	}
+	raw, err := boundaries.geometry(b)
+	if err != nil || len(raw) == 0 || string(raw) == "null" {
+		return "", err
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil {
+		return "", dataErrorf("%s: %v", b.file, err)
+	}
+	geojson := "'" + strings.Replace(compact.String(), "'", "''", -1) + "'"
+	switch geometryFormat {
+	case "wkt":
+		wkt, err := toWKT(raw)
+		if err != nil {
+			return "", dataErrorf("%s: %v", b.file, err)
+		}
+		return "'" + wkt + "'", nil
+	case "mysql":
+		return "ST_GeomFromGeoJSON(" + geojson + ")", nil
+	case "postgis":
+		return "ST_SetSRID(ST_GeomFromGeoJSON(" + geojson + "), 4326)", nil
+	}
+	return geojson, nil
+}
+
+// toWKT converts a GeoJSON geometry to well-known text, e.g. POLYGON ((0 0, 1 0, 1 1, 0 0))
+func toWKT(raw json.RawMessage) (string, error) {
+	var g struct {
+		Type        string            `json:"type"`
+		Coordinates json.RawMessage   `json:"coordinates"`
+		Geometries  []json.RawMessage `json:"geometries"`
+	}
+	if err := json.Unmarshal(raw, &g); err != nil {
+		return "", err
+	}
+	if g.Type == "GeometryCollection" {
+		parts := make([]string, 0, len(g.Geometries))
+		for _, sub := range g.Geometries {
+			wkt, err := toWKT(sub)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, wkt)
+		}
+		return "GEOMETRYCOLLECTION (" + strings.Join(parts, ", ") + ")", nil
+	}
+
+	// nesting of coordinate arrays by type
+	depths := map[string]int{"Point": 1, "MultiPoint": 2, "LineString": 2, "MultiLineString": 3, "Polygon": 3, "MultiPolygon": 4}
+	depth, ok := depths[g.Type]
+	if !ok {
+		return "", fmt.Errorf("unknown geometry type %q", g.Type)
+	}
+	var coords interface{}
+	if err := json.Unmarshal(g.Coordinates, &coords); err != nil {
+		return "", err
+	}
+	text, err := wktCoords(coords, depth)
+	if err != nil {
+		return "", err
+	}
+	if g.Type == "MultiPoint" {
+		// points of a multipoint are parenthesized each
+		text = "((" + strings.Replace(text[1:len(text)-1], ", ", "), (", -1) + "))"
+	}
+	return strings.ToUpper(g.Type) + " " + text, nil
+}
+
+// wktCoords formats nested coordinate arrays, a position is "x y" and arrays of them are parenthesized
+func wktCoords(v interface{}, depth int) (string, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return "", fmt.Errorf("coordinates array expected")
+	}
+	if depth == 1 {
+		if len(list) < 2 {
+			return "", fmt.Errorf("position with %d numbers", len(list))
+		}
+		nums := make([]string, len(list))
+		for i, n := range list {
+			f, ok := n.(float64)
+			if !ok {
+				return "", fmt.Errorf("number expected in position")
+			}
+			nums[i] = strconv.FormatFloat(f, 'f', -1, 64)
+		}
+		return "(" + strings.Join(nums, " ") + ")", nil
+	}
+	parts := make([]string, len(list))
+	for i, sub := range list {
+		text, err := wktCoords(sub, depth-1)
+		if err != nil {
+			return "", err
+		}
+		if depth == 2 {
+			text = text[1 : len(text)-1] // positions of a line are not parenthesized
+		}
+		parts[i] = text
+	}
+	return "(" + strings.Join(parts, ", ") + ")", nil
+}
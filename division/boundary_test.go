@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestBoundaryColumn(t *testing.T) {
+	cases := []struct {
+		format string
+		want   []string
+	}{
+		{"geojson", []string{
+			`VALUES(110000, '北京市', 0, 1, 1, 10, '{"type":"Polygon","coordinates":[[[116,39.5],[117,39.5],[117,40.5],[116,39.5]]]}');`,
+			"VALUES(130000, '河北省', 0, 1, 11, 18, NULL);",
+			"VALUES(110100, '市辖区', 110000, 2, 2, 9, NULL);",
+		}},
+		{"wkt", []string{
+			"VALUES(110000, '北京市', 0, 1, 1, 10, 'POLYGON ((116 39.5, 117 39.5, 117 40.5, 116 39.5))');",
+			"VALUES(130100, '石家庄市', 130000, 2, 12, 17, 'MULTIPOLYGON (((114 38, 115 38, 115 39, 114 38)))');",
+		}},
+		{"mysql", []string{
+			`VALUES(130100, '石家庄市', 130000, 2, 12, 17, ST_GeomFromGeoJSON('{"type":"MultiPolygon","coordinates":[[[[114,38],[115,38],[115,39],[114,38]]]]}'));`,
+		}},
+		{"postgis", []string{
+			`VALUES(130100, '石家庄市', 130000, 2, 12, 17, ST_SetSRID(ST_GeomFromGeoJSON('{"type":"MultiPolygon","coordinates":[[[[114,38],[115,38],[115,39],[114,38]]]]}'), 4326));`,
+		}},
+	}
+	for _, c := range cases {
+		out := usePaths(t, "./testdata/mini")
+		var stderr bytes.Buffer
+		if code := run([]string{"-boundaries", "./testdata/boundaries", "-geometry-format", c.format}, &stderr); code != exitOK {
+			t.Fatal(c.format, "exit code:", code, stderr.String())
+		}
+		data, err := ioutil.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range c.want {
+			if !strings.Contains(string(data), want) {
+				t.Error(c.format, want)
+			}
+		}
+	}
+
+	usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run([]string{"-boundaries", "./testdata/boundaries", "-geometry-format", "kml"}, &stderr); code != exitUsage {
+		t.Error("exit code:", code)
+	}
+}
+
+func TestIndexBoundaries(t *testing.T) {
+	index := make(map[string]boundary)
+	if err := indexBoundaries("./testdata/boundaries/collection.geojson", index); err != nil {
+		t.Fatal(err)
+	}
+	if len(index) != 3 || index["990000"].file == "" {
+		t.Fatal(index)
+	}
+	err := indexBoundaries("./testdata/boundaries/collection.geojson", index)
+	if _, ok := err.(*dataError); !ok || !strings.Contains(err.Error(), "duplicate feature") {
+		t.Error(err)
+	}
+}
+
+func TestToWKT(t *testing.T) {
+	cases := map[string]string{
+		`{"type":"Point","coordinates":[1,2]}`:                                              "POINT (1 2)",
+		`{"type":"MultiPoint","coordinates":[[1,2],[3,4]]}`:                                 "MULTIPOINT ((1 2), (3 4))",
+		`{"type":"LineString","coordinates":[[1,2],[3,4.5]]}`:                               "LINESTRING (1 2, 3 4.5)",
+		`{"type":"MultiLineString","coordinates":[[[1,2],[3,4]]]}`:                          "MULTILINESTRING ((1 2, 3 4))",
+		`{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}`:                      "POLYGON ((0 0, 1 0, 1 1, 0 0))",
+		`{"type":"MultiPolygon","coordinates":[[[[0,0],[1,0],[0,0]]]]}`:                     "MULTIPOLYGON (((0 0, 1 0, 0 0)))",
+		`{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[1,2]}]}`: "GEOMETRYCOLLECTION (POINT (1 2))",
+	}
+	for geojson, want := range cases {
+		got, err := toWKT(json.RawMessage(geojson))
+		if err != nil || got != want {
+			t.Errorf("%s: %q %v", geojson, got, err)
+		}
+	}
+	if _, err := toWKT(json.RawMessage(`{"type":"Circle","coordinates":[1,2]}`)); err == nil {
+		t.Error("unknown type accepted")
+	}
+}
@@ -1,254 +1,1180 @@
 // This program generates division.sql.
-// It can be invoked by running `go run build.go` in current directory.
+// It can be invoked by running `go run .` in current directory, or `go run . <subcommand>` for:
+//   - diff: compare two versions of the data,
+//   - migrate: write sql migrating a table from one version to another,
+//   - history: record versions in a history table,
+//   - explain: show the keys of a division and how they nest,
+//   - verify: check the nested sets of a sql file,
+//   - rebuild: recompute the keys of a table from its ids and pids,
+//   - lint: check the input files without building,
+//   - fixture: extract a small subset of the input files for tests,
+//   - stats: count nodes by level, of one version or two side by side,
+//   - check-update: tell whether the upstream data changed,
+//   - fetch: download the input files from the official statistics pages,
+//   - pick: find a code by picking a province, a city and so on,
+//   - search: find codes by names as users type them,
+//   - address: split addresses into the divisions they name and the rest,
+//   - locate: find the divisions containing GPS points from their boundaries,
+//   - serve: answer REST requests of divisions and searches over HTTP, and GraphQL queries with -graphql,
+//   - serve-grpc: answer them over gRPC, in binaries built with -tags grpc.
 
 package main
 
 import (
+	"bufio"
 	"bytes"
-	"encoding/json"
-	"io/ioutil"
-	"log"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
 	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
 	"runtime/debug"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BionStt/nested"
 )
 
 const (
-	tblName       = "nested"
-	provincesFile = "./data/provinces.json"
-	citiesFile    = "./data/cities.json"
-	areasFile     = "./data/areas.json"
-	streetsFile   = "./data/streets.json"
-	sqlFile       = "./division.sql"
-	insertPrefix  = "INSERT INTO " + tblName + "(id, node, pid, depth, lft, rgt) VALUES("
+	provincesFile = "provinces.json"
+	citiesFile    = "cities.json"
+	areasFile     = "areas.json"
+	streetsFile   = "streets.json"
+	villagesFile  = "villages.json"
+)
+
+var (
+	tblName           = "nested"
+	dataDir           = "./data"
+	sqlFile           = "./division.sql"
+	strict            bool
+	selfCheck         bool
+	columnList        string
+	postcodesFile     string
+	dialingCodesFile  string
+	centroidsFile     string
+	translationsFile  string
+	tradOverridesFile string
+	boundariesPath    string
+	geometryFormat    = "geojson"
+	geojsonDir        string
+	geojsonSplit      = "province"
+	municipalityCity  = "placeholder"
+	coordPrecision    int
+	dropPlaceholders  bool
+	placeholderNames  = "市辖区,县"
+	idPathSep         = ","
+	idPathSelf        bool
+	levelNames        = "省,市,区县,街道,村居"
+	fullNameSep       string
+	abbrInherit       bool
+	keyStep           int32 = 1
+
+	fullNameSkipPlaceholders bool
 )
 
 func main() {
+	os.Exit(run(os.Args[1:], os.Stderr))
+}
+
+// subcommands are run by their name as the first argument, instead of generating the sql file
+var subcommands = map[string]func(args []string, stderr io.Writer) int{
+	"diff":         runDiff,
+	"migrate":      runMigrate,
+	"history":      runHistory,
+	"explain":      runExplain,
+	"verify":       runVerify,
+	"rebuild":      runRebuild,
+	"lint":         runLint,
+	"fixture":      runFixture,
+	"stats":        runStats,
+	"check-update": runCheckUpdate,
+	"fetch":        runFetch,
+	"pick":         runPick,
+	"search":       runSearch,
+	"address":      runAddress,
+	"locate":       runLocate,
+	"serve":        runServe,
+}
+
+// stdin and stdout are read and written by subcommands, replaced in tests
+var (
+	stdin  io.Reader = os.Stdin
+	stdout io.Writer = os.Stdout
+)
+
+// tableName is what -table takes, a plain identifier as table names are written unquoted
+var tableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// tableFlag sets tblName with -table, commands restore it when they are done
+type tableFlag struct{}
+
+func (tableFlag) String() string {
+	return tblName
+}
+
+func (tableFlag) Set(s string) error {
+	if !tableName.MatchString(s) {
+		return fmt.Errorf("%q is not a plain table name", s)
+	}
+	tblName = s
+	return nil
+}
+
+// run runs a subcommand or generates the sql file, and returns the process exit code
+func run(args []string, stderr io.Writer) int {
+	if len(args) > 0 {
+		if cmd, ok := subcommands[args[0]]; ok {
+			return cmd(args[1:], stderr)
+		}
+	}
+	return runGenerate(args, stderr)
+}
+
+// runGenerate generates the sql file and returns the process exit code, failures are summarized on stderr in
+// one line
+func runGenerate(args []string, stderr io.Writer) (code int) {
+	fs := flag.NewFlagSet("division", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	defer func(dir, out, table string, specs []codeSpec) {
+		dataDir, sqlFile, tblName, codeSpecs = dir, out, table, specs
+	}(dataDir, sqlFile, tblName, codeSpecs)
+	fs.StringVar(&dataDir, "data-dir", dataDir, "`directory` of the input files")
+	fs.StringVar(&dataRelease, "data-release", "", "`tag` of the source repository, or base URL, to download the input files of into -data-dir")
+	fs.StringVar(&dataSHA256, "data-sha256", "", "`checksum` the input files must have, a release matching it is not downloaded again")
+	fs.StringVar(&sqlFile, "out", sqlFile, "sql `file` to generate, or JSON, CSV, YAML, Go, protobuf or MessagePack of -format")
+	fs.StringVar(&outputFormat, "format", "sql", "content of the -out file, "+strings.Join(outputFormats, " or "))
+	fs.StringVar(&goPackage, "go-package", "divisions", "`package` of the Go file of -format go")
+	fs.Var(tableFlag{}, "table", "`name` of the nested sets table")
+	fs.Var(codeLevelsFlag{}, "code-levels", "comma separated `prefix:width` of the codes of each level, the digits of the level and those above and the padded width")
+	verbose := fs.Bool("v", false, "log debug messages too")
+	quiet := fs.Bool("q", false, "log only warnings and errors")
+	logFormat := fs.String("log-format", "text", "log as "+strings.Join(logFormats, " or ")+" on stderr")
+	fs.BoolVar(&strict, "strict", false, "fail on invalid records instead of fixing and reporting them")
+	fs.BoolVar(&lenient, "lenient", false, "drop records of bad codes or missing parents instead of failing the run")
+	fs.StringVar(&rejectsFile, "rejects", "", "JSON lines `file` to write the dropped records into")
+	fs.StringVar(&reportFile, "report", "", "JSON `file` to write the invalid records found into, also when they fail the run")
+	fs.BoolVar(&selfCheck, "self-check", true, "parse the generated sql and compare it with the tree before finalizing")
+	step := fs.Int("key-step", 1, "distance of lft and rgt keys, leaving keys free between them for nodes inserted later")
+	fs.StringVar(&columnList, "columns", "", "comma separated optional columns: "+columnNames(optionalColumns))
+	fs.StringVar(&postcodesFile, "postcodes", "", "CSV or JSON `file` of postcodes by code, emitted as postcode column")
+	fs.StringVar(&dialingCodesFile, "dialing-codes", "", "CSV or JSON `file` of long-distance dialing codes by code, inherited by descendants, emitted as dialing_code column")
+	fs.StringVar(&centroidsFile, "centroids", "", "CSV or JSON `file` of centroid coordinates by code, emitted as lng and lat columns")
+	fs.IntVar(&coordPrecision, "coord-precision", 6, "decimals of coordinates")
+	fs.BoolVar(&dropPlaceholders, "drop-placeholders", false, "remove placeholder nodes named in -placeholder-names and hang their children on the grandparent")
+	fs.StringVar(&placeholderNames, "placeholder-names", "市辖区,县", "comma separated exact names of placeholder nodes")
+	fs.StringVar(&idPathSep, "id-path-sep", ",", "separator of ids in id_path column")
+	fs.BoolVar(&idPathSelf, "id-path-self", true, "end id_path with the id of the node itself, otherwise with its parent")
+	fs.StringVar(&levelNames, "level-names", "省,市,区县,街道,村居", "comma separated names of levels from the top, emitted as level_name column")
+	fs.StringVar(&fullNameSep, "full-name-sep", "", "separator of names in full_name column")
+	fs.BoolVar(&fullNameSkipPlaceholders, "full-name-skip-placeholders", false, "leave names in -placeholder-names out of full_name")
+	fs.StringVar(&translationsFile, "translations", "", "CSV or JSON `file` of English names by code, emitted as en_name column with generated names for the rest")
+	fs.BoolVar(&abbrInherit, "abbr-inherit", false, "fill abbr column of nodes below provinces with the abbreviation of their province")
+	fs.StringVar(&tradOverridesFile, "trad-overrides", "", "CSV or JSON `file` of traditional names by code, taking precedence over conversion in name_trad column")
+	fs.StringVar(&boundariesPath, "boundaries", "", "GeoJSON `file or directory` of boundaries with codes in properties, emitted as boundary column")
+	fs.StringVar(&geometryFormat, "geometry-format", "geojson", "boundary column as "+strings.Join(geometryFormats, ", "))
+	fs.StringVar(&geojsonDir, "geojson-dir", "", "`directory` to export GeoJSON FeatureCollections into, with boundaries or centroids as geometries")
+	fs.StringVar(&geojsonSplit, "geojson-split", "province", "GeoJSON files by "+strings.Join(geojsonSplits, " or "))
+	fs.StringVar(&lookupCSV, "lookup-csv", "", "CSV `file` to write codes and full names of the nodes into, names joined by -full-name-sep")
+	fs.BoolVar(&lookupShort, "lookup-short", false, "join short names in -lookup-csv")
+	fs.BoolVar(&lookupLeaves, "lookup-leaves", false, "write only nodes without children into -lookup-csv")
+	fs.StringVar(&normalizedFile, "normalized", "", "sql `file` to write a table of each level into, with foreign keys to the level above")
+	fs.BoolVar(&normalizedKeys, "normalized-keys", false, "keep lft and rgt in the tables of -normalized")
+	fs.StringVar(&closureFile, "closure", "", "sql `file` to write a closure table of ancestors, descendants and their distances into")
+	fs.StringVar(&extraFieldList, "extra-fields", "", "comma separated `field:column` of input records to emit as extra columns, e.g. short:short_name,zip:postcode")
+	fs.StringVar(&dialect, "dialect", "mysql", "sql of the inserts, "+strings.Join(dialects, " or "))
+	fs.StringVar(&dbSchema, "db-schema", "", "PostgreSQL, SQL Server, Oracle or DuckDB `schema`, or ClickHouse database, to qualify the table with")
+	fs.StringVar(&onConflict, "on-conflict", "", "what inserts do about existing ids, "+strings.Join(onConflicts, " or "))
+	fs.BoolVar(&preamble, "preamble", false, "wrap SQLite inserts in pragmas and a transaction for fast loading")
+	fs.IntVar(&batchSize, "batch-size", 0, "rows of each insert statement, 0 for 10000 in ClickHouse, 1000 in DuckDB and 1 otherwise")
+	fs.BoolVar(&withSchema, "with-schema", false, "start the inserts with the CREATE TABLE of the table and its indexes, in the dialect")
+	fs.BoolVar(&transaction, "transaction", false, "run the inserts in a transaction, so a failed import leaves the table as it was")
+	fs.IntVar(&commitEvery, "commit-every", 0, "commit the transaction every `n` inserts and begin another, implies -transaction")
+	fs.StringVar(&clean, "clean", "", "empty the table before the inserts, with truncate or delete")
+	fs.IntVar(&goEvery, "go-every", 1000, "SQL Server inserts between GO batch separators, 0 for one batch")
+	fs.BoolVar(&copyFormat, "copy", false, "write PostgreSQL COPY FROM stdin blocks for psql instead of inserts")
+	fs.StringVar(&loadDataFile, "load-data", "", "csv `file` to write the rows into, which the MySQL sql file loads with LOAD DATA LOCAL INFILE")
+	fs.IntVar(&jobs, "jobs", runtime.NumCPU(), "provinces to serialize at once")
+	fs.StringVar(&dsn, "dsn", "", "`data source` to load the rows into with the database/sql driver of the dialect, instead of writing the sql file")
+	fs.StringVar(&migrationsDir, "migrations", "", "`directory` to write the sql into as versioned up and down migrations")
+	fs.StringVar(&migrationFormat, "migration-format", "migrate", "migrations for "+strings.Join(migrationFormats, " or "))
+	fs.StringVar(&migrationVersion, "migration-version", "0001", "version of the migrations, in front of their names")
+	fs.StringVar(&municipalityCity, "municipality-city", "placeholder", "city_code of nodes below municipality placeholders, placeholder or province")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *verbose && *quiet {
+		fmt.Fprintln(stderr, "division: -v and -q exclude each other")
+		return exitUsage
+	}
+	if *logFormat != "text" && *logFormat != "json" {
+		fmt.Fprintf(stderr, "division: unknown log format %q, available: %s\n", *logFormat, strings.Join(logFormats, ", "))
+		return exitUsage
+	}
+	defer func(l *slog.Logger) { logger = l }(logger)
+	logger = newLogger(logOutput, *logFormat, logLevel(*verbose, *quiet))
+
+	var err error
+	columns, err = parseColumns(columnList)
+	if err != nil {
+		fmt.Fprintln(stderr, "division:", err)
+		return exitUsage
+	}
+	parseColumnFlags()
+	if postcodesFile != "" {
+		columns = addColumn(columns, "postcode")
+	}
+	if dialingCodesFile != "" {
+		columns = addColumn(columns, "dialing_code")
+	}
+	if centroidsFile != "" {
+		columns = addColumn(columns, "lng")
+		columns = addColumn(columns, "lat")
+	}
+	if translationsFile != "" {
+		columns = addColumn(columns, "en_name")
+	}
+	if tradOverridesFile != "" {
+		columns = addColumn(columns, "name_trad")
+	}
+	if boundariesPath != "" {
+		columns = addColumn(columns, "boundary")
+	}
+	extraFields, err = parseExtraFields(extraFieldList, columns)
+	if err != nil {
+		fmt.Fprintln(stderr, "division:", err)
+		return exitUsage
+	}
+	for _, f := range extraFields {
+		columns = append(columns, extraColumn(f))
+	}
+	if !isGeometryFormat(geometryFormat) {
+		fmt.Fprintf(stderr, "division: unknown geometry format %q, available: %s\n", geometryFormat, strings.Join(geometryFormats, ", "))
+		return exitUsage
+	}
+	if !isDialect(dialect) {
+		fmt.Fprintf(stderr, "division: unknown dialect %q, available: %s\n", dialect, strings.Join(dialects, ", "))
+		return exitUsage
+	}
+	if (dialect == "mysql" || dialect == "sqlite") && dbSchema != "" {
+		fmt.Fprintln(stderr, "division: -db-schema needs a dialect other than mysql and sqlite")
+		return exitUsage
+	}
+	if (dialect == "mssql" || dialect == "oracle" || dialect == "clickhouse") && onConflict != "" {
+		fmt.Fprintln(stderr, "division: -on-conflict needs -dialect mysql, postgres, sqlite or duckdb")
+		return exitUsage
+	}
+	switch {
+	case batchSize < 0:
+		fmt.Fprintln(stderr, "division: -batch-size must not be negative")
+		return exitUsage
+	case dialect == "oracle" && batchSize > 1:
+		fmt.Fprintln(stderr, "division: Oracle inserts take a single row, -batch-size must be 1")
+		return exitUsage
+	case dialect == "mssql" && batchSize > mssqlMaxBatch:
+		fmt.Fprintf(stderr, "division: SQL Server inserts take %d rows at most, not %d\n", mssqlMaxBatch, batchSize)
+		return exitUsage
+	}
+	if clean != "" && clean != "truncate" && clean != "delete" {
+		fmt.Fprintf(stderr, "division: -clean must be truncate or delete, not %q\n", clean)
+		return exitUsage
+	}
+	if dialect == "sqlite" && clean == "truncate" {
+		fmt.Fprintln(stderr, "division: SQLite has no TRUNCATE, use -clean delete")
+		return exitUsage
+	}
+	if commitEvery < 0 {
+		fmt.Fprintln(stderr, "division: -commit-every must not be negative")
+		return exitUsage
+	}
+	if dialect == "clickhouse" && (transaction || commitEvery > 0) {
+		fmt.Fprintln(stderr, "division: ClickHouse inserts do not run in transactions")
+		return exitUsage
+	}
+	if strict && lenient {
+		fmt.Fprintln(stderr, "division: -strict and -lenient exclude each other")
+		return exitUsage
+	}
+	if jobs < 1 {
+		fmt.Fprintln(stderr, "division: -jobs must be at least 1")
+		return exitUsage
+	}
+	if *step < 1 || *step > math.MaxInt32/2 {
+		fmt.Fprintln(stderr, "division: -key-step must be at least 1 and fit lft and rgt")
+		return exitUsage
+	}
+	keyStep = int32(*step)
+	if goEvery < 0 {
+		fmt.Fprintln(stderr, "division: -go-every must not be negative")
+		return exitUsage
+	}
+	if dialect != "sqlite" && preamble {
+		fmt.Fprintln(stderr, "division: -preamble needs -dialect sqlite")
+		return exitUsage
+	}
+	if dbSchema != "" && !tableName.MatchString(dbSchema) {
+		fmt.Fprintf(stderr, "division: %q is not a plain schema name\n", dbSchema)
+		return exitUsage
+	}
+	if onConflict != "" && onConflict != "nothing" && onConflict != "update" {
+		fmt.Fprintf(stderr, "division: -on-conflict must be nothing or update, not %q\n", onConflict)
+		return exitUsage
+	}
+	if dialect != "mysql" && normalizedFile != "" {
+		fmt.Fprintln(stderr, "division: -normalized writes MySQL tables only")
+		return exitUsage
+	}
+	if dialect != "mysql" && closureFile != "" {
+		fmt.Fprintln(stderr, "division: -closure writes a MySQL table only")
+		return exitUsage
+	}
+	if dsn != "" && !hasDriver() {
+		fmt.Fprintf(stderr, "division: no %s driver built in for -dsn, build with -tags %s\n", driverNames[dialect], dialect)
+		return exitUsage
+	}
+	if dsn != "" && boundariesPath != "" && (geometryFormat == "mysql" || geometryFormat == "postgis") {
+		fmt.Fprintln(stderr, "division: -dsn loads boundaries as geojson or wkt only")
+		return exitUsage
+	}
+	if !isOutputFormat(outputFormat) {
+		fmt.Fprintf(stderr, "division: unknown format %q, available: %s\n", outputFormat, strings.Join(outputFormats, ", "))
+		return exitUsage
+	}
+	if outputFormat == "go" && !isGoPackage(goPackage) {
+		fmt.Fprintf(stderr, "division: %q is not a Go package name\n", goPackage)
+		return exitUsage
+	}
+	if outputFormat != "sql" && (dsn != "" || copyFormat || loadDataFile != "" || migrationsDir != "") {
+		fmt.Fprintf(stderr, "division: -format %s takes no -dsn, -copy, -load-data or -migrations\n", outputFormat)
+		return exitUsage
+	}
+	if copyFormat {
+		switch {
+		case dialect != "postgres":
+			fmt.Fprintln(stderr, "division: -copy needs -dialect postgres")
+			return exitUsage
+		case onConflict != "" || batchSize > 0:
+			fmt.Fprintln(stderr, "division: -copy takes no -on-conflict or -batch-size")
+			return exitUsage
+		case dsn != "" || migrationsDir != "":
+			fmt.Fprintln(stderr, "division: -copy writes a script for psql, not -dsn or -migrations")
+			return exitUsage
+		case boundariesPath != "" && (geometryFormat == "mysql" || geometryFormat == "postgis"):
+			fmt.Fprintln(stderr, "division: -copy writes boundaries as geojson or wkt only")
+			return exitUsage
+		}
+	}
+	if loadDataFile != "" {
+		switch {
+		case dialect != "mysql":
+			fmt.Fprintln(stderr, "division: -load-data needs -dialect mysql")
+			return exitUsage
+		case batchSize > 0:
+			fmt.Fprintln(stderr, "division: -load-data takes no -batch-size")
+			return exitUsage
+		case dsn != "" || migrationsDir != "":
+			fmt.Fprintln(stderr, "division: -load-data writes a script for the mysql client, not -dsn or -migrations")
+			return exitUsage
+		case boundariesPath != "" && geometryFormat == "mysql":
+			fmt.Fprintln(stderr, "division: -load-data writes boundaries as geojson or wkt only")
+			return exitUsage
+		}
+	}
+	if migrationFormat != "migrate" && migrationFormat != "goose" {
+		fmt.Fprintf(stderr, "division: unknown migration format %q, available: %s\n", migrationFormat, strings.Join(migrationFormats, ", "))
+		return exitUsage
+	}
+	if migrationsDir != "" && !migrationVersionPattern.MatchString(migrationVersion) {
+		fmt.Fprintf(stderr, "division: -migration-version must be digits, not %q\n", migrationVersion)
+		return exitUsage
+	}
+	if municipalityCity != "placeholder" && municipalityCity != "province" {
+		fmt.Fprintf(stderr, "division: -municipality-city must be placeholder or province, not %q\n", municipalityCity)
+		return exitUsage
+	}
+	dataSHA256 = strings.ToLower(dataSHA256)
+	if dataSHA256 != "" && !sha256Pattern.MatchString(dataSHA256) {
+		fmt.Fprintf(stderr, "division: -data-sha256 must be 64 hex digits, not %q\n", dataSHA256)
+		return exitUsage
+	}
+	if geojsonSplit != "province" && geojsonSplit != "level" {
+		fmt.Fprintf(stderr, "division: unknown GeoJSON split %q, available: %s\n", geojsonSplit, strings.Join(geojsonSplits, ", "))
+		return exitUsage
+	}
+
 	defer func() {
 		if r := recover(); r != nil {
-			log.Print(string(debug.Stack()))
+			logger.Error("internal error", "panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+			fmt.Fprintln(stderr, "division: internal error:", r)
+			code = exitInternal
 		}
 	}()
-	loadAddress()
-	trees := buildTrees()
-	log.Printf("tree with %d roots", len(trees))
 
+	err = generate()
+	if err != nil {
+		fmt.Fprintln(stderr, "division:", err)
+		return exitCode(err)
+	}
+	return exitOK
+}
+
+func generate() (err error) {
+	start := time.Now()
+	reported, rejects = nil, nil
+	if reportFile != "" {
+		defer func() {
+			werr := writeFileAtomic(reportFile, writeReport, nil)
+			if err == nil {
+				err = werr
+			}
+		}()
+	}
+	if rejectsFile != "" {
+		defer func() {
+			werr := writeFileAtomic(rejectsFile, writeRejects, nil)
+			if err == nil {
+				err = werr
+			}
+		}()
+	}
+	err = pinData()
+	if err != nil {
+		return err
+	}
+	err = loadAddress()
+	if err != nil {
+		return err
+	}
+	err = validate()
+	if err != nil {
+		return err
+	}
+	trees, err := buildTrees()
+	if err != nil {
+		return err
+	}
+	err = validateTree(trees)
+	if err != nil {
+		return err
+	}
+	if len(trees) == 0 {
+		return dataErrorf("no provinces in %s", dataDir)
+	}
+	logger.Info("tree built", "roots", len(trees))
+	if dropPlaceholders {
+		n := removePlaceholders(trees, placeholders)
+		logger.Info("dropped placeholder nodes", "count", n)
+	}
+
+	err = checkLevelNames(trees)
+	if err != nil {
+		return err
+	}
+
+	err = checkKeyStep(trees)
+	if err != nil {
+		return err
+	}
 	assignKeys(trees)
-	log.Printf("key from %d to %d", trees[0].Left, trees[len(trees)-1].Right)
+	logger.Info("keys assigned", "from", trees[0].Left, "to", trees[len(trees)-1].Right, "step", keyStep)
+	shortenNames(trees)
+	if postcodesFile != "" {
+		err = loadPostcodes(trees)
+		if err != nil {
+			return err
+		}
+	}
+	if dialingCodesFile != "" {
+		err = loadDialingCodes(trees)
+		if err != nil {
+			return err
+		}
+	}
+	if centroidsFile != "" {
+		err = loadCentroids(trees)
+		if err != nil {
+			return err
+		}
+	}
+	if translationsFile != "" {
+		err = loadTranslations(trees)
+		if err != nil {
+			return err
+		}
+	}
+	if hasColumn(columns, "en_name") {
+		translateNames(trees)
+	}
+	if tradOverridesFile != "" {
+		err = loadTradOverrides(trees)
+		if err != nil {
+			return err
+		}
+	}
+	if hasColumn(columns, "name_trad") {
+		convertNames(trees)
+	}
+	if boundariesPath != "" {
+		err = loadBoundaries(trees)
+		if err != nil {
+			return err
+		}
+		defer boundaries.close()
+	}
+	if hasColumn(columns, "abbr") {
+		checkAbbreviations(trees)
+	}
+	if hasColumn(columns, "division_type") {
+		classify(trees)
+	}
+	if hasColumn(columns, "province_code") || hasColumn(columns, "city_code") || hasColumn(columns, "area_code") {
+		assignAncestorCodes(trees)
+	}
 
-	genSQLFile(trees)
+	if dsn != "" {
+		rows, err := loadDB(trees)
+		if err != nil {
+			return fmt.Errorf("loading into the database: %v", err)
+		}
+		logger.Info("rows loaded", "dialect", dialect, "rows", rows, "duration", time.Since(start))
+	} else if outputFormat == "json" {
+		err = genJSONFile(trees)
+		if err != nil {
+			return err
+		}
+		logger.Info("json written", "file", sqlFile, "duration", time.Since(start))
+	} else if outputFormat == "csv" {
+		err = genCSVFile(trees)
+		if err != nil {
+			return err
+		}
+		logger.Info("csv written", "file", sqlFile, "duration", time.Since(start))
+	} else if outputFormat == "yaml" {
+		err = genYAMLFile(trees)
+		if err != nil {
+			return err
+		}
+		logger.Info("yaml written", "file", sqlFile, "duration", time.Since(start))
+	} else if outputFormat == "go" {
+		err = genGoFile(trees)
+		if err != nil {
+			return err
+		}
+		logger.Info("go source written", "file", sqlFile, "package", goPackage, "duration", time.Since(start))
+	} else if outputFormat == "protobuf" {
+		err = genProtobufFile(trees)
+		if err != nil {
+			return err
+		}
+		logger.Info("protobuf written", "file", sqlFile, "duration", time.Since(start))
+	} else if outputFormat == "msgpack" {
+		err = genMsgpackFile(trees)
+		if err != nil {
+			return err
+		}
+		logger.Info("msgpack written", "file", sqlFile, "duration", time.Since(start))
+	} else {
+		if loadDataFile != "" {
+			err = genLoadDataCSV(trees)
+			if err != nil {
+				return err
+			}
+			logger.Info("csv written", "file", loadDataFile)
+		}
+		err = genSQLFile(trees)
+		if err != nil {
+			return err
+		}
+		logger.Info("sql written", "file", sqlFile, "duration", time.Since(start))
+	}
+	if lookupCSV != "" {
+		err = genLookupCSV(trees)
+		if err != nil {
+			return err
+		}
+		logger.Info("lookup table written", "file", lookupCSV)
+	}
+	if migrationsDir != "" {
+		err = genMigrations(trees)
+		if err != nil {
+			return err
+		}
+		logger.Info("migrations written", "dir", migrationsDir, "version", migrationVersion)
+	}
+	if normalizedFile != "" {
+		err = genNormalizedFile(trees)
+		if err != nil {
+			return err
+		}
+		logger.Info("normalized tables written", "file", normalizedFile)
+	}
+	if closureFile != "" {
+		err = genClosureFile(trees)
+		if err != nil {
+			return err
+		}
+		logger.Info("closure table written", "file", closureFile)
+	}
+	if geojsonDir != "" {
+		err = genGeoJSON(trees)
+		if err != nil {
+			return err
+		}
+		logger.Info("geojson written", "dir", geojsonDir)
+	}
+	return nil
 }
 
 type Area struct {
-	Code       string
-	Name       string
-	ParentCode string
-	Left       int32
-	Right      int32
-	SubAreas   []*Area
+	Code          string
+	Name          string
+	ParentCode    string
+	ShortName     string
+	Postcode      string
+	DialingCode   string
+	Centroid      *centroid
+	EnglishName   string
+	TradName      string
+	Boundary      *boundary
+	DivisionType  string
+	AncestorCodes [3]string         // of province_code, city_code and area_code, set by assignAncestorCodes
+	Extra         map[string]string // fields of -extra-fields by their names in the input records
+	Left          int32
+	Right         int32
+	SubAreas      []*Area
+}
+
+// NumChildren implements nested.Nester
+func (a *Area) NumChildren() int {
+	return len(a.SubAreas)
+}
+
+// Child implements nested.Nester
+func (a *Area) Child(i int) nested.Nester {
+	return a.SubAreas[i]
+}
+
+// SetKeys implements nested.Nester
+func (a *Area) SetKeys(left, right int32) {
+	a.Left, a.Right = left, right
 }
 
 type flatNode struct {
-	Code       string `json:"code"`
-	Name       string `json:"name"`
-	ParentCode string `json:"parent_code"`
+	Code       string            `json:"code"`
+	Name       string            `json:"name"`
+	ParentCode string            `json:"parent_code"`
+	badUTF8    []string          // fields with invalid UTF-8, which are replaced with U+FFFD when decoding
+	extra      map[string]string // fields of -extra-fields
 }
 
-var provinces, cities, areas, streets []flatNode
+var provinces, cities, areas, streets, villages []flatNode
 
-// load division data from files
-func loadAddress() {
-	// provinces
-	data, err := ioutil.ReadFile(provincesFile)
-	if err != nil {
-		log.Fatal("ioutil.ReadFile: ", err)
+// level is an input file and the records loaded from it
+type level struct {
+	file  string
+	nodes *[]flatNode
+}
+
+// inputLevels lists the input files from top to bottom of the hierarchy
+func inputLevels() []level {
+	return []level{
+		{provincesFile, &provinces},
+		{citiesFile, &cities},
+		{areasFile, &areas},
+		{streetsFile, &streets},
+		{villagesFile, &villages},
 	}
-	err = json.Unmarshal(data, &provinces)
+}
+
+// load division data from files. Deeper levels may be missing or empty, which makes a shallower tree,
+// but a missing level above populated ones is an error.
+func loadAddress() error {
+	_, err := os.Stat(dataDir)
 	if err != nil {
-		log.Fatal("json.Unmarshal error: ", err)
+		return err
 	}
-	log.Printf("got %d provinces", len(provinces))
-	// log.Printf("%+v\n", provinces[:5])
 
-	// cities
-	data, err = ioutil.ReadFile(citiesFile)
-	if err != nil {
-		log.Fatal("ioutil.ReadFile: ", err)
+	levels := inputLevels()
+	counts := []interface{}{"dir", dataDir}
+	for _, l := range levels {
+		*l.nodes = nil
+		err := readJSONFile(filepath.Join(dataDir, l.file), l.nodes)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		counts = append(counts, levelName(l), len(*l.nodes))
 	}
-	err = json.Unmarshal(data, &cities)
-	if err != nil {
-		log.Fatal("json.Unmarshal error: ", err)
+	logger.Info("loaded levels", counts...)
+
+	depth := 0
+	for depth < len(levels) && len(*levels[depth].nodes) > 0 {
+		depth++
 	}
-	log.Printf("got %d cities", len(cities))
-	// log.Printf("%+v\n", cities[:5])
+	for _, l := range levels[depth:] {
+		if len(*l.nodes) > 0 {
+			return dataErrorf("%s is missing or empty but %s has %d records", levels[depth].file, l.file, len(*l.nodes))
+		}
+	}
+	logger.Debug("tree depth", "depth", depth)
+	return nil
+}
+
+// levelName is the file name without extension, e.g. "cities"
+func levelName(l level) string {
+	return strings.TrimSuffix(l.file, filepath.Ext(l.file))
+}
 
-	// areas
-	data, err = ioutil.ReadFile(areasFile)
+// loadTrees builds the trees of a data directory and assigns their keys, or parses them from a generated sql
+// file with the keys written in it
+func loadTrees(source string) ([]*Area, error) {
+	info, err := os.Stat(source)
 	if err != nil {
-		log.Fatal("ioutil.ReadFile: ", err)
+		return nil, err
 	}
-	err = json.Unmarshal(data, &areas)
-	if err != nil {
-		log.Fatal("json.Unmarshal error: ", err)
+	if !info.IsDir() {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		stmts, err := parseSQL(f)
+		if err != nil {
+			return nil, dataErrorf("%s: %v", source, err)
+		}
+		trees, err := treeFromSQL(stmts)
+		if err != nil {
+			return nil, dataErrorf("%s: %v", source, err)
+		}
+		return trees, nil
 	}
-	log.Printf("got %d areas", len(areas))
-	// log.Printf("%+v\n", areas[:5])
 
-	// streets
-	data, err = ioutil.ReadFile(streetsFile)
+	oldDir := dataDir
+	dataDir = source
+	defer func() { dataDir = oldDir }()
+	err = loadAddress()
+	if err != nil {
+		return nil, err
+	}
+	err = validate()
 	if err != nil {
-		log.Fatal("ioutil.ReadFile: ", err)
+		return nil, err
 	}
-	err = json.Unmarshal(data, &streets)
+	trees, err := buildTrees()
 	if err != nil {
-		log.Fatal("json.Unmarshal error: ", err)
+		return nil, err
 	}
-	log.Printf("got %d streets", len(streets))
-	// log.Printf("%+v\n", streets[:5])
+	assignKeys(trees)
+	return trees, nil
 }
 
 // build trees with all the division data
-func buildTrees() []*Area {
-	trees := make([]*Area, 0, len(provinces))
+func buildTrees() ([]*Area, error) {
+	// nodes are numbered in the order of the levels for nested.Build, with their parents found by the prefixes
+	// of their codes, as a code may repeat at the level below, e.g. area 441900 of city 441900
+	var nodes []*Area
+	var records []nested.Record
+	add := func(area *Area, parentID int64) int64 {
+		nodes = append(nodes, area)
+		id := int64(len(nodes))
+		records = append(records, nested.Record{ID: id, Node: area.Name, ParentID: parentID})
+		return id
+	}
 
 	// build provice nodes
-	provinceOrder := make(map[string]int)
-	for i, p := range provinces {
-		trees = append(trees, &Area{
+	provinceIDs := make(map[string]int64)
+	for _, p := range provinces {
+		provinceIDs[p.Code] = add(&Area{
 			Code:       p.Code,
 			Name:       p.Name,
 			ParentCode: "0",
+			Extra:      p.extra,
 			SubAreas:   make([]*Area, 0),
-		})
-		provinceOrder[p.Code] = i
+		}, 0)
 	}
 
 	// build city nodes
-	cityOrder := make(map[string]int)
+	cityIDs := make(map[string]int64)
 	for _, c := range cities {
 		pCode := getProvince(c.Code)
-		p := trees[provinceOrder[pCode]]
-
-		p.SubAreas = append(p.SubAreas, &Area{
+		pid, ok := provinceIDs[pCode]
+		if !ok {
+			return nil, dataErrorf("city %s: province %s does not exist", c.Code, pCode)
+		}
+		cityIDs[c.Code] = add(&Area{
 			Code:       c.Code,
 			Name:       c.Name,
-			ParentCode: c.ParentCode,
+			ParentCode: pCode,
+			Extra:      c.extra,
 			SubAreas:   make([]*Area, 0),
-		})
-		cityOrder[c.Code] = len(p.SubAreas) - 1
+		}, pid)
 	}
 
 	// build area nodes
-	areaOrder := make(map[string]int)
+	areaIDs := make(map[string]int64)
 	for _, a := range areas {
 		pCode := getProvince(a.Code)
 		cCode := getCity(a.Code)
-		p := trees[provinceOrder[pCode]]
-		c := p.SubAreas[cityOrder[cCode]]
-
-		c.SubAreas = append(c.SubAreas, &Area{
+		if _, ok := provinceIDs[pCode]; !ok {
+			return nil, dataErrorf("area %s: province %s does not exist", a.Code, pCode)
+		}
+		cid, ok := cityIDs[cCode]
+		if !ok {
+			return nil, dataErrorf("area %s: city %s does not exist", a.Code, cCode)
+		}
+		areaIDs[a.Code] = add(&Area{
 			Code:       a.Code,
 			Name:       a.Name,
-			ParentCode: a.ParentCode,
-		})
-		areaOrder[a.Code] = len(c.SubAreas) - 1
+			ParentCode: cCode,
+			Extra:      a.extra,
+		}, cid)
 	}
 
 	// build street nodes
+	streetIDs := make(map[string]int64)
 	for _, s := range streets {
 		pCode := getProvince(s.Code)
 		cCode := getCity(s.Code)
 		aCode := getArea(s.Code)
 
-		p := trees[provinceOrder[pCode]]
-		c := p.SubAreas[cityOrder[cCode]]
-		a := c.SubAreas[areaOrder[aCode]]
-
-		a.SubAreas = append(a.SubAreas, &Area{
+		if _, ok := provinceIDs[pCode]; !ok {
+			return nil, dataErrorf("street %s: province %s does not exist", s.Code, pCode)
+		}
+		if _, ok := cityIDs[cCode]; !ok {
+			return nil, dataErrorf("street %s: city %s does not exist", s.Code, cCode)
+		}
+		aid, ok := areaIDs[aCode]
+		if !ok {
+			return nil, dataErrorf("street %s: area %s does not exist", s.Code, aCode)
+		}
+		streetIDs[s.Code] = add(&Area{
 			Code:       s.Code,
 			Name:       s.Name,
-			ParentCode: s.ParentCode,
-		})
+			ParentCode: aCode,
+			Extra:      s.extra,
+		}, aid)
+	}
+
+	// build village nodes
+	for _, v := range villages {
+		pCode := getProvince(v.Code)
+		cCode := getCity(v.Code)
+		aCode := getArea(v.Code)
+		sCode := getStreet(v.Code)
+
+		if _, ok := provinceIDs[pCode]; !ok {
+			return nil, dataErrorf("village %s: province %s does not exist", v.Code, pCode)
+		}
+		if _, ok := cityIDs[cCode]; !ok {
+			return nil, dataErrorf("village %s: city %s does not exist", v.Code, cCode)
+		}
+		if _, ok := areaIDs[aCode]; !ok {
+			return nil, dataErrorf("village %s: area %s does not exist", v.Code, aCode)
+		}
+		sid, ok := streetIDs[sCode]
+		if !ok {
+			return nil, dataErrorf("village %s: street %s does not exist", v.Code, sCode)
+		}
+		add(&Area{
+			Code:       v.Code,
+			Name:       v.Name,
+			ParentCode: sCode,
+			Extra:      v.extra,
+		}, sid)
+	}
+
+	return areaTrees(nodes, records)
+}
+
+// areaTrees builds the trees of nodes with nested.Build from their records, whose IDs are their positions in
+// nodes from 1, and hangs the nodes on each other as the built trees do
+func areaTrees(nodes []*Area, records []nested.Record) ([]*Area, error) {
+	tree, err := nested.Build(records)
+	if err != nil {
+		return nil, internalErrorf("tree: %v", err)
 	}
+	trees := make([]*Area, 0, len(tree.Roots))
+	for _, r := range tree.Roots {
+		trees = append(trees, nodes[r.ID-1])
+	}
+	tree.Walk(func(n *nested.TreeNode) error {
+		a := nodes[n.ID-1]
+		for _, c := range n.Children {
+			a.SubAreas = append(a.SubAreas, nodes[c.ID-1])
+		}
+		return nil
+	})
+	return trees, nil
+}
+
+// removePlaceholders removes nodes below the roots whose names are in names, their children are reparented
+// to the grandparent in place of them. Depth follows from the new position of the subtrees.
+func removePlaceholders(trees []*Area, names map[string]bool) int {
+	n := 0
+	for _, root := range trees {
+		n += removeSubPlaceholders(root, names)
+	}
+	return n
+}
+
+func removeSubPlaceholders(parent *Area, names map[string]bool) int {
+	n := 0
+	subs := make([]*Area, 0, len(parent.SubAreas))
+	for _, sub := range parent.SubAreas {
+		n += removeSubPlaceholders(sub, names)
+		if !names[strings.TrimSpace(sub.Name)] {
+			subs = append(subs, sub)
+			continue
+		}
+		for _, child := range sub.SubAreas {
+			child.ParentCode = parent.Code
+		}
+		subs = append(subs, sub.SubAreas...)
+		n++
+	}
+	parent.SubAreas = subs
+	return n
+}
+
+// splitNames makes a set of a comma separated list, ignoring blank entries
+func splitNames(list string) map[string]bool {
+	names := make(map[string]bool)
+	for _, name := range splitList(list) {
+		names[name] = true
+	}
+	return names
+}
 
-	return trees
+// splitList splits a comma separated list, ignoring blank entries
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
 
-// number the nodes according a tree traversal
+// treeDepth is the number of levels of the deepest tree
+func treeDepth(areas []*Area) int {
+	depth := 0
+	for _, a := range areas {
+		if d := treeDepth(a.SubAreas) + 1; d > depth {
+			depth = d
+		}
+	}
+	return depth
+}
+
+// number the nodes according a tree traversal, keys -key-step apart
 func assignKeys(trees []*Area) {
-	start := int32(0)
+	roots := make([]nested.Nester, len(trees))
+	for i, p := range trees {
+		roots[i] = p
+	}
+	nested.AssignKeysSpaced(roots, keyStep)
+}
+
+// checkKeyStep fails when the keys of -key-step would not fit the INT columns of lft and rgt
+func checkKeyStep(trees []*Area) error {
+	nodes := 0
 	for _, p := range trees {
-		start = indexTree(p, start)
+		walkSubtree([]*Area{p}, func([]*Area) error {
+			nodes++
+			return nil
+		})
+	}
+	if last := 2 * int64(nodes) * int64(keyStep); last > math.MaxInt32 {
+		return dataErrorf("%d nodes take keys up to %d with -key-step %d, more than lft and rgt hold", nodes, last, keyStep)
 	}
+	return nil
 }
 
 // generate database table initial inserting sql queries
-func genSQLFile(trees []*Area) {
-	f, err := os.Create(sqlFile)
-	if err != nil {
-		log.Panic("os.Create error: ", err)
+func genSQLFile(trees []*Area) error {
+	var check func(string) error
+	if selfCheck && loadDataFile == "" {
+		check = func(tmp string) error {
+			return checkSQLFile(tmp, trees)
+		}
 	}
-	defer f.Close()
+	return writeFileAtomic(sqlFile, func(w io.Writer) error {
+		return writeSQL(w, trees)
+	}, check)
+}
 
+// writeSQL writes the content of the sql file, the inserts of the trees with the statements of the dialect
+// around them. Output is buffered, statements being far smaller than a write worth a syscall.
+func writeSQL(w io.Writer, trees []*Area) error {
+	bw := bufio.NewWriterSize(w, sqlBufferSize)
+	if err := writeStmts(bw, trees); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// sqlBufferSize is the size of the output buffer of writeSQL
+const sqlBufferSize = 256 * 1024
+
+func writeStmts(w io.Writer, trees []*Area) error {
+	if _, err := io.WriteString(w, scriptHead()); err != nil {
+		return err
+	}
+	stmts := newStmtWriter(w)
+	if clean != "" {
+		if _, err := io.WriteString(stmts, cleanStmt()); err != nil {
+			return err
+		}
+	}
+	if loadDataFile != "" {
+		if _, err := io.WriteString(stmts, loadDataStmt()); err != nil {
+			return err
+		}
+		return stmts.Close()
+	}
+	gen := genSQL
+	if copyFormat {
+		gen = genCopy
+	}
+	if jobs > 1 && len(trees) > 1 {
+		if err := genParallel(stmts, trees, gen); err != nil {
+			return err
+		}
+		return stmts.Close()
+	}
 	for _, p := range trees {
-		genSQL(f, p, 1)
+		err := gen(stmts, []*Area{p})
+		if err != nil {
+			return err
+		}
 	}
+	return stmts.Close()
 }
 
-func indexTree(root *Area, start int32) int32 {
-	start++
-	root.Left = start
-	for _, sub := range root.SubAreas {
-		start = indexTree(sub, start)
+// genSQL writes inserts of the subtree at the end of path, which is the path from root, with up to
+// rowsPerInsert rows in each statement. Statements are written on one line each, so the self-check and the
+// subcommands reading sql files parse them line by line; batches end with the subtree.
+func genSQL(w io.Writer, path []*Area) error {
+	prefix, end, batch := insertPrefix(), conflictClause()+";\n", rowsPerInsert()
+	var sql bytes.Buffer
+	rows := 0
+	flush := func() error {
+		if rows == 0 {
+			return nil
+		}
+		sql.WriteString(end)
+		_, err := w.Write(sql.Bytes())
+		sql.Reset()
+		rows = 0
+		return err
+	}
+	err := walkSubtree(path, func(path []*Area) error {
+		area := path[len(path)-1]
+		if rows == 0 {
+			sql.WriteString(prefix)
+		} else {
+			sql.WriteString(", (")
+		}
+		sql.WriteString(area.Code)
+		sql.WriteString(", ")
+		writeText(&sql, nodeName(area))
+		sql.WriteString(", ")
+		sql.WriteString(area.ParentCode)
+		sql.WriteString(", ")
+		writeInt(&sql, int32(len(path)))
+		sql.WriteString(", ")
+		writeInt(&sql, area.Left)
+		sql.WriteString(", ")
+		writeInt(&sql, area.Right)
+		if err := writeColumns(&sql, path); err != nil {
+			return err
+		}
+		sql.WriteString(")")
+		if rows++; rows == batch {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
-	start++
-	root.Right = start
-	return start
+	return flush()
 }
 
-func genSQL(f *os.File, area *Area, depth int32) {
-	sql := bytes.NewBufferString(insertPrefix)
-	sql.WriteString(area.Code)
-	sql.WriteString(", '")
-	sql.WriteString(area.Name)
-	sql.WriteString("', ")
-	sql.WriteString(area.ParentCode)
-	sql.WriteString(", ")
-	sql.WriteString(itoa(depth))
-	sql.WriteString(", ")
-	sql.WriteString(itoa(area.Left))
-	sql.WriteString(", ")
-	sql.WriteString(itoa(area.Right))
-	sql.WriteString(");\n")
+// walkSubtree visits the subtree at the end of path in preorder, with the path from root to each node
+func walkSubtree(path []*Area, visit func(path []*Area) error) error {
+	if err := visit(path); err != nil {
+		return err
+	}
+	for _, sub := range path[len(path)-1].SubAreas {
+		if err := walkSubtree(append(path, sub), visit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	_, err := f.Write(sql.Bytes())
-	if err != nil {
-		log.Panic("f.Write error: ", err, " when writting area: ", *area)
+// writeColumns writes the values of the enabled optional columns of the node at the end of path, each after a
+// comma. Text is quoted with quotes in it doubled, empty values of nullable columns are NULL.
+func writeColumns(sql *bytes.Buffer, path []*Area) error {
+	for _, c := range columns {
+		sql.WriteString(", ")
+		v, err := c.get(path)
+		if err != nil {
+			return err
+		}
+		switch {
+		case v == "" && c.null:
+			sql.WriteString("NULL")
+		case c.text:
+			writeText(sql, v)
+		default:
+			sql.WriteString(v)
+		}
 	}
+	return nil
+}
 
-	for _, sub := range area.SubAreas {
-		genSQL(f, sub, depth+1)
+// nodeName returns the trimmed name, or the code in place of an empty one. Line breaks in names become spaces,
+// so statements keep to one line whatever the dialect.
+func nodeName(area *Area) string {
+	name := lineBreaks.Replace(strings.TrimSpace(area.Name))
+	if name == "" {
+		return area.Code
 	}
+	return name
 }
 
+var lineBreaks = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ")
+
 func getProvince(code string) string {
-	p := []byte("000000")
-	copy(p[:2], []byte(code)[:2])
-	return string(p)
+	return codePrefix(code, 1)
 }
 
 func getCity(code string) string {
-	c := []byte("000000")
-	copy(c[:4], []byte(code)[:4])
-	return string(c)
+	return codePrefix(code, 2)
 }
 
 func getArea(code string) string {
-	c := []byte("000000")
-	copy(c[:], []byte(code)[:6])
-	return string(c)
+	return codePrefix(code, 3)
+}
+
+func getStreet(code string) string {
+	return codePrefix(code, 4)
 }
 
 func itoa(i int32) string {
 	return strconv.FormatInt(int64(i), 10)
 }
+
+// writeInt writes i like itoa without allocating the string
+func writeInt(b *bytes.Buffer, i int32) {
+	var digits [11]byte
+	b.Write(strconv.AppendInt(digits[:0], int64(i), 10))
+}
@@ -1,8 +1,13 @@
 package main
 
 import (
+	"bytes"
+	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
 	"runtime/debug"
+	"strings"
 	"testing"
 )
 
@@ -12,8 +17,14 @@ func TestBuild(t *testing.T) {
 			log.Print(string(debug.Stack()))
 		}
 	}()
-	loadAddress()
-	trees := buildTrees()
+	err := loadAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	trees, err := buildTrees()
+	if err != nil {
+		t.Fatal(err)
+	}
 	log.Print("len of beijing areas:", len(trees[0].SubAreas))
 	log.Print("len of tianjin areas: ", len(trees[1].SubAreas))
 	log.Print("len of hebei cities: ", len(trees[2].SubAreas))
@@ -22,7 +33,10 @@ func TestBuild(t *testing.T) {
 	assignKeys(trees)
 	log.Printf("key from %d to %d", trees[0].Left, trees[len(trees)-1].Right)
 
-	genSQLFile(trees)
+	err = genSQLFile(trees)
+	if err != nil {
+		t.Error(err)
+	}
 }
 
 func TestCode(t *testing.T) {
@@ -31,3 +45,444 @@ func TestCode(t *testing.T) {
 		t.Error(p)
 	}
 }
+
+// usePaths points the generator at a fixture directory and a temp output file
+func usePaths(t *testing.T, dir string) string {
+	oldDir, oldOut := dataDir, sqlFile
+	dataDir, sqlFile = dir, filepath.Join(t.TempDir(), "division.sql")
+	t.Cleanup(func() {
+		dataDir, sqlFile = oldDir, oldOut
+		columns, extraFields = nil, nil
+	})
+	return sqlFile
+}
+
+// useStdout captures reports of subcommands
+func useStdout(t *testing.T) *bytes.Buffer {
+	var out bytes.Buffer
+	old := stdout
+	stdout = &out
+	t.Cleanup(func() { stdout = old })
+	return &out
+}
+
+func TestRun(t *testing.T) {
+	out := usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run(nil, &stderr); code != exitOK {
+		t.Fatal(code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "\n"); n != 9 {
+		t.Error("lines:", n)
+	}
+}
+
+func TestRunFailures(t *testing.T) {
+	cases := []struct {
+		dir  string
+		code int
+	}{
+		{"./testdata/none", exitIO},
+		{"./testdata/badjson", exitData},
+		{"./testdata/orphan", exitData},
+	}
+	for _, c := range cases {
+		out := usePaths(t, c.dir)
+		var stderr bytes.Buffer
+		code := run(nil, &stderr)
+		if code != c.code {
+			t.Error(c.dir, "exit code:", code)
+		}
+		if strings.Count(stderr.String(), "\n") != 1 {
+			t.Error(c.dir, "summary:", stderr.String())
+		}
+		if _, err := os.Stat(out); !os.IsNotExist(err) {
+			t.Error(c.dir, "output left behind:", err)
+		}
+	}
+}
+
+func TestRunWriteFailure(t *testing.T) {
+	usePaths(t, "./testdata/mini")
+	sqlFile = filepath.Join(sqlFile, "missing", "division.sql")
+	var stderr bytes.Buffer
+	if code := run(nil, &stderr); code != exitIO {
+		t.Error("exit code:", code, stderr.String())
+	}
+}
+
+func TestRunPaths(t *testing.T) {
+	usePaths(t, "./testdata/none")
+	out := filepath.Join(t.TempDir(), "out.sql")
+	var stderr bytes.Buffer
+	code := run([]string{"-data-dir", "./testdata/mini", "-out", out, "-table", "areas"}, &stderr)
+	if code != exitOK {
+		t.Fatal(code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "INSERT INTO areas(") {
+		t.Error("table:", string(data[:40]))
+	}
+	if dataDir != "./testdata/none" || tblName != "nested" {
+		t.Error("flags not restored:", dataDir, tblName)
+	}
+
+	stderr.Reset()
+	if code = run([]string{"-data-dir", "./testdata/mini", "-table", "a-b"}, &stderr); code != exitUsage {
+		t.Error("bad table exit code:", code, stderr.String())
+	}
+}
+
+func TestShallowLevels(t *testing.T) {
+	cases := []struct {
+		dir   string
+		lines int
+	}{
+		{"./testdata/nostreets", 6},
+		{"./testdata/noareas", 4},
+	}
+	for _, c := range cases {
+		out := usePaths(t, c.dir)
+		var stderr bytes.Buffer
+		if code := run(nil, &stderr); code != exitOK {
+			t.Fatal(c.dir, "exit code:", code, stderr.String())
+		}
+		data, err := ioutil.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := strings.Count(string(data), "\n"); n != c.lines {
+			t.Error(c.dir, "lines:", n)
+		}
+	}
+}
+
+func TestMissingLevel(t *testing.T) {
+	usePaths(t, "./testdata/gap")
+	var stderr bytes.Buffer
+	if code := run(nil, &stderr); code != exitData {
+		t.Error("exit code:", code)
+	}
+	if !strings.Contains(stderr.String(), "cities.json is missing or empty but areas.json has 2 records") {
+		t.Error(stderr.String())
+	}
+}
+
+func TestDropPlaceholders(t *testing.T) {
+	out := usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run([]string{"-drop-placeholders"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sql := string(data)
+	if strings.Contains(sql, "市辖区") {
+		t.Error("placeholder kept")
+	}
+	for _, want := range []string{
+		"VALUES(110000, '北京市', 0, 1, 1, 8);",
+		"VALUES(110101, '东城区', 110000, 2, 2, 7);",
+		"VALUES(110101001000, '东华门街道办事处', 110101, 3, 3, 4);",
+		"VALUES(130100, '石家庄市', 130000, 2, 10, 15);",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Error(want)
+		}
+	}
+
+	out = usePaths(t, "./testdata/mini")
+	if code := run([]string{"-drop-placeholders", "-placeholder-names", "石家庄市"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err = ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "VALUES(130102, '长安区', 130000, 2,") || !strings.Contains(string(data), "市辖区") {
+		t.Error(string(data))
+	}
+}
+
+func TestBatchSize(t *testing.T) {
+	out := usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run([]string{"-batch-size", "4"}, &stderr); code != exitOK {
+		t.Fatal(code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 3 || strings.Count(lines[0], "), (") != 3 || strings.Count(lines[1], "), (") != 0 {
+		t.Fatal(string(data))
+	}
+	if !strings.HasPrefix(lines[0], "INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(110000, '北京市', 0, 1, 1, 10), (110100, ") {
+		t.Error(lines[0])
+	}
+	trees, err := loadTrees(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := loadTrees("./testdata/mini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = compareTrees(want, trees); err != nil {
+		t.Error(err)
+	}
+
+	for _, args := range [][]string{
+		{"-batch-size", "-1"},
+		{"-dialect", "oracle", "-batch-size", "2"},
+		{"-dialect", "mssql", "-batch-size", "1001"},
+	} {
+		usePaths(t, "./testdata/mini")
+		if code := run(args, &stderr); code != exitUsage {
+			t.Error(args, "exit code:", code)
+		}
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	out := usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run([]string{"-commit-every", "5"}, &stderr); code != exitOK {
+		t.Fatal(code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 9+4 || lines[0] != "START TRANSACTION;" || lines[6] != "COMMIT;" || lines[7] != "START TRANSACTION;" || lines[12] != "COMMIT;" {
+		t.Error(string(data))
+	}
+
+	usePaths(t, "./testdata/mini")
+	if code := run([]string{"-dialect", "clickhouse", "-transaction"}, &stderr); code != exitUsage {
+		t.Error("ClickHouse exit code:", code)
+	}
+}
+
+func TestClean(t *testing.T) {
+	for _, c := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-clean", "truncate"}, "TRUNCATE TABLE nested;\nINSERT INTO nested("},
+		{[]string{"-clean", "delete", "-transaction", "-dialect", "postgres"}, "BEGIN;\nDELETE FROM \"nested\";\nINSERT INTO \"nested\"("},
+	} {
+		out := usePaths(t, "./testdata/mini")
+		var stderr bytes.Buffer
+		if code := run(c.args, &stderr); code != exitOK {
+			t.Fatal(c.args, code, stderr.String())
+		}
+		data, err := ioutil.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(data), c.want) {
+			t.Error(c.args, string(data))
+		}
+	}
+
+	for _, args := range [][]string{{"-clean", "drop"}, {"-clean", "truncate", "-dialect", "sqlite"}} {
+		usePaths(t, "./testdata/mini")
+		var stderr bytes.Buffer
+		if code := run(args, &stderr); code != exitUsage {
+			t.Error(args, "exit code:", code)
+		}
+	}
+}
+
+func TestCopy(t *testing.T) {
+	out := usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run([]string{"-dialect", "postgres", "-copy", "-transaction", "-columns", "lng"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "BEGIN;\nCOPY \"nested\" (\"id\", \"node\", \"pid\", \"depth\", \"lft\", \"rgt\", \"lng\") FROM stdin;\n" +
+		"110000\t北京市\t0\t1\t1\t10\t\\N\n"
+	if !strings.HasPrefix(string(data), want) || !strings.HasSuffix(string(data), "\\.\nCOMMIT;\n") {
+		t.Error(string(data))
+	}
+
+	for _, args := range [][]string{{"-copy"}, {"-dialect", "postgres", "-copy", "-on-conflict", "update"}} {
+		usePaths(t, "./testdata/mini")
+		if code := run(args, &stderr); code != exitUsage {
+			t.Error(args, "exit code:", code)
+		}
+	}
+}
+
+func TestLoadData(t *testing.T) {
+	out := usePaths(t, "./testdata/mini")
+	csvFile := filepath.Join(filepath.Dir(out), "nested.csv")
+	var stderr bytes.Buffer
+	if code := run([]string{"-load-data", csvFile, "-on-conflict", "update", "-columns", "lng,short_name"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "LOAD DATA LOCAL INFILE '" + filepath.ToSlash(csvFile) + "' REPLACE INTO TABLE nested CHARACTER SET utf8mb4 " +
+		`FIELDS TERMINATED BY ',' OPTIONALLY ENCLOSED BY '"' ESCAPED BY '' LINES TERMINATED BY '\n' (id, node, pid, depth, lft, rgt, lng, short_name);` + "\n"
+	if string(data) != want {
+		t.Error(string(data))
+	}
+	data, err = ioutil.ReadFile(csvFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), `110000,"北京市",0,1,1,10,NULL,"北京"`+"\n") || strings.Count(string(data), "\n") != 9 {
+		t.Error(string(data))
+	}
+
+	for _, args := range [][]string{{"-load-data", csvFile, "-dialect", "postgres"}, {"-load-data", csvFile, "-batch-size", "10"}} {
+		usePaths(t, "./testdata/mini")
+		if code := run(args, &stderr); code != exitUsage {
+			t.Error(args, "exit code:", code)
+		}
+	}
+}
+
+func BenchmarkWriteSQL(b *testing.B) {
+	trees, err := loadTrees("division.sql")
+	if err != nil {
+		b.Fatal(err)
+	}
+	f, err := ioutil.TempFile(b.TempDir(), "division.sql")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer f.Close()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := f.Seek(0, 0); err != nil {
+			b.Fatal(err)
+		}
+		if err := writeSQL(f, trees); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestJobs(t *testing.T) {
+	var outputs []string
+	for _, jobs := range []string{"1", "3"} {
+		out := usePaths(t, "./testdata/mini")
+		var stderr bytes.Buffer
+		args := []string{"-jobs", jobs, "-dialect", "mssql", "-commit-every", "3", "-go-every", "2", "-columns", "initial,full_name,id_path"}
+		if code := run(args, &stderr); code != exitOK {
+			t.Fatal(jobs, "exit code:", code, stderr.String())
+		}
+		data, err := ioutil.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		outputs = append(outputs, string(data))
+	}
+	if outputs[0] != outputs[1] {
+		t.Errorf("-jobs 1:\n%s\n-jobs 3:\n%s", outputs[0], outputs[1])
+	}
+
+	usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run([]string{"-jobs", "0"}, &stderr); code != exitUsage {
+		t.Error("exit code:", code)
+	}
+}
+
+func TestVillages(t *testing.T) {
+	out := usePaths(t, "./testdata/villages")
+	var stderr bytes.Buffer
+	if code := run([]string{"-columns", "level_name"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"VALUES(110101001000, '东华门街道办事处', 110101, 4, 4, 9, '街道');",
+		"VALUES(110101001001, '多福巷社区居委会', 110101001000, 5, 5, 6, '村居');",
+		"VALUES(110101001002, '银闸社区居委会', 110101001000, 5, 7, 8, '村居');",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Error(want, "missing in", string(data))
+		}
+	}
+
+	dir := t.TempDir()
+	for _, name := range []string{"provinces.json", "cities.json", "areas.json", "streets.json"} {
+		data, err := ioutil.ReadFile(filepath.Join("testdata/villages", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = os.WriteFile(filepath.Join(dir, "villages.json"), []byte(`[{"code":"110101003001","name":"村"}]`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usePaths(t, dir)
+	stderr.Reset()
+	if code := run(nil, &stderr); code != exitData || !strings.Contains(stderr.String(), "orphan: 110101003001") {
+		t.Error("exit code:", code, stderr.String())
+	}
+}
+
+func TestKeyStep(t *testing.T) {
+	out := usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run([]string{"-key-step", "10", "-columns", "descendants_count"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"VALUES(110000, '北京市', 0, 1, 10, 100, 4);", "VALUES(130102001000, '建北街道办事处', 130102, 4, 140, 150, 0);"} {
+		if !strings.Contains(string(data), want) {
+			t.Error(want, "missing in", string(data))
+		}
+	}
+
+	stdout := useStdout(t)
+	if code := run([]string{"verify", "-key-step", "10", out}, &stderr); code != exitOK {
+		t.Error("verify exit code:", code, stdout.String())
+	}
+	if code := run([]string{"verify", out}, &stderr); code != exitInvalid {
+		t.Error("verify exit code:", code)
+	}
+	stdout.Reset()
+	if code := run([]string{"explain", "-from", out, "-key-step", "10", "110000"}, &stderr); code != exitOK ||
+		!strings.Contains(stdout.String(), "(100 - 10 - 10) / (2 * 10) = 4\n") {
+		t.Error("explain exit code:", code, stdout.String())
+	}
+
+	for args, want := range map[string]int{"0": exitUsage, "1073741823": exitData} {
+		usePaths(t, "./testdata/mini")
+		if code := run([]string{"-key-step", args}, &stderr); code != want {
+			t.Error(args, "exit code:", code)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// upstreamURL is where the input files are published, each level file under its own name
+const upstreamURL = "https://raw.githubusercontent.com/modood/Administrative-divisions-of-China/master/dist/"
+
+// fetchUpstream downloads the input files from base into dir. Files which are not found are left out, like
+// missing deeper levels of a data directory.
+func fetchUpstream(client *http.Client, base, dir string) error {
+	if !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+	for _, l := range inputLevels() {
+		resp, err := client.Get(base + l.file)
+		if err != nil {
+			return &networkError{err}
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			resp.Body.Close()
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return &networkError{fmt.Errorf("%s%s: %s", base, l.file, resp.Status)}
+		}
+		err = writeFileAtomic(filepath.Join(dir, l.file), func(w io.Writer) error {
+			_, err := io.Copy(w, resp.Body)
+			if err != nil {
+				return &networkError{err}
+			}
+			return nil
+		}, nil)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeUpdate summarizes the changes of the upstream data, by fingerprint and by the number of nodes of each
+// level, and tells whether there are any
+func writeUpdate(w io.Writer, local, upstream *datasetStats) bool {
+	if local.Fingerprint == upstream.Fingerprint {
+		fmt.Fprintf(w, "up to date, fingerprint %s\n", local.Fingerprint)
+		return false
+	}
+	fmt.Fprintf(w, "update available, fingerprint %s -> %s\n", local.Fingerprint, upstream.Fingerprint)
+	levels := inputLevels()
+	for i := 0; i < len(local.Levels) || i < len(upstream.Levels); i++ {
+		var before, after int
+		if i < len(local.Levels) {
+			before = local.Levels[i].Nodes
+		}
+		if i < len(upstream.Levels) {
+			after = upstream.Levels[i].Nodes
+		}
+		name := fmt.Sprintf("depth %d", i+1)
+		if i < len(levels) {
+			name = levelName(levels[i])
+		}
+		fmt.Fprintf(w, "  %-10s %d -> %d", name, before, after)
+		if after != before {
+			fmt.Fprintf(w, " (%+d)", after-before)
+		}
+		fmt.Fprintln(w)
+	}
+	return true
+}
+
+// runCheckUpdate downloads the upstream input files and compares them with the local ones. A scheduled job
+// tells an update by its exit code, from no update and from a failed download.
+func runCheckUpdate(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("division check-update", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	from := fs.String("from", dataDir, "local data `directory` or generated sql file")
+	upstream := fs.String("upstream", upstreamURL, "base `URL` of the upstream input files")
+	timeout := fs.Duration("timeout", time.Minute, "timeout of each download")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: division check-update [-from dir|file] [-upstream url] [-timeout d]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	local, err := loadTrees(*from)
+	if err != nil {
+		fmt.Fprintln(stderr, "division check-update:", err)
+		return exitCode(err)
+	}
+	dir, err := os.MkdirTemp("", "division-upstream-")
+	if err != nil {
+		fmt.Fprintln(stderr, "division check-update:", err)
+		return exitIO
+	}
+	defer os.RemoveAll(dir)
+	if err = fetchUpstream(&http.Client{Timeout: *timeout}, *upstream, dir); err != nil {
+		fmt.Fprintln(stderr, "division check-update:", err)
+		return exitCode(err)
+	}
+	trees, err := loadTrees(dir)
+	if err == nil && len(trees) == 0 {
+		err = dataErrorf("no provinces at %s", *upstream)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "division check-update: upstream data: %v\n", err)
+		return exitCode(err)
+	}
+
+	if writeUpdate(stdout, computeStats(*from, local), computeStats(*upstream, trees)) {
+		return exitUpdate
+	}
+	return exitOK
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckUpdate(t *testing.T) {
+	out := useStdout(t)
+	server := httptest.NewServer(http.FileServer(http.Dir("./testdata")))
+	defer server.Close()
+
+	var stderr bytes.Buffer
+	if code := run([]string{"check-update", "-from", "./testdata/mini", "-upstream", server.URL + "/mini"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	if !strings.HasPrefix(out.String(), "up to date, fingerprint ") {
+		t.Error(out.String())
+	}
+
+	out.Reset()
+	if code := run([]string{"check-update", "-from", "./testdata/mini", "-upstream", server.URL + "/mini2/"}, &stderr); code != exitUpdate {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	for _, s := range []string{"update available, fingerprint ", "  areas      2 -> 3 (+1)\n", "  streets    3 -> 2 (-1)\n", "  provinces  2 -> 2\n"} {
+		if !strings.Contains(out.String(), s) {
+			t.Error(s, "missing in", out.String())
+		}
+	}
+
+	// a failed download is not mistaken for no update
+	if code := run([]string{"check-update", "-from", "./testdata/mini", "-upstream", server.URL + "/nowhere"}, &stderr); code != exitData {
+		t.Error("exit code of missing files:", code)
+	}
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	if code := run([]string{"check-update", "-from", "./testdata/mini", "-upstream", failing.URL}, &stderr); code != exitNetwork {
+		t.Error("exit code:", code)
+	}
+	failing.Close()
+	if code := run([]string{"check-update", "-from", "./testdata/mini", "-upstream", failing.URL}, &stderr); code != exitNetwork {
+		t.Error("exit code of a closed server:", code)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"io"
+)
+
+// closureFile is the sql file of -closure, the closure table of the trees
+var closureFile string
+
+// closureTable is the table of -closure, named after the table of the nested sets
+func closureTable() string {
+	return tblName + "_closure"
+}
+
+// closureSchema creates the closure table, keyed by ancestor and descendant and indexed by descendant for the
+// ancestors of a node
+func closureSchema() string {
+	return "CREATE TABLE IF NOT EXISTS `" + closureTable() + "`(\n" +
+		"`ancestor` BIGINT NOT NULL COMMENT 'ancestor ID',\n" +
+		"`descendant` BIGINT NOT NULL COMMENT 'descendant ID',\n" +
+		"`distance` INT NOT NULL COMMENT 'levels from ancestor down to descendant, 0 for the node itself',\n" +
+		"  PRIMARY KEY (`ancestor`, `descendant`),\n" +
+		"  INDEX `descendant_index` (`descendant` ASC))\n" +
+		"ENGINE = InnoDB COMMENT = 'closure table of " + tblName + "';\n"
+}
+
+// genClosure writes the schema of the closure table and an insert of each node in preorder, with a row of each
+// ancestor and one of the node itself. A node of the code of its parent, a city without districts listed also
+// as its own district, is folded into the parent, as its rows would repeat the keys of the parent's.
+func genClosure(w io.Writer, trees []*Area) error {
+	if _, err := io.WriteString(w, closureSchema()+"\n"); err != nil {
+		return err
+	}
+	prefix := "INSERT INTO `" + closureTable() + "`(ancestor, descendant, distance) VALUES("
+	var sql bytes.Buffer
+	var codes []string
+	for _, p := range trees {
+		err := walkSubtree([]*Area{p}, func(path []*Area) error {
+			codes = codes[:0]
+			for i, a := range path {
+				if i == 0 || a.Code != path[i-1].Code {
+					codes = append(codes, a.Code)
+				}
+			}
+			area := path[len(path)-1]
+			if len(path) > 1 && area.Code == path[len(path)-2].Code {
+				return nil
+			}
+			sql.Reset()
+			sql.WriteString(prefix)
+			for i, code := range codes {
+				if i > 0 {
+					sql.WriteString("), (")
+				}
+				sql.WriteString(code)
+				sql.WriteString(", ")
+				sql.WriteString(area.Code)
+				sql.WriteString(", ")
+				writeInt(&sql, int32(len(codes)-1-i))
+			}
+			sql.WriteString(");\n")
+			_, err := w.Write(sql.Bytes())
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// genClosureFile writes the closure table into -closure through a temp file
+func genClosureFile(trees []*Area) error {
+	return writeFileAtomic(closureFile, func(w io.Writer) error {
+		return genClosure(w, trees)
+	}, nil)
+}
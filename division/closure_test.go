@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClosure(t *testing.T) {
+	usePaths(t, "./testdata/mini")
+	name := filepath.Join(t.TempDir(), "closure.sql")
+	var stderr bytes.Buffer
+	if code := run([]string{"-closure", name}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sql := string(data)
+	for _, want := range []string{
+		"CREATE TABLE IF NOT EXISTS `nested_closure`(\n`ancestor` BIGINT NOT NULL COMMENT 'ancestor ID',\n",
+		"  PRIMARY KEY (`ancestor`, `descendant`),\n",
+		"\nINSERT INTO `nested_closure`(ancestor, descendant, distance) VALUES(110000, 110000, 0);\n" +
+			"INSERT INTO `nested_closure`(ancestor, descendant, distance) VALUES(110000, 110100, 1), (110100, 110100, 0);\n",
+		"VALUES(130000, 130102001000, 3), (130100, 130102001000, 2), (130102, 130102001000, 1), (130102001000, 130102001000, 0);\n",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Error(want, "missing in", sql)
+		}
+	}
+	if n := strings.Count(sql, "INSERT") + strings.Count(sql, "), ("); n != 2*1+2*2+2*3+3*4 {
+		t.Error("rows:", n)
+	}
+
+	usePaths(t, "./testdata/mini")
+	if code := run([]string{"-closure", name, "-dialect", "postgres"}, &stderr); code != exitUsage {
+		t.Error("exit code:", code)
+	}
+}
+
+func TestClosureFolds(t *testing.T) {
+	street := &Area{Code: "441900003000"}
+	district := &Area{Code: "441900", SubAreas: []*Area{street}}
+	city := &Area{Code: "441900", SubAreas: []*Area{district}}
+	province := &Area{Code: "440000", SubAreas: []*Area{city}}
+	var sql bytes.Buffer
+	if err := genClosure(&sql, []*Area{province}); err != nil {
+		t.Fatal(err)
+	}
+	inserts := strings.SplitAfter(sql.String(), "\n\n")[1]
+	want := "INSERT INTO `nested_closure`(ancestor, descendant, distance) VALUES(440000, 440000, 0);\n" +
+		"INSERT INTO `nested_closure`(ancestor, descendant, distance) VALUES(440000, 441900, 1), (441900, 441900, 0);\n" +
+		"INSERT INTO `nested_closure`(ancestor, descendant, distance) VALUES(440000, 441900003000, 2), (441900, 441900003000, 1), (441900003000, 441900003000, 0);\n"
+	if inserts != want {
+		t.Error(inserts)
+	}
+}
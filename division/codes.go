@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// codeSpec is the structure of the codes of an input level: the leading digits which are its own and those of
+// the levels above, and the width the codes are padded to with zeros
+type codeSpec struct {
+	prefix int
+	width  int
+}
+
+// defaultCodeSpecs are those of the Chinese statistical division codes, 110000, 110100, 110101, 110101001000
+// and 110101001001
+var defaultCodeSpecs = []codeSpec{{2, 6}, {4, 6}, {6, 6}, {9, 12}, {12, 12}}
+
+// codeSpecs are the code structures of the input levels, set with -code-levels
+var codeSpecs = defaultCodeSpecs
+
+// codeLevelsFlag sets codeSpecs with -code-levels, a prefix:width per level, commands restore it when they are
+// done
+type codeLevelsFlag struct{}
+
+func (codeLevelsFlag) String() string {
+	items := make([]string, len(codeSpecs))
+	for i, s := range codeSpecs {
+		items[i] = strconv.Itoa(s.prefix) + ":" + strconv.Itoa(s.width)
+	}
+	return strings.Join(items, ",")
+}
+
+func (codeLevelsFlag) Set(v string) error {
+	items := strings.Split(v, ",")
+	if len(items) != len(inputLevels()) {
+		return fmt.Errorf("%d levels expected, got %d", len(inputLevels()), len(items))
+	}
+	specs := make([]codeSpec, len(items))
+	for i, item := range items {
+		var s codeSpec
+		parts := strings.Split(strings.TrimSpace(item), ":")
+		if len(parts) != 2 {
+			return fmt.Errorf("%q is not prefix:width", item)
+		}
+		var err1, err2 error
+		s.prefix, err1 = strconv.Atoi(parts[0])
+		s.width, err2 = strconv.Atoi(parts[1])
+		switch {
+		case err1 != nil || err2 != nil || s.prefix < 1 || s.width < s.prefix:
+			return fmt.Errorf("%q is not prefix:width with 0 < prefix <= width", item)
+		case i > 0 && (s.prefix <= specs[i-1].prefix || s.width < specs[i-1].width):
+			return fmt.Errorf("%q does not extend the level above", item)
+		}
+		specs[i] = s
+	}
+	codeSpecs = specs
+	return nil
+}
+
+// codePrefix is the code of the ancestor at level, from 1, of code: its prefix padded with zeros
+func codePrefix(code string, level int) string {
+	s := codeSpecs[level-1]
+	if len(code) > s.prefix {
+		code = code[:s.prefix]
+	}
+	return code + strings.Repeat("0", s.width-len(code))
+}
+
+// codeLevel infers the level from the code structure: with the default -code-levels 1 for province 110000,
+// 2 for city 110100, 3 for area 110101, 4 for street 110101001000 and 5 for village 110101001001. A code is of the first level whose width
+// it has, whose digits are zeros after its prefix and not all zeros within the part of the prefix beyond the
+// level above. It is 0 for codes of none of these shapes.
+func codeLevel(code string) int {
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return 0
+		}
+	}
+	above := 0
+	for i, s := range codeSpecs {
+		if len(code) == s.width && strings.Trim(code[s.prefix:], "0") == "" && strings.Trim(code[above:s.prefix], "0") != "" {
+			return i + 1
+		}
+		above = s.prefix
+	}
+	return 0
+}
+
+// fileLevel is the level of a code read from the input file of level. It is that level where the code fits it
+// although its structure is of another one: a city repeated as its own area when repeated is set, e.g. 441900
+// in areas.json, which gives the streets of a city without areas a parent, and a code of the width of the level
+// with digits of its own after them, e.g. street 653130103001, which codeLevel takes for a village. Otherwise
+// it is codeLevel.
+func fileLevel(code string, level int, repeated bool) int {
+	l := codeLevel(code)
+	if l == 0 || l == level {
+		return l
+	}
+	if l == level-1 && repeated {
+		return level
+	}
+	s := codeSpecs[level-1]
+	above := 0
+	if level > 1 {
+		above = codeSpecs[level-2].prefix
+	}
+	if l > level && codeSpecs[l-1].width == s.width && strings.Trim(code[above:s.prefix], "0") != "" {
+		return level
+	}
+	return l
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCodeLevelsFlag(t *testing.T) {
+	defer func(specs []codeSpec) { codeSpecs = specs }(codeSpecs)
+	var f codeLevelsFlag
+	for _, v := range []string{"2:6,4:6,6:6,9:12", "2:6,4:6,6:6,x:12,12:12", "3:2,4:6,6:6,9:12,12:12", "2:6,2:6,6:6,9:12,12:12", "2:6,4:6,6:6,9:4,12:12"} {
+		if err := f.Set(v); err == nil {
+			t.Error("no error:", v)
+		}
+	}
+	if err := f.Set("1:4, 2:4, 3:4, 5:6, 6:6"); err != nil {
+		t.Fatal(err)
+	}
+	if s := f.String(); s != "1:4,2:4,3:4,5:6,6:6" {
+		t.Error(s)
+	}
+	for code, level := range map[string]int{"1000": 1, "1200": 2, "1230": 3, "123040": 4, "123041": 5, "0000": 0, "123000": 0, "110000": 0} {
+		if l := codeLevel(code); l != level {
+			t.Error(code, l)
+		}
+	}
+	if p, c := getProvince("123040"), getArea("123040"); p != "1000" || c != "1230" {
+		t.Error(p, c)
+	}
+}
+
+func TestCodeLevels(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"provinces.json": `[{"code":"1000","name":"A"}]`,
+		"cities.json":    `[{"code":"1200","name":"B"}]`,
+		"areas.json":     `[{"code":"1230","name":"C"}]`,
+		"streets.json":   `[{"code":"123040","name":"D"}]`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := usePaths(t, dir)
+	var stderr bytes.Buffer
+	if code := run([]string{"-code-levels", "1:4,2:4,3:4,5:6,6:6"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	if s := (codeLevelsFlag{}).String(); s != "2:6,4:6,6:6,9:12,12:12" {
+		t.Error("not restored:", s)
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "VALUES(123040, 'D', 1230, 4, 4, 5);") {
+		t.Error(string(data))
+	}
+
+	if code := run(nil, &stderr); code != exitData {
+		t.Error("default -code-levels, exit code:", code)
+	}
+}
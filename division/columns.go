@@ -0,0 +1,386 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// column is an optional output column, whose value is computed from the path from root to the node
+type column struct {
+	name  string
+	ddl   string // column definition as in createtable.sql
+	text  bool   // quoted as string in sql, with quotes in it doubled
+	null  bool   // empty values written as NULL
+	value func(path []*Area) string
+	read  func(path []*Area) (string, error) // in place of value for values read from files while writing
+}
+
+// fullName joins names from root to the node at the end of path with -full-name-sep, leaving out placeholders
+// with -full-name-skip-placeholders. Placeholders are told by their names, whichever name is joined.
+func fullName(path []*Area, name func(*Area) string) string {
+	var skip map[string]bool
+	if fullNameSkipPlaceholders {
+		skip = placeholders
+	}
+	names := make([]string, 0, len(path))
+	for _, a := range path {
+		if !skip[nodeName(a)] {
+			names = append(names, name(a))
+		}
+	}
+	return strings.Join(names, fullNameSep)
+}
+
+// get computes the value of the column for the node at the end of path
+func (c column) get(path []*Area) (string, error) {
+	if c.read != nil {
+		return c.read(path)
+	}
+	return c.value(path), nil
+}
+
+// levelNameList and placeholders are -level-names and -placeholder-names, parsed by parseColumnFlags once for
+// all nodes
+var (
+	levelNameList = splitList(levelNames)
+	placeholders  = splitNames(placeholderNames)
+)
+
+// parseColumnFlags parses the lists of the flags which columns read, once the flags are set, and sizes id_path
+// for -code-levels and -id-path-sep
+func parseColumnFlags() {
+	levelNameList = splitList(levelNames)
+	placeholders = splitNames(placeholderNames)
+	for i, c := range columns {
+		if c.name == "id_path" {
+			columns[i].ddl = idPathDDL()
+		}
+	}
+}
+
+// idPathDDL defines id_path wide enough for the longest path: a code of every level, as a repeated city like
+// 441900 takes the place of its area, and the separators between them. That is 46 characters by default.
+func idPathDDL() string {
+	width := len(idPathSep) * (len(codeSpecs) - 1)
+	for _, s := range codeSpecs {
+		width += s.width
+	}
+	return fmt.Sprintf("VARCHAR(%d) NOT NULL DEFAULT '' COMMENT 'ids from root to the node'", width)
+}
+
+// optionalColumns could be enabled with -columns
+var optionalColumns = []column{
+	{
+		name: "initial",
+		ddl:  "CHAR(1) NOT NULL DEFAULT '' COMMENT 'first letter of pinyin'",
+		text: true,
+		value: func(path []*Area) string {
+			return initial(nodeName(path[len(path)-1]))
+		},
+	},
+	{
+		// the longest pinyin of a name in the data is 89 characters
+		name: "pinyin",
+		ddl:  "VARCHAR(128) NOT NULL DEFAULT '' COMMENT 'pinyin of name, syllables separated by spaces'",
+		text: true,
+		value: func(path []*Area) string {
+			return namePinyin(nodeName(path[len(path)-1]))
+		},
+	},
+	{
+		name: "pinyin_initials",
+		ddl:  "VARCHAR(32) NOT NULL DEFAULT '' COMMENT 'first letters of pinyin syllables, for typeahead'",
+		text: true,
+		value: func(path []*Area) string {
+			return pinyinInitials(nodeName(path[len(path)-1]))
+		},
+	},
+	{
+		name: "short_name",
+		ddl:  "VARCHAR(64) CHARACTER SET 'utf8' NOT NULL DEFAULT '' COMMENT 'name without administrative suffix'",
+		text: true,
+		value: func(path []*Area) string {
+			return path[len(path)-1].ShortName
+		},
+	},
+	{
+		name: "postcode",
+		ddl:  "CHAR(6) NULL COMMENT 'postal code'",
+		text: true,
+		null: true,
+		value: func(path []*Area) string {
+			return path[len(path)-1].Postcode
+		},
+	},
+	{
+		name: "dialing_code",
+		ddl:  "VARCHAR(4) NULL COMMENT 'long-distance dialing code'",
+		text: true,
+		null: true,
+		value: func(path []*Area) string {
+			return path[len(path)-1].DialingCode
+		},
+	},
+	{
+		name: "lng",
+		ddl:  "DOUBLE NULL COMMENT 'longitude of centroid'",
+		null: true,
+		value: func(path []*Area) string {
+			if c := path[len(path)-1].Centroid; c != nil {
+				return formatCoord(c.lng)
+			}
+			return ""
+		},
+	},
+	{
+		name: "lat",
+		ddl:  "DOUBLE NULL COMMENT 'latitude of centroid'",
+		null: true,
+		value: func(path []*Area) string {
+			if c := path[len(path)-1].Centroid; c != nil {
+				return formatCoord(c.lat)
+			}
+			return ""
+		},
+	},
+	{
+		name: "is_leaf",
+		ddl:  "TINYINT(1) NOT NULL DEFAULT 0 COMMENT 'whether the node has no children in this table'",
+		value: func(path []*Area) string {
+			if len(path[len(path)-1].SubAreas) == 0 {
+				return "1"
+			}
+			return "0"
+		},
+	},
+	{
+		name: "children_count",
+		ddl:  "INT UNSIGNED NOT NULL DEFAULT 0 COMMENT 'number of direct children'",
+		value: func(path []*Area) string {
+			return itoa(int32(len(path[len(path)-1].SubAreas)))
+		},
+	},
+	{
+		name: "descendants_count",
+		ddl:  "INT UNSIGNED NOT NULL DEFAULT 0 COMMENT 'number of descendants, (rgt-lft-1)/2'",
+		value: func(path []*Area) string {
+			area := path[len(path)-1]
+			return itoa((area.Right - area.Left - keyStep) / (2 * keyStep))
+		},
+	},
+	{
+		// five levels of 6, 6, 6, 12 and 12 digits with separators take 46 characters
+		name: "id_path",
+		ddl:  idPathDDL(),
+		text: true,
+		value: func(path []*Area) string {
+			if !idPathSelf {
+				path = path[:len(path)-1]
+			}
+			ids := make([]string, len(path))
+			for i, a := range path {
+				ids[i] = a.Code
+			}
+			return strings.Join(ids, idPathSep)
+		},
+	},
+	{
+		name: "level_name",
+		ddl:  "VARCHAR(16) CHARACTER SET 'utf8' NOT NULL DEFAULT '' COMMENT 'name of the level at depth'",
+		text: true,
+		value: func(path []*Area) string {
+			return levelNameList[len(path)-1]
+		},
+	},
+	{
+		// the longest full name in the data is 38 characters
+		name: "full_name",
+		ddl:  "VARCHAR(128) CHARACTER SET 'utf8' NOT NULL DEFAULT '' COMMENT 'names from root to the node'",
+		text: true,
+		value: func(path []*Area) string {
+			return fullName(path, nodeName)
+		},
+	},
+	{
+		name: "en_name",
+		ddl:  "VARCHAR(128) NOT NULL DEFAULT '' COMMENT 'English name'",
+		text: true,
+		value: func(path []*Area) string {
+			return path[len(path)-1].EnglishName
+		},
+	},
+	{
+		name:  "abbr",
+		ddl:   "CHAR(1) CHARACTER SET 'utf8' NOT NULL DEFAULT '' COMMENT 'abbreviation of province'",
+		text:  true,
+		value: abbreviation,
+	},
+	{
+		name: "division_type",
+		ddl:  "VARCHAR(32) NOT NULL DEFAULT '' COMMENT 'kind of division, e.g. county or banner'",
+		text: true,
+		value: func(path []*Area) string {
+			return path[len(path)-1].DivisionType
+		},
+	},
+	{
+		name: "name_trad",
+		ddl:  "VARCHAR(64) CHARACTER SET 'utf8' NOT NULL DEFAULT '' COMMENT 'name in traditional characters'",
+		text: true,
+		value: func(path []*Area) string {
+			return path[len(path)-1].TradName
+		},
+	},
+	{
+		name: "boundary",
+		ddl:  "LONGTEXT NULL COMMENT 'boundary as GeoJSON'",
+		null: true,
+		read: func(path []*Area) (string, error) {
+			return boundarySQL(path[len(path)-1].Boundary)
+		},
+	},
+	{
+		name: "province_code",
+		ddl:  "BIGINT NULL COMMENT 'code of the province of the node, NULL above province level'",
+		null: true,
+		value: func(path []*Area) string {
+			return path[len(path)-1].AncestorCodes[0]
+		},
+	},
+	{
+		name: "city_code",
+		ddl:  "BIGINT NULL COMMENT 'code of the city of the node, NULL above city level'",
+		null: true,
+		value: func(path []*Area) string {
+			return path[len(path)-1].AncestorCodes[1]
+		},
+	},
+	{
+		name: "area_code",
+		ddl:  "BIGINT NULL COMMENT 'code of the area of the node, NULL above area level'",
+		null: true,
+		value: func(path []*Area) string {
+			return path[len(path)-1].AncestorCodes[2]
+		},
+	},
+}
+
+// columns are the enabled optional columns, in order of output
+var columns []column
+
+// parseColumns looks up a comma separated list of optional column names
+func parseColumns(list string) ([]column, error) {
+	var cols []column
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, c := range optionalColumns {
+			if c.name == name {
+				cols = append(cols, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown column %q, available: %s", name, columnNames(optionalColumns))
+		}
+	}
+	return cols, nil
+}
+
+// addColumn appends the named optional column unless it is enabled already
+func addColumn(cols []column, name string) []column {
+	if hasColumn(cols, name) {
+		return cols
+	}
+	added, _ := parseColumns(name)
+	return append(cols, added...)
+}
+
+// ancestorCodes returns the codes of the province, city and area of the node at the end of path, the node
+// itself for its own level and "" for levels below its own. Levels come from the codes, a node with a code of
+// its parent's level or above is one level below it, e.g. area 441900 under city 441900.
+//
+// The city of nodes below a placeholder like 北京市/市辖区 is the placeholder, or the province with
+// -municipality-city province. So is the city of nodes whose placeholder is dropped.
+func ancestorCodes(path []*Area) [3]string {
+	var codes [3]string
+	level := 0
+	for i, a := range path {
+		l := codeLevel(a.Code)
+		if l <= level {
+			l = level + 1
+		}
+		switch {
+		case l == 2 && i < len(path)-1 && municipalityCity == "province" && placeholders[nodeName(a)]:
+			codes[1] = codes[0]
+		case l <= 3:
+			codes[l-1] = a.Code
+		}
+		if l == 3 && level == 1 {
+			codes[1] = getCity(a.Code)
+			if municipalityCity == "province" {
+				codes[1] = codes[0]
+			}
+		}
+		level = l
+	}
+	return codes
+}
+
+// assignAncestorCodes sets the AncestorCodes of every node, for the province_code, city_code and area_code
+// columns to share
+func assignAncestorCodes(trees []*Area) {
+	var walk func(path []*Area)
+	walk = func(path []*Area) {
+		a := path[len(path)-1]
+		a.AncestorCodes = ancestorCodes(path)
+		for _, sub := range a.SubAreas {
+			walk(append(path, sub))
+		}
+	}
+	for _, p := range trees {
+		walk([]*Area{p})
+	}
+}
+
+// hasColumn tells whether the named column is enabled
+func hasColumn(cols []column, name string) bool {
+	for _, c := range cols {
+		if c.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkLevelNames makes sure -level-names covers every depth of the trees when level_name is enabled
+func checkLevelNames(trees []*Area) error {
+	if !hasColumn(columns, "level_name") {
+		return nil
+	}
+	if depth := treeDepth(trees); len(levelNameList) < depth {
+		return dataErrorf("-level-names has %d names but the tree is %d levels deep", len(levelNameList), depth)
+	}
+	return nil
+}
+
+func columnNames(cols []column) string {
+	names := make([]string, 0, len(cols))
+	for _, c := range cols {
+		names = append(names, c.name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// insertPrefix starts an insert statement with all enabled columns
+func insertPrefix() string {
+	names := insertColumns()
+	for i, name := range names {
+		names[i] = quoteIdent(name)
+	}
+	return "INSERT INTO " + tableRef() + "(" + strings.Join(names, ", ") + ") VALUES("
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestInitialColumn(t *testing.T) {
+	out := usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run([]string{"-columns", "initial"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sql := string(data)
+	if !strings.Contains(sql, "(id, node, pid, depth, lft, rgt, initial) VALUES(130100, '石家庄市', 130000, 2, 12, 17, 'S');") {
+		t.Error(sql)
+	}
+}
+
+func TestUnknownColumn(t *testing.T) {
+	usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run([]string{"-columns", "initial,nope"}, &stderr); code != exitUsage {
+		t.Error("exit code:", code)
+	}
+	if !strings.Contains(stderr.String(), `unknown column "nope"`) {
+		t.Error(stderr.String())
+	}
+}
+
+func TestIsLeafColumn(t *testing.T) {
+	cases := []struct {
+		dir, args string
+		want      []string
+	}{
+		{"./testdata/mini", "-columns is_leaf", []string{
+			"VALUES(110101, '东城区', 110100, 3, 3, 8, 0);",
+			"VALUES(110101001000, '东华门街道办事处', 110101, 4, 4, 5, 1);",
+		}},
+		// areas are the finest level when there are no streets
+		{"./testdata/nostreets", "-columns is_leaf", []string{
+			"VALUES(130100, '石家庄市', 130000, 2, 8, 11, 0);",
+			"VALUES(130102, '长安区', 130100, 3, 9, 10, 1);",
+		}},
+		{"./testdata/mini", "-columns is_leaf -drop-placeholders", []string{
+			"VALUES(110000, '北京市', 0, 1, 1, 8, 0);",
+			"VALUES(110101001000, '东华门街道办事处', 110101, 3, 3, 4, 1);",
+		}},
+	}
+	for _, c := range cases {
+		out := usePaths(t, c.dir)
+		var stderr bytes.Buffer
+		if code := run(strings.Fields(c.args), &stderr); code != exitOK {
+			t.Fatal(c.dir, "exit code:", code, stderr.String())
+		}
+		data, err := ioutil.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range c.want {
+			if !strings.Contains(string(data), want) {
+				t.Error(c.dir, c.args, want)
+			}
+		}
+	}
+}
+
+func TestCountColumns(t *testing.T) {
+	cases := []struct {
+		args string
+		want []string
+	}{
+		{"-columns children_count,descendants_count", []string{
+			"VALUES(110000, '北京市', 0, 1, 1, 10, 1, 4);",
+			"VALUES(110101, '东城区', 110100, 3, 3, 8, 2, 2);",
+			"VALUES(110101001000, '东华门街道办事处', 110101, 4, 4, 5, 0, 0);",
+		}},
+		{"-columns children_count,descendants_count -drop-placeholders", []string{
+			"VALUES(110000, '北京市', 0, 1, 1, 8, 1, 3);",
+		}},
+	}
+	for _, c := range cases {
+		out := usePaths(t, "./testdata/mini")
+		var stderr bytes.Buffer
+		if code := run(strings.Fields(c.args), &stderr); code != exitOK {
+			t.Fatal(c.args, "exit code:", code, stderr.String())
+		}
+		data, err := ioutil.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range c.want {
+			if !strings.Contains(string(data), want) {
+				t.Error(c.args, want)
+			}
+		}
+	}
+}
+
+func TestIDPathColumn(t *testing.T) {
+	cases := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"-columns", "id_path"}, []string{
+			"VALUES(110000, '北京市', 0, 1, 1, 10, '110000');",
+			"VALUES(110101001000, '东华门街道办事处', 110101, 4, 4, 5, '110000,110100,110101,110101001000');",
+		}},
+		{[]string{"-columns", "id_path", "-id-path-self=false", "-id-path-sep", "/"}, []string{
+			"VALUES(110000, '北京市', 0, 1, 1, 10, '');",
+			"VALUES(110101001000, '东华门街道办事处', 110101, 4, 4, 5, '110000/110100/110101');",
+		}},
+		// 6+6+6+12+12 digits of the five levels and four separators of 3
+		{[]string{"-columns", "id_path", "-id-path-sep", " / ", "-with-schema"}, []string{
+			"`id_path` VARCHAR(54) NOT NULL DEFAULT ''",
+			"'110000 / 110100 / 110101 / 110101001000'",
+		}},
+	}
+	for _, c := range cases {
+		out := usePaths(t, "./testdata/mini")
+		var stderr bytes.Buffer
+		if code := run(c.args, &stderr); code != exitOK {
+			t.Fatal(c.args, "exit code:", code, stderr.String())
+		}
+		data, err := ioutil.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range c.want {
+			if !strings.Contains(string(data), want) {
+				t.Error(c.args, want)
+			}
+		}
+	}
+}
+
+func TestLevelNameColumn(t *testing.T) {
+	out := usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run([]string{"-columns", "level_name"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"VALUES(110000, '北京市', 0, 1, 1, 10, '省');",
+		"VALUES(110101001000, '东华门街道办事处', 110101, 4, 4, 5, '街道');",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Error(want)
+		}
+	}
+
+	out = usePaths(t, "./testdata/nostreets")
+	if code := run([]string{"-columns", "level_name", "-level-names", "province, city, county"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err = ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "VALUES(130102, '长安区', 130100, 3, 9, 10, 'county');") {
+		t.Error(string(data))
+	}
+
+	usePaths(t, "./testdata/mini")
+	stderr.Reset()
+	if code := run([]string{"-columns", "level_name", "-level-names", "province,city"}, &stderr); code != exitData {
+		t.Error("exit code:", code)
+	}
+	if !strings.Contains(stderr.String(), "-level-names has 2 names but the tree is 4 levels deep") {
+		t.Error(stderr.String())
+	}
+}
+
+func TestFullNameColumn(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-columns", "full_name"}, "'北京市市辖区东城区东华门街道办事处');"},
+		{[]string{"-columns", "full_name", "-full-name-sep", " ", "-full-name-skip-placeholders"}, "'北京市 东城区 东华门街道办事处');"},
+		{[]string{"-columns", "full_name", "-drop-placeholders"}, "'北京市东城区东华门街道办事处');"},
+	}
+	for _, c := range cases {
+		out := usePaths(t, "./testdata/mini")
+		var stderr bytes.Buffer
+		if code := run(c.args, &stderr); code != exitOK {
+			t.Fatal(c.args, "exit code:", code, stderr.String())
+		}
+		data, err := ioutil.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), c.want) {
+			t.Error(c.args, c.want)
+		}
+	}
+}
+
+func TestAncestorCodeColumns(t *testing.T) {
+	cases := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"-columns", "province_code,city_code,area_code"}, []string{
+			"VALUES(110000, '北京市', 0, 1, 1, 10, 110000, NULL, NULL);",
+			"VALUES(110100, '市辖区', 110000, 2, 2, 9, 110000, 110100, NULL);",
+			"VALUES(110101001000, '东华门街道办事处', 110101, 4, 4, 5, 110000, 110100, 110101);",
+			"VALUES(130102, '长安区', 130100, 3, 13, 16, 130000, 130100, 130102);",
+		}},
+		{[]string{"-columns", "province_code,city_code,area_code", "-municipality-city", "province"}, []string{
+			"VALUES(110100, '市辖区', 110000, 2, 2, 9, 110000, 110100, NULL);",
+			"VALUES(110101001000, '东华门街道办事处', 110101, 4, 4, 5, 110000, 110000, 110101);",
+			"VALUES(130102, '长安区', 130100, 3, 13, 16, 130000, 130100, 130102);",
+		}},
+		{[]string{"-columns", "province_code,city_code,area_code", "-drop-placeholders"}, []string{
+			"VALUES(110101, '东城区', 110000, 2, 2, 7, 110000, 110100, 110101);",
+		}},
+		{[]string{"-columns", "province_code,city_code,area_code", "-drop-placeholders", "-municipality-city", "province"}, []string{
+			"VALUES(110101, '东城区', 110000, 2, 2, 7, 110000, 110000, 110101);",
+		}},
+	}
+	for _, c := range cases {
+		out := usePaths(t, "./testdata/mini")
+		var stderr bytes.Buffer
+		if code := run(c.args, &stderr); code != exitOK {
+			t.Fatal(c.args, "exit code:", code, stderr.String())
+		}
+		data, err := ioutil.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range c.want {
+			if !strings.Contains(string(data), want) {
+				t.Error(c.args, want)
+			}
+		}
+	}
+
+	// an area with the code of its city
+	path := []*Area{{Code: "440000"}, {Code: "441900"}, {Code: "441900"}, {Code: "441900003000"}}
+	if codes := ancestorCodes(path); codes != [3]string{"440000", "441900", "441900"} {
+		t.Error(codes)
+	}
+	for i, a := range path[:len(path)-1] {
+		a.SubAreas = []*Area{path[i+1]}
+	}
+	assignAncestorCodes(path[:1])
+	if codes := path[1].AncestorCodes; codes != [3]string{"440000", "441900", ""} {
+		t.Error("assigned to the city:", codes)
+	}
+	if codes := path[3].AncestorCodes; codes != [3]string{"440000", "441900", "441900"} {
+		t.Error("assigned to the street:", codes)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+)
+
+// copyFormat writes PostgreSQL COPY blocks with -copy instead of inserts, which psql loads much faster
+var copyFormat bool
+
+// copyEscaper escapes the characters of values which the text format of COPY takes as delimiters
+var copyEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// copyNull is NULL in the text format of COPY
+const copyNull = `\N`
+
+// copyEnd ends the rows of a COPY block
+const copyEnd = `\.`
+
+// copyPrefix starts a COPY block with all enabled columns
+func copyPrefix() string {
+	names := insertColumns()
+	for i, name := range names {
+		names[i] = quoteIdent(name)
+	}
+	return "COPY " + tableRef() + " (" + strings.Join(names, ", ") + ") FROM stdin;\n"
+}
+
+// genCopy writes the subtree at the end of path as one COPY ... FROM stdin block, a row of tab separated values
+// on each line and \. after the last. The block is one statement for the transactions of the stmtWriter.
+func genCopy(w io.Writer, path []*Area) error {
+	var b bytes.Buffer
+	b.WriteString(copyPrefix())
+	err := walkSubtree(path, func(path []*Area) error {
+		area := path[len(path)-1]
+		b.WriteString(area.Code)
+		b.WriteByte('\t')
+		b.WriteString(copyEscaper.Replace(nodeName(area)))
+		b.WriteByte('\t')
+		b.WriteString(area.ParentCode)
+		b.WriteByte('\t')
+		b.WriteString(itoa(int32(len(path))))
+		b.WriteByte('\t')
+		b.WriteString(itoa(area.Left))
+		b.WriteByte('\t')
+		b.WriteString(itoa(area.Right))
+		for _, c := range columns {
+			v, err := c.get(path)
+			if err != nil {
+				return err
+			}
+			b.WriteByte('\t')
+			if v == "" && c.null {
+				b.WriteString(copyNull)
+			} else {
+				b.WriteString(copyEscaper.Replace(v))
+			}
+		}
+		b.WriteByte('\n')
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	b.WriteString(copyEnd + "\n")
+	_, err = w.Write(b.Bytes())
+	return err
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+)
+
+// dialects are the values of -dialect, the flavour of SQL the inserts are written in
+var dialects = []string{"mysql", "postgres", "sqlite", "mssql", "oracle", "clickhouse", "duckdb"}
+
+// onConflicts are the values of -on-conflict, what MySQL, PostgreSQL, SQLite and DuckDB inserts do about rows
+// whose id exists
+var onConflicts = []string{"nothing", "update"}
+
+var (
+	dialect     = "mysql"
+	dbSchema    string
+	onConflict  string
+	preamble    bool
+	goEvery     int
+	withSchema  bool
+	batchSize   int
+	transaction bool
+	commitEvery int
+	clean       string
+)
+
+func isDialect(name string) bool {
+	for _, d := range dialects {
+		if d == name {
+			return true
+		}
+	}
+	return false
+}
+
+// quoteIdent quotes a table or column name, in brackets for SQL Server, in backquotes for ClickHouse and in double
+// quotes for the others but MySQL, whose names are written as they are unless they are reserved words or not
+// plain identifiers. Quoted names are case sensitive in Oracle, so they are written like in createtable.oracle.sql.
+func quoteIdent(name string) string {
+	switch dialect {
+	case "mysql":
+		if columnName.MatchString(strings.ToLower(name)) && !mysqlReserved[strings.ToUpper(name)] {
+			return name
+		}
+		return "`" + strings.Replace(name, "`", "``", -1) + "`"
+	case "mssql":
+		return "[" + strings.Replace(name, "]", "]]", -1) + "]"
+	case "clickhouse":
+		return "`" + strings.Replace(name, "`", "\\`", -1) + "`"
+	}
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// mysqlReserved are the MySQL reserved words likely to be taken for names of extra columns or tables
+var mysqlReserved = map[string]bool{
+	"ADD": true, "ALL": true, "AND": true, "AS": true, "BY": true, "CHANGE": true, "CHECK": true, "COLUMN": true,
+	"CONDITION": true, "CREATE": true, "CROSS": true, "DATABASE": true, "DEFAULT": true, "DELETE": true, "DESC": true,
+	"DIV": true, "DROP": true, "FROM": true, "FUNCTION": true, "GROUP": true, "GROUPS": true, "HAVING": true,
+	"IN": true, "INDEX": true, "INSERT": true, "INTERVAL": true, "IS": true, "KEY": true, "KEYS": true,
+	"LEFT": true, "LIKE": true, "LIMIT": true, "LINES": true, "LOAD": true, "MATCH": true, "MOD": true, "NOT": true,
+	"NULL": true, "OF": true, "ON": true, "OR": true, "ORDER": true, "OUT": true, "RANGE": true, "RANK": true,
+	"READ": true, "REFERENCES": true, "RELEASE": true, "REPEAT": true, "REPLACE": true, "RIGHT": true, "ROW": true,
+	"ROWS": true, "SELECT": true, "SET": true, "SHOW": true, "SPATIAL": true, "TABLE": true, "TO": true,
+	"UNION": true, "UNIQUE": true, "UPDATE": true, "USAGE": true, "USE": true, "VALUES": true, "WHEN": true,
+	"WHERE": true, "WITH": true, "WRITE": true,
+}
+
+// quoteText quotes a string value with quotes in it doubled, as an N'...' Unicode literal for SQL Server, whose
+// plain literals are in the code page of the database. MySQL and ClickHouse take backslashes for escapes in
+// literals, so they are escaped along with quotes.
+func quoteText(s string) string {
+	var b bytes.Buffer
+	writeText(&b, s)
+	return b.String()
+}
+
+// writeText writes s quoted as by quoteText
+func writeText(b *bytes.Buffer, s string) {
+	escaper := quoteDoubler
+	switch dialect {
+	case "mysql":
+		escaper = mysqlEscaper
+	case "clickhouse":
+		escaper = clickhouseEscaper
+	case "mssql":
+		b.WriteByte('N')
+	}
+	b.WriteByte('\'')
+	escaper.WriteString(b, s)
+	b.WriteByte('\'')
+}
+
+// tableRef is the table the inserts go into, qualified by -db-schema
+func tableRef() string {
+	if dbSchema != "" {
+		return quoteIdent(dbSchema) + "." + quoteIdent(tblName)
+	}
+	return quoteIdent(tblName)
+}
+
+// insertColumns are the columns of the inserts, the fixed ones and then the enabled optional ones
+func insertColumns() []string {
+	names := []string{"id", "node", "pid", "depth", "lft", "rgt"}
+	for _, c := range columns {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+// conflictClause ends the inserts with -on-conflict, the id being the primary key. Updates overwrite all the
+// other columns, so that seeding a database again brings it up to date. MySQL has ON DUPLICATE KEY UPDATE, with
+// VALUES() for MariaDB, and sets the id to itself for nothing, as INSERT IGNORE would ignore other errors too.
+func conflictClause() string {
+	if dialect == "mysql" && onConflict != "" {
+		set := []string{quoteIdent("id") + " = " + quoteIdent("id")}
+		if onConflict == "update" {
+			set = nil
+			for _, name := range insertColumns()[1:] {
+				set = append(set, quoteIdent(name)+" = VALUES("+quoteIdent(name)+")")
+			}
+		}
+		return " ON DUPLICATE KEY UPDATE " + strings.Join(set, ", ")
+	}
+	switch onConflict {
+	case "nothing":
+		return " ON CONFLICT (" + quoteIdent("id") + ") DO NOTHING"
+	case "update":
+		var set []string
+		for _, name := range insertColumns()[1:] {
+			set = append(set, quoteIdent(name)+" = EXCLUDED."+quoteIdent(name))
+		}
+		return " ON CONFLICT (" + quoteIdent("id") + ") DO UPDATE SET " + strings.Join(set, ", ")
+	}
+	return ""
+}
+
+// sqlitePreamble speeds up loading a SQLite seed script with -preamble, the inserts then run in a transaction.
+// The journal is kept in memory and syncs are off, a crash may corrupt the file.
+const sqlitePreamble = "PRAGMA journal_mode = MEMORY;\nPRAGMA synchronous = OFF;\n"
+
+var clickhouseEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+var quoteDoubler = strings.NewReplacer(`'`, `''`)
+
+// mysqlEscaper escapes literals for MySQL without NO_BACKSLASH_ESCAPES, with quotes doubled as before
+var mysqlEscaper = strings.NewReplacer(`\`, `\\`, `'`, `''`)
+
+// clickhouseBatch is the number of rows of ClickHouse inserts, which creates a part of the table for each insert
+const clickhouseBatch = 10000
+
+// mssqlMaxBatch is the most rows a VALUES list takes in SQL Server
+const mssqlMaxBatch = 1000
+
+// duckdbBatch is the number of rows of DuckDB inserts, which are parsed and planned one statement at a time
+const duckdbBatch = 1000
+
+// rowsPerInsert is the number of rows an insert statement takes at most, -batch-size or else the default of the
+// dialect
+func rowsPerInsert() int {
+	if batchSize > 0 {
+		return batchSize
+	}
+	switch dialect {
+	case "clickhouse":
+		return clickhouseBatch
+	case "duckdb":
+		return duckdbBatch
+	}
+	return 1
+}
+
+// oraclePreamble keeps sqlplus from taking & in names for substitution variables, the inserts are committed
+// at the end for sqlplus does not commit by itself
+const oraclePreamble = "SET DEFINE OFF\n"
+
+// cleanStmt empties the table before the inserts with -clean, in the transaction of the inserts if any. Whether
+// TRUNCATE can be rolled back depends on the database, e.g. MySQL commits right away.
+func cleanStmt() string {
+	if clean == "truncate" {
+		return "TRUNCATE TABLE " + tableRef() + ";\n"
+	}
+	return "DELETE FROM " + tableRef() + ";\n"
+}
+
+// scriptHead is written before the inserts
+func scriptHead() string {
+	var head string
+	switch {
+	case dialect == "sqlite" && preamble:
+		head = sqlitePreamble
+	case dialect == "oracle":
+		head = oraclePreamble
+	}
+	if withSchema {
+		head += schemaStmts()
+	}
+	return head
+}
+
+// beginStmt starts a transaction, which Oracle does by itself
+func beginStmt() string {
+	switch dialect {
+	case "mysql":
+		return "START TRANSACTION;\n"
+	case "postgres":
+		return "BEGIN;\n"
+	case "oracle":
+		return ""
+	}
+	return "BEGIN TRANSACTION;\n"
+}
+
+// inTransactions tells whether the inserts run in transactions, with -transaction or -commit-every, and always
+// in Oracle, which sqlplus does not commit, in DuckDB, which is much faster so, and in SQLite with -preamble
+func inTransactions() bool {
+	return transaction || commitEvery > 0 || dialect == "oracle" || dialect == "duckdb" || dialect == "sqlite" && preamble
+}
+
+// stmtWriter writes the statements of genSQL, each Write being one. In transactions it commits every
+// commitEvery statements, or once after all; for SQL Server it ends batches of goEvery statements, or the one
+// batch of all, with GO for sqlcmd and SSMS, which send the statements between separators at once.
+type stmtWriter struct {
+	w           io.Writer
+	tx          bool
+	begin       string
+	commitEvery int
+	batches     bool
+	goEvery     int
+	n           int
+	open        bool // transaction begun and not committed yet
+	unsent      bool // statements after the last GO
+}
+
+func newStmtWriter(w io.Writer) *stmtWriter {
+	return &stmtWriter{w: w, tx: inTransactions(), begin: beginStmt(), commitEvery: commitEvery,
+		batches: dialect == "mssql", goEvery: goEvery}
+}
+
+func (s *stmtWriter) Write(stmt []byte) (int, error) {
+	if s.tx && !s.open {
+		if _, err := io.WriteString(s.w, s.begin); err != nil {
+			return 0, err
+		}
+		s.open = true
+	}
+	n, err := s.w.Write(stmt)
+	if err != nil {
+		return n, err
+	}
+	s.n++
+	s.unsent = true
+	if s.open && s.commitEvery > 0 && s.n%s.commitEvery == 0 {
+		if err = s.commit(); err != nil {
+			return n, err
+		}
+	}
+	if s.batches && s.goEvery > 0 && s.n%s.goEvery == 0 {
+		err = s.sendBatch()
+	}
+	return n, err
+}
+
+func (s *stmtWriter) commit() error {
+	s.open = false
+	s.unsent = true
+	_, err := io.WriteString(s.w, "COMMIT;\n")
+	return err
+}
+
+func (s *stmtWriter) sendBatch() error {
+	s.unsent = false
+	_, err := io.WriteString(s.w, "GO\n")
+	return err
+}
+
+// Close commits the last transaction and ends the last batch
+func (s *stmtWriter) Close() error {
+	if s.open {
+		if err := s.commit(); err != nil {
+			return err
+		}
+	}
+	if s.batches && s.unsent {
+		return s.sendBatch()
+	}
+	return nil
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestPostgresDialect(t *testing.T) {
+	out := usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	args := []string{"-dialect", "postgres", "-db-schema", "geo", "-on-conflict", "update", "-columns", "initial"}
+	if code := run(args, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `INSERT INTO "geo"."nested"("id", "node", "pid", "depth", "lft", "rgt", "initial") ` +
+		`VALUES(130100, '石家庄市', 130000, 2, 12, 17, 'S') ON CONFLICT ("id") DO UPDATE SET "node" = EXCLUDED."node", ` +
+		`"pid" = EXCLUDED."pid", "depth" = EXCLUDED."depth", "lft" = EXCLUDED."lft", "rgt" = EXCLUDED."rgt", ` +
+		`"initial" = EXCLUDED."initial";` + "\n"
+	if !strings.Contains(string(data), want) {
+		t.Error(string(data))
+	}
+
+	// read back like generated MySQL files
+	trees, err := loadTrees(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trees) != 2 || trees[1].Code != "130000" || trees[1].Left != 11 {
+		t.Error(trees)
+	}
+}
+
+func TestDialectFlags(t *testing.T) {
+	cases := [][]string{
+		{"-dialect", "db2"},
+		{"-db-schema", "geo"},
+		{"-dialect", "sqlite", "-db-schema", "geo"},
+		{"-dialect", "mssql", "-on-conflict", "nothing"},
+		{"-dialect", "oracle", "-on-conflict", "update"},
+		{"-dialect", "mssql", "-go-every", "-1"},
+		{"-dialect", "postgres", "-on-conflict", "replace"},
+		{"-dialect", "postgres", "-db-schema", "a.b"},
+		{"-dialect", "postgres", "-normalized", "x.sql"},
+	}
+	for _, args := range cases {
+		usePaths(t, "./testdata/mini")
+		var stderr bytes.Buffer
+		if code := run(args, &stderr); code != exitUsage {
+			t.Error(args, "exit code:", code, stderr.String())
+		}
+	}
+}
+
+func TestSQLiteDialect(t *testing.T) {
+	out := usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run([]string{"-dialect", "sqlite", "-preamble", "-on-conflict", "nothing"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sql := string(data)
+	if !strings.HasPrefix(sql, sqlitePreamble+"BEGIN TRANSACTION;\n"+`INSERT INTO "nested"("id", "node", "pid", "depth", "lft", "rgt") VALUES(110000, `) ||
+		!strings.HasSuffix(sql, `ON CONFLICT ("id") DO NOTHING;`+"\nCOMMIT;\n") {
+		t.Error(sql)
+	}
+	if trees, err := loadTrees(out); err != nil || len(trees) != 2 {
+		t.Error(trees, err)
+	}
+
+	usePaths(t, "./testdata/mini")
+	if code := run([]string{"-dialect", "postgres", "-preamble"}, &stderr); code != exitUsage {
+		t.Error("-preamble with postgres exit code:", code)
+	}
+}
+
+func TestMSSQLDialect(t *testing.T) {
+	out := usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run([]string{"-dialect", "mssql", "-db-schema", "dbo", "-go-every", "4", "-columns", "initial"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 9+3 || lines[4] != "GO" || lines[9] != "GO" || lines[11] != "GO" {
+		t.Error(string(data))
+	}
+	want := "INSERT INTO [dbo].[nested]([id], [node], [pid], [depth], [lft], [rgt], [initial]) " +
+		"VALUES(130100, N'石家庄市', 130000, 2, 12, 17, N'S');"
+	if !strings.Contains(string(data), want) {
+		t.Error(string(data))
+	}
+	if trees, err := loadTrees(out); err != nil || len(trees) != 2 || trees[0].Name != "北京市" {
+		t.Error(trees, err)
+	}
+}
+
+func TestStmtWriter(t *testing.T) {
+	for _, c := range []struct {
+		tx                   bool
+		commitEvery, goEvery int
+		stmts                int
+		want                 string
+	}{
+		{false, 0, 0, 2, "s\ns\n"},
+		{true, 0, 0, 2, "B\ns\ns\nCOMMIT;\n"},
+		{true, 2, 0, 3, "B\ns\ns\nCOMMIT;\nB\ns\nCOMMIT;\n"},
+		{true, 2, 0, 2, "B\ns\ns\nCOMMIT;\n"},
+		{true, 0, 0, 0, ""},
+	} {
+		var buf bytes.Buffer
+		s := &stmtWriter{w: &buf, tx: c.tx, begin: "B\n", commitEvery: c.commitEvery}
+		for i := 0; i < c.stmts; i++ {
+			s.Write([]byte("s\n"))
+		}
+		if err := s.Close(); err != nil || buf.String() != c.want {
+			t.Errorf("%+v: %q %v", c, buf.String(), err)
+		}
+	}
+}
+
+func TestStmtWriterBatches(t *testing.T) {
+	for _, c := range []struct {
+		commitEvery, goEvery, stmts int
+		want                        string
+	}{
+		{0, 2, 4, "s\ns\nGO\ns\ns\nGO\n"},
+		{0, 3, 4, "s\ns\ns\nGO\ns\nGO\n"},
+		{0, 0, 2, "s\ns\nGO\n"},
+		{0, 2, 0, ""},
+		{2, 2, 3, "B\ns\ns\nCOMMIT;\nGO\nB\ns\nCOMMIT;\nGO\n"},
+	} {
+		var buf bytes.Buffer
+		s := &stmtWriter{w: &buf, tx: c.commitEvery > 0, begin: "B\n", commitEvery: c.commitEvery, batches: true, goEvery: c.goEvery}
+		for i := 0; i < c.stmts; i++ {
+			s.Write([]byte("s\n"))
+		}
+		if err := s.Close(); err != nil || buf.String() != c.want {
+			t.Errorf("%+v: %q %v", c, buf.String(), err)
+		}
+	}
+}
+
+func TestOracleDialect(t *testing.T) {
+	out := usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run([]string{"-dialect", "oracle", "-db-schema", "geo"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sql := string(data)
+	if !strings.HasPrefix(sql, "SET DEFINE OFF\n"+`INSERT INTO "geo"."nested"("id", "node", "pid", "depth", "lft", "rgt") VALUES(110000, '北京市', 0, 1, 1, 10);`) ||
+		!strings.HasSuffix(sql, ");\nCOMMIT;\n") {
+		t.Error(sql)
+	}
+	if trees, err := loadTrees(out); err != nil || len(trees) != 2 {
+		t.Error(trees, err)
+	}
+}
+
+func TestClickHouseDialect(t *testing.T) {
+	out := usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run([]string{"-dialect", "clickhouse", "-with-schema", "-db-schema", "geo", "-columns", "initial,is_leaf,province_code"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatal(string(data))
+	}
+	schema := "CREATE TABLE IF NOT EXISTS `geo`.`nested`(`id` Int64, `node` String, `pid` Int64, `depth` Int32, `lft` Int32, " +
+		"`rgt` Int32, `initial` String, `is_leaf` UInt8, `province_code` Nullable(Int64)) ENGINE = MergeTree ORDER BY `lft`;"
+	if lines[0] != schema {
+		t.Error(lines[0])
+	}
+	if !strings.HasPrefix(lines[2], "INSERT INTO `geo`.`nested`(`id`, `node`, `pid`, `depth`, `lft`, `rgt`, `initial`, `is_leaf`, `province_code`) "+
+		"VALUES(130000, '河北省', 0, 1, 11, 18, 'H', 0, 130000), (130100, '石家庄市', 130000, 2, 12, 17, 'S', 0, 130000), ") {
+		t.Error(lines[2])
+	}
+	if trees, err := loadTrees(out); err != nil || len(trees) != 2 || len(trees[1].SubAreas) != 1 {
+		t.Error(trees, err)
+	}
+}
+
+func TestQuoteText(t *testing.T) {
+	defer func(d string) { dialect = d }(dialect)
+	for _, c := range []struct{ dialect, want string }{
+		{"mysql", `'It''s a\\b'`},
+		{"postgres", `'It''s a\b'`},
+		{"mssql", `N'It''s a\b'`},
+		{"clickhouse", `'It\'s a\\b'`},
+	} {
+		dialect = c.dialect
+		if got := quoteText(`It's a\b`); got != c.want {
+			t.Error(c.dialect, got)
+		}
+	}
+}
+
+func TestQuoteIdent(t *testing.T) {
+	defer func(d string) { dialect = d }(dialect)
+	for _, c := range []struct{ dialect, name, want string }{
+		{"mysql", "lng", "lng"},
+		{"mysql", "order", "`order`"},
+		{"mysql", "a`b", "`a``b`"},
+		{"postgres", `a"b`, `"a""b"`},
+		{"mssql", "a]b", "[a]]b]"},
+	} {
+		dialect = c.dialect
+		if got := quoteIdent(c.name); got != c.want {
+			t.Error(c.dialect, got)
+		}
+	}
+}
+
+// TestEscapedNames reads names with quotes and backslashes back from the inserts of each dialect
+func TestEscapedNames(t *testing.T) {
+	defer func(d string) { dialect = d }(dialect)
+	name := `It's a\b "c"`
+	for _, d := range dialects {
+		dialect = d
+		stmts, err := parseSQL(strings.NewReader(insertPrefix() + "1, " + quoteText(name) + ", 0, 1, 1, 2);\n"))
+		if err != nil {
+			t.Fatal(d, err)
+		}
+		if got := stmts[0].values[0][1]; got != name {
+			t.Error(d, got)
+		}
+	}
+}
+
+func TestDuckDBDialect(t *testing.T) {
+	out := usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run([]string{"-dialect", "duckdb", "-with-schema", "-on-conflict", "nothing", "-columns", "lng"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	want := []string{
+		`CREATE TABLE IF NOT EXISTS "nested"("id" BIGINT NOT NULL, "node" VARCHAR NOT NULL, "pid" BIGINT NOT NULL, ` +
+			`"depth" INTEGER NOT NULL, "lft" INTEGER NOT NULL, "rgt" INTEGER NOT NULL, "lng" DOUBLE, PRIMARY KEY ("id"));`,
+		"BEGIN TRANSACTION;",
+		`INSERT INTO "nested"("id", "node", "pid", "depth", "lft", "rgt", "lng") VALUES(110000, '北京市', 0, 1, 1, 10, NULL), `,
+		`INSERT INTO "nested"("id", "node", "pid", "depth", "lft", "rgt", "lng") VALUES(130000, '河北省', 0, 1, 11, 18, NULL), `,
+		"COMMIT;",
+	}
+	if len(lines) != len(want) {
+		t.Fatal(string(data))
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(lines[i], w) {
+			t.Error(lines[i])
+		}
+	}
+	if !strings.HasSuffix(lines[2], `ON CONFLICT ("id") DO NOTHING;`) {
+		t.Error(lines[2])
+	}
+	if trees, err := loadTrees(out); err != nil || len(trees) != 2 {
+		t.Error(trees, err)
+	}
+}
+
+func TestMySQLOnConflict(t *testing.T) {
+	for _, c := range []struct{ mode, want string }{
+		{"update", "INSERT INTO nested(id, node, pid, depth, lft, rgt, initial) VALUES(110000, '北京市', 0, 1, 1, 10, 'B') " +
+			"ON DUPLICATE KEY UPDATE node = VALUES(node), pid = VALUES(pid), depth = VALUES(depth), lft = VALUES(lft), " +
+			"rgt = VALUES(rgt), initial = VALUES(initial);\n"},
+		{"nothing", "INSERT INTO nested(id, node, pid, depth, lft, rgt, initial) VALUES(110000, '北京市', 0, 1, 1, 10, 'B') " +
+			"ON DUPLICATE KEY UPDATE id = id;\n"},
+	} {
+		out := usePaths(t, "./testdata/mini")
+		var stderr bytes.Buffer
+		if code := run([]string{"-on-conflict", c.mode, "-columns", "initial"}, &stderr); code != exitOK {
+			t.Fatal("exit code:", code, stderr.String())
+		}
+		data, err := ioutil.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(data), c.want) {
+			t.Error(c.mode, string(data))
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// change kinds of a tree diff
+const (
+	changeAdded   = "added"
+	changeRemoved = "removed"
+	changeRenamed = "renamed"
+	changeMoved   = "moved"
+)
+
+var changeKinds = []string{changeAdded, changeRemoved, changeRenamed, changeMoved}
+
+// change is a difference of one division between two versions of the trees
+type change struct {
+	Kind          string `json:"type"`
+	Code          string `json:"code"`
+	Name          string `json:"name"`
+	OldName       string `json:"old_name,omitempty"`
+	ParentCode    string `json:"parent_code,omitempty"`
+	OldParentCode string `json:"old_parent_code,omitempty"`
+	Depth         int    `json:"depth"`
+}
+
+// diffNode is a node of one version with its depth
+type diffNode struct {
+	area  *Area
+	depth int
+}
+
+// indexNodes lists nodes by code in preorder, codes may repeat at different depths like 441900
+func indexNodes(trees []*Area) (map[string][]diffNode, []diffNode) {
+	index := make(map[string][]diffNode)
+	var order []diffNode
+	var walk func(areas []*Area, depth int)
+	walk = func(areas []*Area, depth int) {
+		for _, a := range areas {
+			n := diffNode{a, depth}
+			index[a.Code] = append(index[a.Code], n)
+			order = append(order, n)
+			walk(a.SubAreas, depth+1)
+		}
+	}
+	walk(trees, 1)
+	return index, order
+}
+
+// diffTrees compares two versions of the trees by code. Nodes of a repeated code are matched in preorder.
+// Changes of the new version come in its preorder, followed by removed nodes in the preorder of the old one.
+func diffTrees(older, newer []*Area) []change {
+	oldIndex, oldOrder := indexNodes(older)
+	newIndex, newOrder := indexNodes(newer)
+	seen := make(map[string]int)
+	var changes []change
+	for _, n := range newOrder {
+		a := n.area
+		i := seen[a.Code]
+		seen[a.Code]++
+		if i >= len(oldIndex[a.Code]) {
+			changes = append(changes, change{Kind: changeAdded, Code: a.Code, Name: nodeName(a), ParentCode: a.ParentCode, Depth: n.depth})
+			continue
+		}
+		o := oldIndex[a.Code][i].area
+		if nodeName(o) != nodeName(a) {
+			changes = append(changes, change{Kind: changeRenamed, Code: a.Code, Name: nodeName(a), OldName: nodeName(o), Depth: n.depth})
+		}
+		if o.ParentCode != a.ParentCode {
+			changes = append(changes, change{Kind: changeMoved, Code: a.Code, Name: nodeName(a), ParentCode: a.ParentCode,
+				OldParentCode: o.ParentCode, Depth: n.depth})
+		}
+	}
+	removed := make(map[string]int)
+	for _, n := range oldOrder {
+		a := n.area
+		i := removed[a.Code]
+		removed[a.Code]++
+		if i >= len(newIndex[a.Code]) {
+			changes = append(changes, change{Kind: changeRemoved, Code: a.Code, Name: nodeName(a), OldParentCode: a.ParentCode, Depth: n.depth})
+		}
+	}
+	return changes
+}
+
+// diffReport is a diff grouped by province, headlined by counts of each kind of change in total and by depth
+type diffReport struct {
+	Summary   map[string]int         `json:"summary"`
+	Depths    map[int]map[string]int `json:"depths"`
+	Provinces []provinceChanges      `json:"provinces"`
+}
+
+type provinceChanges struct {
+	Code    string   `json:"code"`
+	Name    string   `json:"name"`
+	Changes []change `json:"changes"`
+}
+
+func newDiffReport(older, newer []*Area, changes []change) *diffReport {
+	r := &diffReport{Summary: make(map[string]int), Depths: make(map[int]map[string]int), Provinces: []provinceChanges{}}
+	for _, kind := range changeKinds {
+		r.Summary[kind] = 0
+	}
+	names := make(map[string]string)
+	for _, p := range older {
+		names[p.Code] = nodeName(p)
+	}
+	for _, p := range newer {
+		names[p.Code] = nodeName(p)
+	}
+	byProvince := make(map[string]int)
+	for _, c := range changes {
+		r.Summary[c.Kind]++
+		if r.Depths[c.Depth] == nil {
+			r.Depths[c.Depth] = make(map[string]int)
+		}
+		r.Depths[c.Depth][c.Kind]++
+
+		p := getProvince(c.Code)
+		i, ok := byProvince[p]
+		if !ok {
+			i = len(r.Provinces)
+			byProvince[p] = i
+			r.Provinces = append(r.Provinces, provinceChanges{Code: p, Name: names[p]})
+		}
+		r.Provinces[i].Changes = append(r.Provinces[i].Changes, c)
+	}
+	sort.SliceStable(r.Provinces, func(i, j int) bool {
+		return r.Provinces[i].Code < r.Provinces[j].Code
+	})
+	return r
+}
+
+// counts formats counts of change kinds, e.g. "added 2, renamed 1"
+func counts(m map[string]int, zeros bool) string {
+	var parts []string
+	for _, kind := range changeKinds {
+		if m[kind] > 0 || zeros {
+			parts = append(parts, fmt.Sprintf("%s %d", kind, m[kind]))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (r *diffReport) depths() []int {
+	depths := make([]int, 0, len(r.Depths))
+	for d := range r.Depths {
+		depths = append(depths, d)
+	}
+	sort.Ints(depths)
+	return depths
+}
+
+// describe tells what changed, e.g. "130100 石家庄市 → 石家庄"
+func (c change) describe() string {
+	switch c.Kind {
+	case changeAdded:
+		return fmt.Sprintf("%s %s under %s", c.Code, c.Name, c.ParentCode)
+	case changeRenamed:
+		return fmt.Sprintf("%s %s → %s", c.Code, c.OldName, c.Name)
+	case changeMoved:
+		return fmt.Sprintf("%s %s from %s to %s", c.Code, c.Name, c.OldParentCode, c.ParentCode)
+	}
+	return fmt.Sprintf("%s %s under %s", c.Code, c.Name, c.OldParentCode)
+}
+
+func (r *diffReport) writeText(w io.Writer) error {
+	fmt.Fprintf(w, "changes: %s\n", counts(r.Summary, true))
+	for _, d := range r.depths() {
+		fmt.Fprintf(w, "depth %d: %s\n", d, counts(r.Depths[d], false))
+	}
+	for _, p := range r.Provinces {
+		fmt.Fprintf(w, "\n%s %s\n", p.Code, p.Name)
+		for _, c := range p.Changes {
+			fmt.Fprintf(w, "  %-8s %s\n", c.Kind, c.describe())
+		}
+	}
+	return nil
+}
+
+func (r *diffReport) writeMarkdown(w io.Writer) error {
+	fmt.Fprintf(w, "# Division changes\n\n| depth | %s |\n|---|%s\n", strings.Join(changeKinds, " | "), strings.Repeat("---|", len(changeKinds)))
+	row := func(label string, m map[string]int) {
+		fmt.Fprintf(w, "| %s |", label)
+		for _, kind := range changeKinds {
+			fmt.Fprintf(w, " %d |", m[kind])
+		}
+		fmt.Fprintln(w)
+	}
+	for _, d := range r.depths() {
+		row(itoa(int32(d)), r.Depths[d])
+	}
+	row("total", r.Summary)
+	for _, p := range r.Provinces {
+		fmt.Fprintf(w, "\n## %s %s\n\n", p.Code, p.Name)
+		for _, c := range p.Changes {
+			fmt.Fprintf(w, "- %s %s\n", c.Kind, c.describe())
+		}
+	}
+	return nil
+}
+
+func (r *diffReport) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// runDiff compares two versions of the division data, each a data directory or a generated sql file
+func runDiff(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("division diff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "text", "report as text, markdown or json")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: division diff [-format text|markdown|json] old new")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	writers := map[string]func(*diffReport, io.Writer) error{
+		"text":     (*diffReport).writeText,
+		"markdown": (*diffReport).writeMarkdown,
+		"json":     (*diffReport).writeJSON,
+	}
+	write, ok := writers[*format]
+	if fs.NArg() != 2 || !ok {
+		fs.Usage()
+		return exitUsage
+	}
+
+	older, err := loadTrees(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(stderr, "division diff:", err)
+		return exitCode(err)
+	}
+	newer, err := loadTrees(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintln(stderr, "division diff:", err)
+		return exitCode(err)
+	}
+	err = write(newDiffReport(older, newer, diffTrees(older, newer)), stdout)
+	if err != nil {
+		fmt.Fprintln(stderr, "division diff:", err)
+		return exitIO
+	}
+	return exitOK
+}
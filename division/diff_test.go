@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDiffTrees(t *testing.T) {
+	older := []*Area{{Code: "1", Name: "a", ParentCode: "0", SubAreas: []*Area{
+		{Code: "11", Name: "b", ParentCode: "1"},
+		{Code: "12", Name: "c", ParentCode: "1", SubAreas: []*Area{{Code: "12", Name: "c", ParentCode: "12"}}},
+	}}}
+	newer := []*Area{{Code: "1", Name: "a", ParentCode: "0", SubAreas: []*Area{
+		{Code: "12", Name: "C", ParentCode: "1", SubAreas: []*Area{
+			{Code: "12", Name: "c", ParentCode: "12"},
+			{Code: "11", Name: "b", ParentCode: "12"},
+		}},
+		{Code: "13", Name: "d", ParentCode: "1"},
+	}}}
+	var got []string
+	for _, c := range diffTrees(older, newer) {
+		got = append(got, c.Kind+" "+c.describe())
+	}
+	want := []string{
+		"renamed 12 c → C",
+		"moved 11 b from 1 to 12",
+		"added 13 d under 1",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Error(got)
+	}
+	if changes := diffTrees(older, older[:0]); len(changes) != 4 || changes[3].Kind != changeRemoved || changes[3].Depth != 3 {
+		t.Error(changes)
+	}
+}
+
+func TestRunDiff(t *testing.T) {
+	out := useStdout(t)
+	var stderr bytes.Buffer
+	if code := run([]string{"diff", "./testdata/mini", "./testdata/mini2"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	want := `changes: added 1, removed 1, renamed 1, moved 0
+depth 2: renamed 1
+depth 3: added 1
+depth 4: removed 1
+
+130000 河北省
+  renamed  130100 石家庄市 → 石家庄
+  added    130104 桥西区 under 130100
+  removed  130102001000 建北街道办事处 under 130102
+`
+	if out.String() != want {
+		t.Error(out.String())
+	}
+
+	// a generated sql file against a data directory
+	sqlFile := usePaths(t, "./testdata/mini")
+	if code := run(nil, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	out.Reset()
+	if code := run([]string{"diff", "-format", "json", sqlFile, "./testdata/mini2"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	var report diffReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Summary[changeAdded] != 1 || report.Depths[2][changeRenamed] != 1 || len(report.Provinces) != 1 ||
+		report.Provinces[0].Name != "河北省" || len(report.Provinces[0].Changes) != 3 {
+		t.Error(out.String())
+	}
+
+	out.Reset()
+	if code := run([]string{"diff", "-format", "markdown", "./testdata/mini", "./testdata/mini2"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	if !strings.Contains(out.String(), "| total | 1 | 1 | 1 | 0 |") || !strings.Contains(out.String(), "## 130000 河北省") {
+		t.Error(out.String())
+	}
+
+	for _, args := range [][]string{{"diff", "./testdata/mini"}, {"diff", "-format", "xml", "./testdata/mini", "./testdata/mini2"}} {
+		if code := run(args, &stderr); code != exitUsage {
+			t.Error(args, "exit code:", code)
+		}
+	}
+	if code := run([]string{"diff", "./testdata/mini", "./testdata/none"}, &stderr); code != exitIO {
+		t.Error("exit code:", code)
+	}
+}
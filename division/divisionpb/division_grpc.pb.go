@@ -0,0 +1,246 @@
+// Division service of the serve-grpc subcommand, the RPC counterpart of the REST endpoints of serve.
+// Go code is generated into ../divisionpb by `go generate -tags grpc` in the division directory.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: division.proto
+
+package divisionpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	DivisionService_Lookup_FullMethodName    = "/nested.division.v1.DivisionService/Lookup"
+	DivisionService_Children_FullMethodName  = "/nested.division.v1.DivisionService/Children"
+	DivisionService_Ancestors_FullMethodName = "/nested.division.v1.DivisionService/Ancestors"
+	DivisionService_Search_FullMethodName    = "/nested.division.v1.DivisionService/Search"
+)
+
+// DivisionServiceClient is the client API for DivisionService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type DivisionServiceClient interface {
+	// Lookup returns the division of a code, NOT_FOUND if there is none
+	Lookup(ctx context.Context, in *CodeRequest, opts ...grpc.CallOption) (*Division, error)
+	// Children returns the children of a division
+	Children(ctx context.Context, in *CodeRequest, opts ...grpc.CallOption) (*DivisionList, error)
+	// Ancestors returns the ancestors of a division from the province down, none for a province
+	Ancestors(ctx context.Context, in *CodeRequest, opts ...grpc.CallOption) (*DivisionList, error)
+	// Search returns the candidates of names as users type them, best first
+	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*DivisionList, error)
+}
+
+type divisionServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDivisionServiceClient(cc grpc.ClientConnInterface) DivisionServiceClient {
+	return &divisionServiceClient{cc}
+}
+
+func (c *divisionServiceClient) Lookup(ctx context.Context, in *CodeRequest, opts ...grpc.CallOption) (*Division, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Division)
+	err := c.cc.Invoke(ctx, DivisionService_Lookup_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *divisionServiceClient) Children(ctx context.Context, in *CodeRequest, opts ...grpc.CallOption) (*DivisionList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DivisionList)
+	err := c.cc.Invoke(ctx, DivisionService_Children_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *divisionServiceClient) Ancestors(ctx context.Context, in *CodeRequest, opts ...grpc.CallOption) (*DivisionList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DivisionList)
+	err := c.cc.Invoke(ctx, DivisionService_Ancestors_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *divisionServiceClient) Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*DivisionList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DivisionList)
+	err := c.cc.Invoke(ctx, DivisionService_Search_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DivisionServiceServer is the server API for DivisionService service.
+// All implementations must embed UnimplementedDivisionServiceServer
+// for forward compatibility.
+type DivisionServiceServer interface {
+	// Lookup returns the division of a code, NOT_FOUND if there is none
+	Lookup(context.Context, *CodeRequest) (*Division, error)
+	// Children returns the children of a division
+	Children(context.Context, *CodeRequest) (*DivisionList, error)
+	// Ancestors returns the ancestors of a division from the province down, none for a province
+	Ancestors(context.Context, *CodeRequest) (*DivisionList, error)
+	// Search returns the candidates of names as users type them, best first
+	Search(context.Context, *SearchRequest) (*DivisionList, error)
+	mustEmbedUnimplementedDivisionServiceServer()
+}
+
+// UnimplementedDivisionServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedDivisionServiceServer struct{}
+
+func (UnimplementedDivisionServiceServer) Lookup(context.Context, *CodeRequest) (*Division, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Lookup not implemented")
+}
+func (UnimplementedDivisionServiceServer) Children(context.Context, *CodeRequest) (*DivisionList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Children not implemented")
+}
+func (UnimplementedDivisionServiceServer) Ancestors(context.Context, *CodeRequest) (*DivisionList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Ancestors not implemented")
+}
+func (UnimplementedDivisionServiceServer) Search(context.Context, *SearchRequest) (*DivisionList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Search not implemented")
+}
+func (UnimplementedDivisionServiceServer) mustEmbedUnimplementedDivisionServiceServer() {}
+func (UnimplementedDivisionServiceServer) testEmbeddedByValue()                         {}
+
+// UnsafeDivisionServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DivisionServiceServer will
+// result in compilation errors.
+type UnsafeDivisionServiceServer interface {
+	mustEmbedUnimplementedDivisionServiceServer()
+}
+
+func RegisterDivisionServiceServer(s grpc.ServiceRegistrar, srv DivisionServiceServer) {
+	// If the following call pancis, it indicates UnimplementedDivisionServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&DivisionService_ServiceDesc, srv)
+}
+
+func _DivisionService_Lookup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DivisionServiceServer).Lookup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DivisionService_Lookup_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DivisionServiceServer).Lookup(ctx, req.(*CodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DivisionService_Children_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DivisionServiceServer).Children(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DivisionService_Children_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DivisionServiceServer).Children(ctx, req.(*CodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DivisionService_Ancestors_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DivisionServiceServer).Ancestors(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DivisionService_Ancestors_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DivisionServiceServer).Ancestors(ctx, req.(*CodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DivisionService_Search_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DivisionServiceServer).Search(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DivisionService_Search_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DivisionServiceServer).Search(ctx, req.(*SearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// DivisionService_ServiceDesc is the grpc.ServiceDesc for DivisionService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var DivisionService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "nested.division.v1.DivisionService",
+	HandlerType: (*DivisionServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Lookup",
+			Handler:    _DivisionService_Lookup_Handler,
+		},
+		{
+			MethodName: "Children",
+			Handler:    _DivisionService_Children_Handler,
+		},
+		{
+			MethodName: "Ancestors",
+			Handler:    _DivisionService_Ancestors_Handler,
+		},
+		{
+			MethodName: "Search",
+			Handler:    _DivisionService_Search_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "division.proto",
+}
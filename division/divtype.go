@@ -0,0 +1,139 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// division types of the division_type column
+const (
+	typeProvince             = "province"
+	typeAutonomousRegion     = "autonomous_region"
+	typeMunicipality         = "municipality"
+	typeSAR                  = "sar"
+	typePrefectureCity       = "prefecture_city"
+	typePrefecture           = "prefecture"
+	typeAutonomousPrefecture = "autonomous_prefecture"
+	typeLeague               = "league"
+	typePlaceholder          = "placeholder"
+	typeDistrict             = "district"
+	typeCountyCity           = "county_city"
+	typeCounty               = "county"
+	typeAutonomousCounty     = "autonomous_county"
+	typeBanner               = "banner"
+	typeAutonomousBanner     = "autonomous_banner"
+	typeForestDistrict       = "forest_district"
+	typeSpecialDistrict      = "special_district"
+	typeSubdistrict          = "subdistrict"
+	typeTown                 = "town"
+	typeTownship             = "township"
+	typeEthnicTownship       = "ethnic_township"
+	typeSumu                 = "sumu"
+	typeEthnicSumu           = "ethnic_sumu"
+	typeFarm                 = "farm"
+	typeZone                 = "zone"
+	typeOther                = "other"
+)
+
+// divisionTypeRules classify divisions by the level of their codes and the suffix of their names, the first
+// matching rule applies so longer suffixes come first. Level 0 matches codes of any level.
+var divisionTypeRules = []struct {
+	level  int
+	suffix string
+	typ    string
+}{
+	{1, "特别行政区", typeSAR},
+	{1, "自治区", typeAutonomousRegion},
+	{1, "省", typeProvince},
+	{1, "市", typeMunicipality},
+
+	{2, "行政区划", typePlaceholder}, // 省直辖县级行政区划
+	{2, "市辖区", typePlaceholder},
+	{2, "县", typePlaceholder},
+	{2, "自治州", typeAutonomousPrefecture},
+	{2, "地区", typePrefecture},
+	{2, "盟", typeLeague},
+	{2, "市", typePrefectureCity},
+
+	{3, "自治县", typeAutonomousCounty},
+	{3, "自治旗", typeAutonomousBanner},
+	{3, "林区", typeForestDistrict},
+	{3, "特区", typeSpecialDistrict},
+	{3, "区", typeDistrict},
+	{3, "市", typeCountyCity},
+	{3, "县", typeCounty},
+	{3, "旗", typeBanner},
+
+	{4, "街道办事处", typeSubdistrict},
+	{4, "办事处", typeSubdistrict},
+	{4, "街道办", typeSubdistrict},
+	{4, "街道", typeSubdistrict},
+	{4, "族苏木", typeEthnicSumu},
+	{4, "苏木", typeSumu},
+	{4, "族乡", typeEthnicTownship},
+	{4, "镇", typeTown},
+	{4, "乡", typeTownship},
+	{4, "场", typeFarm}, // 农场, 林场, 牧场
+
+	{0, "开发区", typeZone},
+	{0, "园区", typeZone},
+	{0, "示范区", typeZone},
+	{0, "试验区", typeZone},
+	{0, "实验区", typeZone},
+	{0, "管理区", typeZone},
+	{0, "工业区", typeZone},
+	{0, "新区", typeZone},
+	{0, "新城", typeZone},
+	{0, "经济区", typeZone},
+	{0, "保税区", typeZone},
+	{0, "保税港区", typeZone},
+	{0, "商务区", typeZone},
+	{0, "工业园", typeZone},
+	{0, "产业园", typeZone},
+	{0, "产业基地", typeZone},
+	{0, "管理委员会", typeZone},
+	{0, "管委会", typeZone},
+}
+
+// divisionType classifies a division by the rules, or as other if none matches
+func divisionType(code, name string) string {
+	level := codeLevel(code)
+	for _, r := range divisionTypeRules {
+		if (r.level == 0 || r.level == level) && strings.HasSuffix(name, r.suffix) {
+			return r.typ
+		}
+	}
+	return typeOther
+}
+
+// classify assigns division types, and logs how many nodes there are of each type and which are other
+func classify(trees []*Area) {
+	counts := make(map[string]int)
+	var others []string
+	var walk func(areas []*Area)
+	walk = func(areas []*Area) {
+		for _, a := range areas {
+			a.DivisionType = divisionType(a.Code, nodeName(a))
+			counts[a.DivisionType]++
+			if a.DivisionType == typeOther {
+				others = append(others, a.Code+" "+nodeName(a))
+			}
+			walk(a.SubAreas)
+		}
+	}
+	walk(trees)
+
+	types := make([]string, 0, len(counts))
+	for typ := range counts {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	args := make([]interface{}, 0, 2*len(types))
+	for _, typ := range types {
+		args = append(args, typ, counts[typ])
+	}
+	logger.Info("division types", args...)
+	if len(others) > 0 {
+		logger.Warn("division types unclassified", "count", len(others), "nodes", strings.Join(others, ", "))
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestDivisionType(t *testing.T) {
+	cases := []struct {
+		code, name, want string
+	}{
+		{"130000", "河北省", typeProvince},
+		{"450000", "广西壮族自治区", typeAutonomousRegion},
+		{"110000", "北京市", typeMunicipality},
+		{"810000", "香港特别行政区", typeSAR},
+		{"130100", "石家庄市", typePrefectureCity},
+		{"232700", "大兴安岭地区", typePrefecture},
+		{"422800", "恩施土家族苗族自治州", typeAutonomousPrefecture},
+		{"152200", "兴安盟", typeLeague},
+		{"110100", "市辖区", typePlaceholder},
+		{"500200", "县", typePlaceholder},
+		{"419000", "省直辖县级行政区划", typePlaceholder},
+		{"110101", "东城区", typeDistrict},
+		{"130181", "辛集市", typeCountyCity},
+		{"130121", "井陉县", typeCounty},
+		{"130826", "丰宁满族自治县", typeAutonomousCounty},
+		{"150523", "开鲁县", typeCounty},
+		{"150221", "土默特右旗", typeBanner},
+		{"150724", "鄂温克族自治旗", typeAutonomousBanner},
+		{"429021", "神农架林区", typeForestDistrict},
+		{"520203", "六枝特区", typeSpecialDistrict},
+		{"110101001000", "东华门街道办事处", typeSubdistrict},
+		{"110101002000", "景山街道", typeSubdistrict},
+		{"130102100000", "东营镇", typeTown},
+		{"130121200000", "南峪乡", typeTownship},
+		{"130826200000", "万胜永乡", typeTownship},
+		{"130826201000", "四岔口乡", typeTownship},
+		{"130929200000", "大褚村回族乡", typeEthnicTownship},
+		{"150784200000", "敖鲁古雅鄂温克族苏木", typeEthnicSumu},
+		{"150521200000", "巴彦塔拉苏木", typeSumu},
+		{"130209450000", "唐海农场", typeFarm},
+		{"130102400000", "石家庄经济技术开发区", typeZone},
+		{"130982400000", "华北石油管理局", typeOther},
+		// code rules tell a municipality from a county-level city
+		{"130181", "北京市", typeCountyCity},
+	}
+	for _, c := range cases {
+		if got := divisionType(c.code, c.name); got != c.want {
+			t.Errorf("%s %s: %s, want %s", c.code, c.name, got, c.want)
+		}
+	}
+}
+
+func TestDivisionTypeColumn(t *testing.T) {
+	out := usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run([]string{"-columns", "division_type"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"VALUES(110000, '北京市', 0, 1, 1, 10, 'municipality');",
+		"VALUES(110100, '市辖区', 110000, 2, 2, 9, 'placeholder');",
+		"VALUES(130102001000, '建北街道办事处', 130102, 4, 14, 15, 'subdistrict');",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Error(want)
+		}
+	}
+}
@@ -0,0 +1,6 @@
+//go:build mysql
+
+package main
+
+// the database/sql driver of -dsn with -dialect mysql
+import _ "github.com/go-sql-driver/mysql"
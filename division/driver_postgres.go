@@ -0,0 +1,6 @@
+//go:build postgres
+
+package main
+
+// the database/sql driver of -dsn with -dialect postgres
+import _ "github.com/lib/pq"
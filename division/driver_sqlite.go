@@ -0,0 +1,6 @@
+//go:build sqlite
+
+package main
+
+// the database/sql driver of -dsn with -dialect sqlite
+import _ "modernc.org/sqlite"
@@ -0,0 +1,52 @@
+//go:build sqlite
+
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+// TestLoadSQLite loads the rows into a SQLite database with the driver of the sqlite tag, then again over
+// them, and checks the table
+func TestLoadSQLite(t *testing.T) {
+	usePaths(t, "./testdata/mini")
+	dsn := filepath.Join(t.TempDir(), "geo.db")
+	var stderr bytes.Buffer
+	for _, args := range [][]string{
+		{"-dialect", "sqlite", "-dsn", dsn, "-with-schema", "-columns", "full_name"},
+		{"-dialect", "sqlite", "-dsn", dsn, "-with-schema", "-columns", "full_name", "-clean", "delete", "-commit-every", "4"},
+	} {
+		if code := run(args, &stderr); code != exitOK {
+			t.Fatal(args, "exit code:", code, stderr.String())
+		}
+	}
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM nested`).Scan(&count); err != nil || count != 9 {
+		t.Error("rows:", count, err)
+	}
+	var node, path string
+	var pid, depth, lft, rgt int64
+	err = db.QueryRow(`SELECT node, pid, depth, lft, rgt, full_name FROM nested WHERE id = 110101`).Scan(&node, &pid,
+		&depth, &lft, &rgt, &path)
+	if err != nil || node != "东城区" || pid != 110100 || depth != 3 || lft != 3 || rgt != 8 || path != "北京市市辖区东城区" {
+		t.Error(node, pid, depth, lft, rgt, path, err)
+	}
+	// the descendants of a node by its keys, as the table is queried
+	if err := db.QueryRow(`SELECT COUNT(*) FROM nested WHERE lft > 11 AND rgt < 18`).Scan(&count); err != nil || count != 3 {
+		t.Error("descendants of 130000:", count, err)
+	}
+
+	stderr.Reset()
+	if code := runVerify([]string{"-dialect", "sqlite", "-table", "nested", "-dsn", dsn}, &stderr); code != exitOK {
+		t.Error("verify exit code:", code, stderr.String())
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// englishSuffixes translate administrative suffixes for generated English names, the first matching one
+// applies so longer suffixes come first
+var englishSuffixes = []struct {
+	suffix     string
+	english    string
+	autonomous bool
+}{
+	{"经济技术开发区", "Economic and Technological Development Zone", false},
+	{"特别行政区", "Special Administrative Region", false},
+	{"街道办事处", "Subdistrict", false},
+	{"自治区", "Autonomous Region", true},
+	{"自治州", "Autonomous Prefecture", true},
+	{"自治县", "Autonomous County", true},
+	{"自治旗", "Autonomous Banner", true},
+	{"开发区", "Development Zone", false},
+	{"管理区", "Administrative Zone", false},
+	{"街道办", "Subdistrict", false},
+	{"办事处", "Subdistrict", false},
+	{"街道", "Subdistrict", false},
+	{"地区", "Prefecture", false},
+	{"新区", "New Area", false},
+	{"林区", "Forest District", false},
+	{"矿区", "Mining District", false},
+	{"苏木乡", "Sumu", false},
+	{"苏木", "Sumu", false},
+	{"省", "Province", false},
+	{"市", "City", false},
+	{"区", "District", false},
+	{"县", "County", false},
+	{"盟", "League", false},
+	{"旗", "Banner", false},
+	{"镇", "Town", false},
+	{"乡", "Township", false},
+}
+
+// englishPlaceholders are the English names of placeholder nodes
+var englishPlaceholders = map[string]string{
+	"市辖区":         "Municipal Districts",
+	"县":           "Counties",
+	"省直辖县级行政区划":   "County-level Divisions",
+	"自治区直辖县级行政区划": "County-level Divisions",
+}
+
+// englishStems are conventional English names which are not the pinyin, e.g. 陕西 is Shaanxi to tell it from 山西
+var englishStems = map[string]string{
+	"内蒙古": "Inner Mongolia",
+	"西藏":  "Tibet",
+	"陕西":  "Shaanxi",
+	"香港":  "Hong Kong",
+	"澳门":  "Macao",
+}
+
+// englishEthnic are English names of ethnic groups which are not the pinyin, e.g. 维吾尔 is Uygur
+var englishEthnic = map[string]string{
+	"蒙古":   "Mongol",
+	"藏":    "Tibetan",
+	"维吾尔":  "Uygur",
+	"朝鲜":   "Korean",
+	"满":    "Manchu",
+	"哈萨克":  "Kazak",
+	"柯尔克孜": "Kirgiz",
+	"达斡尔":  "Daur",
+	"锡伯":   "Xibe",
+	"塔吉克":  "Tajik",
+	"撒拉":   "Salar",
+	"鄂温克":  "Evenki",
+	"鄂伦春":  "Oroqen",
+	"俄罗斯":  "Russian",
+	"乌孜别克": "Uzbek",
+	"塔塔尔":  "Tatar",
+	"各":    "Multi-ethnic",
+}
+
+// englishName generates the English name of a division from the pinyin of its name and the translation of
+// its suffix, e.g. 兴安盟 → Xing'an League, 恩施土家族苗族自治州 → Enshi Tujia and Miao Autonomous Prefecture.
+// Municipalities at depth 1 get no suffix, e.g. 北京市 → Beijing.
+func englishName(name string, depth int) string {
+	if english, ok := englishPlaceholders[name]; ok {
+		return english
+	}
+	for _, r := range englishSuffixes {
+		if !strings.HasSuffix(name, r.suffix) || name == r.suffix {
+			continue
+		}
+		stem, groups := splitEthnic(strings.TrimSuffix(name, r.suffix), r.autonomous)
+		words := []string{romanize(stem)}
+		if english, ok := englishEthnic[stem]; ok && r.autonomous {
+			words[0] = english // named after the ethnic group only, e.g. 鄂伦春自治旗
+		}
+		if len(groups) > 0 {
+			words = append(words, englishGroups(groups, r.autonomous))
+		}
+		if depth == 1 && r.suffix == "市" {
+			return words[0]
+		}
+		return strings.Join(append(words, r.english), " ")
+	}
+	return romanize(name)
+}
+
+// englishGroups names the ethnic groups of an autonomous division, e.g. Tujia and Miao, or tells that a
+// township is one of ethnic minorities, e.g. 鄂伦春民族乡 → Elunchun Ethnic Township
+func englishGroups(groups []string, autonomous bool) string {
+	if !autonomous {
+		return "Ethnic"
+	}
+	names := make([]string, 0, len(groups))
+	for _, g := range groups {
+		if g == "民" {
+			continue
+		}
+		if english, ok := englishEthnic[g]; ok {
+			names = append(names, english)
+		} else {
+			names = append(names, romanize(g))
+		}
+	}
+	switch len(names) {
+	case 0:
+		return "Ethnic"
+	case 1:
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+// romanize spells name in pinyin as one capitalized word, with apostrophes before syllables starting with a
+// vowel, e.g. 西安 → Xi'an
+func romanize(name string) string {
+	if english, ok := englishStems[name]; ok {
+		return english
+	}
+	var b strings.Builder
+	for i, syllable := range pinyin(name) {
+		if i > 0 && strings.IndexByte("aeo", syllable[0]) >= 0 {
+			b.WriteByte('\'')
+		}
+		b.WriteString(syllable)
+	}
+	word := []rune(b.String())
+	if len(word) > 0 {
+		word[0] = unicode.ToUpper(word[0])
+	}
+	return string(word)
+}
+
+// translateNames assigns generated English names to nodes without one from -translations, and logs how many
+// of each there are
+func translateNames(trees []*Area) {
+	translated, generated := 0, 0
+	var walk func(areas []*Area, depth int)
+	walk = func(areas []*Area, depth int) {
+		for _, a := range areas {
+			if a.EnglishName != "" {
+				translated++
+			} else {
+				a.EnglishName = englishName(nodeName(a), depth)
+				generated++
+			}
+			walk(a.SubAreas, depth+1)
+		}
+	}
+	walk(trees, 1)
+	logger.Info("english names", "translated", translated, "generated", generated)
+}
+
+// loadTranslations attaches English names of -translations to the trees
+func loadTranslations(trees []*Area) error {
+	mapping, err := readMapping(translationsFile, "en_name")
+	if err != nil {
+		return err
+	}
+	attach(trees, "translations", mappingCodes(mapping), func(area *Area) bool {
+		area.EnglishName = strings.TrimSpace(mapping[area.Code])
+		return area.EnglishName != ""
+	})
+	return nil
+}
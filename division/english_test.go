@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestEnglishName(t *testing.T) {
+	cases := []struct {
+		name  string
+		depth int
+		want  string
+	}{
+		// municipalities and their placeholders
+		{"北京市", 1, "Beijing"},
+		{"重庆市", 1, "Chongqing"},
+		{"市辖区", 2, "Municipal Districts"},
+		{"县", 2, "Counties"},
+		{"东城区", 3, "Dongcheng District"},
+		// provinces and autonomous regions
+		{"河北省", 1, "Hebei Province"},
+		{"陕西省", 1, "Shaanxi Province"},
+		{"内蒙古自治区", 1, "Inner Mongolia Autonomous Region"},
+		{"广西壮族自治区", 1, "Guangxi Zhuang Autonomous Region"},
+		{"新疆维吾尔自治区", 1, "Xinjiang Uygur Autonomous Region"},
+		{"香港特别行政区", 1, "Hong Kong Special Administrative Region"},
+		// leagues and banners of Inner Mongolia
+		{"兴安盟", 2, "Xing'an League"},
+		{"锡林郭勒盟", 2, "Xilinguole League"},
+		{"科尔沁右翼前旗", 3, "Ke'erqinyouyiqian Banner"},
+		{"鄂伦春自治旗", 3, "Oroqen Autonomous Banner"},
+		{"莫力达瓦达斡尔族自治旗", 3, "Molidawa Daur Autonomous Banner"},
+		{"巴彦木仁苏木乡", 4, "Bayanmuren Sumu"},
+		// prefectures and counties
+		{"恩施土家族苗族自治州", 2, "Enshi Tujia and Miao Autonomous Prefecture"},
+		{"大兴安岭地区", 2, "Daxing'anling Prefecture"},
+		{"西安市", 2, "Xi'an City"},
+		{"沙县", 3, "Sha County"},
+		{"喀什市", 3, "Kashi City"},
+		{"东华门街道办事处", 4, "Donghuamen Subdistrict"},
+		{"景山街道", 4, "Jingshan Subdistrict"},
+	}
+	for _, c := range cases {
+		if got := englishName(c.name, c.depth); got != c.want {
+			t.Errorf("%s: %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestEnglishNameColumn(t *testing.T) {
+	out := usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run([]string{"-translations", "./testdata/enrich/translations.csv"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"(id, node, pid, depth, lft, rgt, en_name) VALUES(110000, '北京市', 0, 1, 1, 10, 'Peking');",
+		"VALUES(130000, '河北省', 0, 1, 11, 18, 'Hebei Province');",
+		"VALUES(130102, '长安区', 130100, 3, 13, 16, 'Chang''an District');",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Error(want)
+		}
+	}
+}
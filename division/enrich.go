@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// readMapping reads a code to value mapping of column, from a JSON object keyed by code or from CSV rows of
+// code and value with an optional header. Files with values of several columns, e.g. a header of code,postcode
+// and dialing_code or JSON objects like {"postcode":"100000"}, give the values of column.
+func readMapping(name, column string) (map[string]string, error) {
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		var raw map[string]json.RawMessage
+		if err := readJSONFile(name, &raw); err != nil {
+			return nil, err
+		}
+		m := make(map[string]string, len(raw))
+		for code, value := range raw {
+			var s string
+			if err := json.Unmarshal(value, &s); err == nil {
+				m[code] = s
+				continue
+			}
+			var values map[string]string
+			if err := json.Unmarshal(value, &values); err != nil {
+				return nil, dataErrorf("%s: value of %s is neither a string nor an object of strings", name, code)
+			}
+			if v, ok := values[column]; ok {
+				m[code] = v
+			}
+		}
+		return m, nil
+	}
+
+	rows, err := readCSV(name)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]string, len(rows))
+	index := 1
+	for i, row := range rows {
+		if len(row) <= index {
+			return nil, dataErrorf("%s:%d: code and value expected", name, i+1)
+		}
+		code := strings.TrimSpace(row[0])
+		if i == 0 && codeLevel(code) == 0 {
+			// header, the value is in the column named so or in the second one of code and value
+			found := false
+			for j, h := range row[1:] {
+				if strings.EqualFold(strings.TrimSpace(h), column) {
+					index, found = j+1, true
+				}
+			}
+			if !found && len(row) > 2 {
+				return nil, dataErrorf("%s:1: no %s column in the header", name, column)
+			}
+			continue
+		}
+		m[code] = strings.TrimSpace(row[index])
+	}
+	return m, nil
+}
+
+// readCSV reads all rows of a CSV file, tolerating a leading UTF-8 BOM
+func readCSV(name string) ([][]string, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err = trimBOM(name, data)
+	if err != nil {
+		return nil, err
+	}
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, dataErrorf("%s: %v", name, err)
+	}
+	return rows, nil
+}
+
+// attach calls set for every node, which reports whether the auxiliary file had a value for it. How many
+// nodes matched is logged, as well as rows of the file whose codes are not in the trees.
+func attach(trees []*Area, what string, codes []string, set func(area *Area) bool) {
+	used := make(map[string]bool, len(codes))
+	nodes := 0
+	var walk func(areas []*Area)
+	walk = func(areas []*Area) {
+		for _, a := range areas {
+			nodes++
+			if set(a) {
+				used[a.Code] = true
+			}
+			walk(a.SubAreas)
+		}
+	}
+	walk(trees)
+
+	var unused []string
+	for _, code := range codes {
+		if !used[code] {
+			unused = append(unused, code)
+		}
+	}
+	sort.Strings(unused)
+	logger.Info("enriched", "what", what, "matched", len(used), "nodes", nodes, "unused", len(unused), "rows", len(codes))
+	if len(unused) > 10 {
+		unused = append(unused[:10], "...")
+	}
+	if len(unused) > 0 {
+		logger.Warn("codes not in the tree", "what", what, "codes", strings.Join(unused, ", "))
+	}
+}
+
+func mappingCodes(mapping map[string]string) []string {
+	codes := make([]string, 0, len(mapping))
+	for code := range mapping {
+		codes = append(codes, code)
+	}
+	return codes
+}
+
+// loadPostcodes attaches postcodes of -postcodes to the trees
+func loadPostcodes(trees []*Area) error {
+	mapping, err := readMapping(postcodesFile, "postcode")
+	if err != nil {
+		return err
+	}
+	attach(trees, "postcodes", mappingCodes(mapping), func(area *Area) bool {
+		area.Postcode = mapping[area.Code]
+		return area.Postcode != ""
+	})
+	return nil
+}
+
+// loadDialingCodes attaches long-distance dialing codes of -dialing-codes to the trees. Codes are defined at
+// city level mostly, nodes without their own code inherit the one of the nearest ancestor.
+func loadDialingCodes(trees []*Area) error {
+	mapping, err := readMapping(dialingCodesFile, "dialing_code")
+	if err != nil {
+		return err
+	}
+	attach(trees, "dialing codes", mappingCodes(mapping), func(area *Area) bool {
+		area.DialingCode = mapping[area.Code]
+		return area.DialingCode != ""
+	})
+	inheritDialingCodes(trees, "")
+	return nil
+}
+
+func inheritDialingCodes(areas []*Area, inherited string) {
+	for _, a := range areas {
+		if a.DialingCode == "" {
+			a.DialingCode = inherited
+		}
+		inheritDialingCodes(a.SubAreas, a.DialingCode)
+	}
+}
+
+// centroid is a representative coordinate of a division
+type centroid struct {
+	lng, lat float64
+}
+
+// readCentroids reads coordinates by code, from a JSON object of [lng, lat] arrays keyed by code or from
+// CSV rows of code, lng and lat with an optional header
+func readCentroids(name string) (map[string]centroid, error) {
+	m := make(map[string]centroid)
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		var coords map[string][2]float64
+		err := readJSONFile(name, &coords)
+		for code, c := range coords {
+			m[code] = centroid{c[0], c[1]}
+		}
+		return m, err
+	}
+
+	rows, err := readCSV(name)
+	if err != nil {
+		return nil, err
+	}
+	for i, row := range rows {
+		if i == 0 && codeLevel(strings.TrimSpace(row[0])) == 0 {
+			continue // header
+		}
+		if len(row) < 3 {
+			return nil, dataErrorf("%s:%d: code, lng and lat expected", name, i+1)
+		}
+		lng, err1 := strconv.ParseFloat(strings.TrimSpace(row[1]), 64)
+		lat, err2 := strconv.ParseFloat(strings.TrimSpace(row[2]), 64)
+		if err1 != nil || err2 != nil {
+			return nil, dataErrorf("%s:%d: lng and lat must be numbers", name, i+1)
+		}
+		m[strings.TrimSpace(row[0])] = centroid{lng, lat}
+	}
+	return m, nil
+}
+
+// loadCentroids attaches coordinates of -centroids to the trees
+func loadCentroids(trees []*Area) error {
+	coords, err := readCentroids(centroidsFile)
+	if err != nil {
+		return err
+	}
+	codes := make([]string, 0, len(coords))
+	for code, c := range coords {
+		if c.lng < -180 || c.lng > 180 || c.lat < -90 || c.lat > 90 {
+			return dataErrorf("%s: coordinate of %s out of range: %v, %v", centroidsFile, code, c.lng, c.lat)
+		}
+		codes = append(codes, code)
+	}
+	attach(trees, "centroids", codes, func(area *Area) bool {
+		c, ok := coords[area.Code]
+		if ok {
+			area.Centroid = &c
+		}
+		return ok
+	})
+	return nil
+}
+
+// formatCoord rounds a coordinate to -coord-precision decimals, so files stay diff-friendly
+func formatCoord(v float64) string {
+	return strconv.FormatFloat(v, 'f', coordPrecision, 64)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestReadMapping(t *testing.T) {
+	m, err := readMapping("./testdata/enrich/postcodes.csv", "postcode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 4 || m["130100"] != "050000" {
+		t.Error(m)
+	}
+
+	m, err = readMapping("./testdata/enrich/postcodes.json", "postcode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 2 || m["130102"] != "050051" {
+		t.Error(m)
+	}
+
+	// one file of both columns serves -postcodes and -dialing-codes
+	for _, name := range []string{"./testdata/enrich/combined.csv", "./testdata/enrich/combined.json"} {
+		m, err = readMapping(name, "postcode")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(m) != 2 || m["110000"] != "100000" || m["130100"] != "050000" {
+			t.Error(name, m)
+		}
+		m, err = readMapping(name, "dialing_code")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m["110000"] != "010" || m["130100"] == "050000" {
+			t.Error(name, m)
+		}
+	}
+	if _, err = readMapping("./testdata/enrich/combined.csv", "en_name"); err == nil {
+		t.Error("missing column accepted")
+	}
+}
+
+func TestPostcodeColumn(t *testing.T) {
+	out := usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run([]string{"-postcodes", "./testdata/enrich/postcodes.csv"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sql := string(data)
+	for _, want := range []string{
+		"(id, node, pid, depth, lft, rgt, postcode) VALUES(110000, '北京市', 0, 1, 1, 10, '100000');",
+		"VALUES(110100, '市辖区', 110000, 2, 2, 9, NULL);",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Error(sql)
+		}
+	}
+}
+
+func TestInheritDialingCodes(t *testing.T) {
+	// a municipality with the code at province level, a province-administered county-level city with its own
+	// code under a placeholder without one, and a city whose areas inherit
+	jiyuan := &Area{Code: "419001", Name: "济源市"}
+	trees := []*Area{
+		{Code: "110000", Name: "北京市", SubAreas: []*Area{
+			{Code: "110100", Name: "市辖区", SubAreas: []*Area{{Code: "110101", Name: "东城区"}}},
+		}},
+		{Code: "410000", Name: "河南省", SubAreas: []*Area{
+			{Code: "410100", Name: "郑州市", SubAreas: []*Area{{Code: "410102", Name: "中原区"}}},
+			{Code: "419000", Name: "省直辖县级行政区划", SubAreas: []*Area{jiyuan}},
+		}},
+	}
+	mapping := map[string]string{"110000": "010", "410100": "0371", "419001": "0391"}
+	attach(trees, "dialing codes", mappingCodes(mapping), func(area *Area) bool {
+		area.DialingCode = mapping[area.Code]
+		return area.DialingCode != ""
+	})
+	inheritDialingCodes(trees, "")
+
+	for _, c := range []struct {
+		area *Area
+		want string
+	}{
+		{trees[0].SubAreas[0].SubAreas[0], "010"},
+		{trees[1], ""},
+		{trees[1].SubAreas[0].SubAreas[0], "0371"},
+		{trees[1].SubAreas[1], ""},
+		{jiyuan, "0391"},
+	} {
+		if c.area.DialingCode != c.want {
+			t.Error(c.area.Code, c.area.DialingCode)
+		}
+	}
+}
+
+func TestDialingCodeColumn(t *testing.T) {
+	out := usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run([]string{"-dialing-codes", "./testdata/enrich/dialingcodes.csv"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sql := string(data)
+	for _, want := range []string{
+		"VALUES(110101001000, '东华门街道办事处', 110101, 4, 4, 5, '010');",
+		"VALUES(130102001000, '建北街道办事处', 130102, 4, 14, 15, '0312');",
+		"VALUES(130100, '石家庄市', 130000, 2, 12, 17, '0311');",
+		"VALUES(130000, '河北省', 0, 1, 11, 18, NULL);",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Error(want)
+		}
+	}
+}
+
+func TestCentroidColumns(t *testing.T) {
+	out := usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run([]string{"-centroids", "./testdata/enrich/centroids.csv", "-coord-precision", "2"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sql := string(data)
+	for _, want := range []string{
+		"(id, node, pid, depth, lft, rgt, lng, lat) VALUES(110000, '北京市', 0, 1, 1, 10, 116.41, 39.90);",
+		"VALUES(110100, '市辖区', 110000, 2, 2, 9, NULL, NULL);",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Error(want)
+		}
+	}
+
+	coords, err := readCentroids("./testdata/enrich/centroids.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := coords["130100"]; c.lng != 114.514 || c.lat != 38.042 {
+		t.Error(coords)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// exit codes of the generator
+const (
+	exitOK       = 0
+	exitInternal = 1 // a bug, e.g. a recovered panic
+	exitUsage    = 2 // bad flags
+	exitData     = 3 // bad input data
+	exitIO       = 4 // reading inputs or writing outputs failed
+	exitInvalid  = 5 // a checked sql file breaks nested set invariants
+	exitUpdate   = 6 // check-update found newer upstream data
+	exitNetwork  = 7 // downloading failed
+
+	exitCanceled = 130 // pick was left without a pick, like a shell after Ctrl-C
+)
+
+// dataError reports bad input data, as opposed to I/O failures
+type dataError struct {
+	msg string
+}
+
+func (e *dataError) Error() string {
+	return e.msg
+}
+
+func dataErrorf(format string, args ...interface{}) error {
+	return &dataError{msg: fmt.Sprintf(format, args...)}
+}
+
+// internalError reports a bug of the generator, e.g. output which does not match the tree
+type internalError struct {
+	msg string
+}
+
+func (e *internalError) Error() string {
+	return e.msg
+}
+
+func internalErrorf(format string, args ...interface{}) error {
+	return &internalError{msg: fmt.Sprintf(format, args...)}
+}
+
+// networkError reports a failed download, which tells nothing about whether an update exists
+type networkError struct {
+	err error
+}
+
+func (e *networkError) Error() string {
+	return e.err.Error()
+}
+
+func (e *networkError) Unwrap() error {
+	return e.err
+}
+
+// exitCode maps an error returned by generate to the process exit code
+func exitCode(err error) int {
+	var de *dataError
+	if errors.As(err, &de) {
+		return exitData
+	}
+	var ie *internalError
+	if errors.As(err, &ie) {
+		return exitInternal
+	}
+	var ne *networkError
+	if errors.As(err, &ne) {
+		return exitNetwork
+	}
+	return exitIO
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// explain shows how the keys of the node at the end of path place it in the trees: its interval, its descendants
+// up to limit, the intervals of its ancestors containing it, and queries of descendants and ancestors
+func explain(w io.Writer, path []*Area, limit int, repeated bool) {
+	a := path[len(path)-1]
+	depth := len(path)
+	fmt.Fprintf(w, "%s %s\n  depth %d, lft %d, rgt %d\n", a.Code, nodeName(a), depth, a.Left, a.Right)
+
+	count := (a.Right - a.Left - keyStep) / (2 * keyStep)
+	if keyStep == 1 {
+		fmt.Fprintf(w, "\ndescendants: (rgt - lft - 1) / 2 = (%d - %d - 1) / 2 = %d\n", a.Right, a.Left, count)
+	} else {
+		fmt.Fprintf(w, "\ndescendants: (rgt - lft - step) / (2 * step) = (%d - %d - %d) / (2 * %d) = %d\n", a.Right, a.Left,
+			keyStep, keyStep, count)
+	}
+	if n := len(keysOf(a.SubAreas)); n != int(count) {
+		fmt.Fprintf(w, "  keys are wrong, %d descendants in the tree\n", n)
+		count = int32(n)
+	}
+	listed := 0
+	var list func(areas []*Area, indent int)
+	list = func(areas []*Area, indent int) {
+		for _, sub := range areas {
+			if listed == limit {
+				return
+			}
+			listed++
+			fmt.Fprintf(w, "  %s%s %s [%d, %d]\n", strings.Repeat("  ", indent), sub.Code, nodeName(sub), sub.Left, sub.Right)
+			list(sub.SubAreas, indent+1)
+		}
+	}
+	list(a.SubAreas, 0)
+	if int(count) > listed {
+		fmt.Fprintf(w, "  ... %d more\n", int(count)-listed)
+	}
+
+	fmt.Fprintf(w, "\nancestors, lft < %d and rgt > %d:\n", a.Left, a.Right)
+	if depth == 1 {
+		fmt.Fprintln(w, "  none, a root")
+	}
+	for i, p := range path[:depth-1] {
+		fmt.Fprintf(w, "  %s%s %s [%d, %d]\n", strings.Repeat("  ", i), p.Code, nodeName(p), p.Left, p.Right)
+	}
+
+	// a repeated code needs the depth to tell its nodes apart
+	node := func(alias string) string {
+		if repeated {
+			return fmt.Sprintf("%s.id=%s AND %s.depth=%d", alias, a.Code, alias, depth)
+		}
+		return alias + ".id=" + a.Code
+	}
+	fmt.Fprintf(w, "\nquery of descendants:\n"+
+		"SELECT child.id, child.node, child.lft, child.rgt\n"+
+		"    FROM `%[1]s` parent, `%[1]s` child\n"+
+		"    WHERE child.lft > parent.lft AND child.rgt < parent.rgt\n"+
+		"    AND %[2]s\n"+
+		"    ORDER BY child.lft\n", tblName, node("parent"))
+	fmt.Fprintf(w, "\nquery of ancestors:\n"+
+		"SELECT parent.id, parent.node, parent.lft, parent.rgt\n"+
+		"    FROM `%[1]s` parent, `%[1]s` child\n"+
+		"    WHERE child.lft > parent.lft AND child.rgt < parent.rgt\n"+
+		"    AND %[2]s\n"+
+		"    ORDER BY parent.lft\n", tblName, node("child"))
+}
+
+// runExplain explains the nested set keys of divisions by code, or by name for every node of the name
+func runExplain(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("division explain", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	defer func(table string, step int32) { tblName, keyStep = table, step }(tblName, keyStep)
+	fs.Var(tableFlag{}, "table", "`name` of the nested sets table")
+	from := fs.String("from", dataDir, "data `directory` or generated sql file")
+	limit := fs.Int("limit", 20, "descendants to list at most")
+	step := fs.Int("key-step", 1, "distance of the keys, as generated with -key-step")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: division explain [-table name] [-from dir|file] [-limit n] [-key-step n] code|name...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 || *limit < 0 || *step < 1 || *step > math.MaxInt32/2 {
+		fs.Usage()
+		return exitUsage
+	}
+	keyStep = int32(*step)
+
+	trees, err := loadTrees(*from)
+	if err != nil {
+		fmt.Fprintln(stderr, "division explain:", err)
+		return exitCode(err)
+	}
+	index := newAreaIndex(trees)
+	for i, arg := range fs.Args() {
+		areas := index.codes[arg]
+		if len(areas) == 0 {
+			areas = index.ByName(arg)
+		}
+		if len(areas) == 0 {
+			fmt.Fprintf(stderr, "division explain: %s not found in %s\n", arg, *from)
+			return exitData
+		}
+		for j, a := range areas {
+			if i+j > 0 {
+				fmt.Fprintln(stdout)
+			}
+			explain(stdout, index.Path(a), *limit, len(index.codes[a.Code]) > 1)
+		}
+	}
+	return exitOK
+}
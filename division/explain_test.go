@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	out := useStdout(t)
+	var stderr bytes.Buffer
+	if code := run([]string{"explain", "-from", "./testdata/mini", "-limit", "1", "110100"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	want := "110100 市辖区\n" +
+		"  depth 2, lft 2, rgt 9\n" +
+		"\n" +
+		"descendants: (rgt - lft - 1) / 2 = (9 - 2 - 1) / 2 = 3\n" +
+		"  110101 东城区 [3, 8]\n" +
+		"  ... 2 more\n" +
+		"\n" +
+		"ancestors, lft < 2 and rgt > 9:\n" +
+		"  110000 北京市 [1, 10]\n" +
+		"\n" +
+		"query of descendants:\n" +
+		"SELECT child.id, child.node, child.lft, child.rgt\n" +
+		"    FROM `nested` parent, `nested` child\n" +
+		"    WHERE child.lft > parent.lft AND child.rgt < parent.rgt\n" +
+		"    AND parent.id=110100\n" +
+		"    ORDER BY child.lft\n" +
+		"\n" +
+		"query of ancestors:\n" +
+		"SELECT parent.id, parent.node, parent.lft, parent.rgt\n" +
+		"    FROM `nested` parent, `nested` child\n" +
+		"    WHERE child.lft > parent.lft AND child.rgt < parent.rgt\n" +
+		"    AND child.id=110100\n" +
+		"    ORDER BY parent.lft\n"
+	if out.String() != want {
+		t.Error(out.String())
+	}
+
+	// keys as written in a sql file, wrong ones included
+	name := filepath.Join(t.TempDir(), "division.sql")
+	sql := "INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(130000, '河北省', 0, 1, 1, 8);\n" +
+		"INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(130100, '石家庄市', 130000, 2, 2, 3);\n"
+	if err := os.WriteFile(name, []byte(sql), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if code := run([]string{"explain", "-from", name, "130000", "130100"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	for _, s := range []string{"(8 - 1 - 1) / 2 = 3\n  keys are wrong, 1 descendants in the tree\n  130100 石家庄市 [2, 3]\n\n",
+		"\n130100 石家庄市\n  depth 2, lft 2, rgt 3\n", "ancestors, lft < 1 and rgt > 8:\n  none, a root\n"} {
+		if !strings.Contains(out.String(), s) {
+			t.Error(s, "missing in", out.String())
+		}
+	}
+
+	// names stand for their nodes
+	out.Reset()
+	if code := run([]string{"explain", "-from", "./testdata/mini", "东城区"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	if !strings.HasPrefix(out.String(), "110101 东城区\n  depth 3, lft 3, rgt 8\n") ||
+		!strings.Contains(out.String(), "  110000 北京市 [1, 10]\n    110100 市辖区 [2, 9]\n") {
+		t.Error(out.String())
+	}
+
+	if code := run([]string{"explain", "-from", "./testdata/mini", "120000"}, &stderr); code != exitData {
+		t.Error("exit code:", code)
+	}
+	if code := run([]string{"explain", "-from", "./testdata/mini"}, &stderr); code != exitUsage {
+		t.Error("exit code:", code)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// extraField maps a field of the input records, which the loader would drop, to an output column
+type extraField struct {
+	field  string // in the JSON records
+	column string
+}
+
+var (
+	extraFieldList string
+	extraFields    []extraField
+)
+
+var columnName = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// parseExtraFields parses a comma separated list of field:column, or field alone for a column of the same name.
+// Columns must not clash with the fixed ones or the enabled cols, nor fields with the ones read anyway. Names of
+// optional columns which are not enabled are free, e.g. zip:postcode when postcodes come with the records.
+func parseExtraFields(list string, cols []column) ([]extraField, error) {
+	var fields []extraField
+	taken := map[string]bool{"id": true, "node": true, "pid": true, "depth": true, "lft": true, "rgt": true}
+	for _, c := range cols {
+		taken[c.name] = true
+	}
+	for _, item := range splitList(list) {
+		f := extraField{field: item, column: item}
+		if i := strings.Index(item, ":"); i >= 0 {
+			f.field, f.column = strings.TrimSpace(item[:i]), strings.TrimSpace(item[i+1:])
+		}
+		switch {
+		case f.field == "" || f.field == "code" || f.field == "name" || f.field == "parent_code":
+			return nil, fmt.Errorf("extra field %q: not a field to pass through", item)
+		case !columnName.MatchString(f.column):
+			return nil, fmt.Errorf("extra field %q: column %q is not a lowercase identifier", item, f.column)
+		case taken[f.column]:
+			return nil, fmt.Errorf("extra field %q: column %s exists already", item, f.column)
+		}
+		taken[f.column] = true
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// extraColumn outputs the field as a string, NULL where records miss it or leave it empty
+func extraColumn(f extraField) column {
+	return column{
+		name: f.column,
+		ddl:  "VARCHAR(255) CHARACTER SET 'utf8' NULL COMMENT 'field " + f.field + " of the input records'",
+		text: true,
+		null: true,
+		value: func(path []*Area) string {
+			return path[len(path)-1].Extra[f.field]
+		},
+	}
+}
+
+// readExtra keeps the fields of -extra-fields of a decoded record, only strings are taken for now
+func (n *flatNode) readExtra(fields map[string]json.RawMessage) error {
+	for _, f := range extraFields {
+		raw, ok := fields[f.field]
+		if !ok || string(raw) == "null" {
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return fmt.Errorf("field %s of %s is not a string: %s", f.field, n.Code, raw)
+		}
+		if n.extra == nil {
+			n.extra = make(map[string]string, len(extraFields))
+		}
+		n.extra[f.field] = s
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseExtraFields(t *testing.T) {
+	fields, err := parseExtraFields("short:short_name, zip", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 2 || fields[0] != (extraField{"short", "short_name"}) || fields[1] != (extraField{"zip", "zip"}) {
+		t.Error(fields)
+	}
+	enabled, _ := parseColumns("postcode")
+	for _, list := range []string{"name:alias", "zip:postcode", "zip:lft", "zip:Zip-Code", ":zip", "a:x,b:x"} {
+		if _, err := parseExtraFields(list, enabled); err == nil {
+			t.Error(list, "accepted")
+		}
+	}
+}
+
+func TestExtraColumns(t *testing.T) {
+	out := usePaths(t, "./testdata/extra")
+	var stderr bytes.Buffer
+	if code := run([]string{"-extra-fields", "short:short_name,zip:postcode"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sql := string(data)
+	for _, want := range []string{
+		"(id, node, pid, depth, lft, rgt, short_name, postcode) VALUES(110000, '北京市', 0, 1, 1, 4, '京', '100000');",
+		"VALUES(110100, '市辖区', 110000, 2, 2, 3, NULL, NULL);",
+		"VALUES(130000, '河北省', 0, 1, 5, 8, '冀', NULL);",
+		"VALUES(130100, '石家庄市', 130000, 2, 6, 7, '石', '050000');",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Error(want, "missing in", sql)
+		}
+	}
+
+	// only strings are taken
+	dir := t.TempDir()
+	err = os.WriteFile(filepath.Join(dir, "provinces.json"), []byte(`[{"code":"110000","name":"北京市","zip":100000}]`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usePaths(t, dir)
+	if code := run([]string{"-extra-fields", "zip"}, &stderr); code != exitData {
+		t.Error("exit code:", code)
+	}
+	if code := run([]string{"-columns", "short_name", "-extra-fields", "short:short_name"}, &stderr); code != exitUsage {
+		t.Error("exit code:", code)
+	}
+}
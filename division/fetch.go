@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// statsURL is where the National Bureau of Statistics publishes the division codes, an edition of each year
+// under its own directory
+const statsURL = "https://www.stats.gov.cn/sj/tjbz/tjyqhdmhcxhfdm/"
+
+// statsRecord is a record of the input files converted from the pages
+type statsRecord struct {
+	Code       string `json:"code"`
+	Name       string `json:"name"`
+	ParentCode string `json:"parent_code,omitempty"`
+}
+
+// statsLink is an entry of a page, of the class of its row, linking the page of its children, "" for leaves
+type statsLink struct {
+	class, code, name, href string
+}
+
+var (
+	// provinces of the index page are links like <a href="11.html">北京市<br/></a>
+	statsProvince = regexp.MustCompile(`<a href=['"]?([0-9]+)\.html['"]?>([^<]+)<br`)
+	// rows of the other pages, the cells are code, name and for villages the urban-rural class before the name
+	statsRow     = regexp.MustCompile(`(?s)<tr class=['"]?(citytr|countytr|towntr|villagetr)['"]?>(.*?)</tr>`)
+	statsCell    = regexp.MustCompile(`(?s)<td[^>]*>(.*?)</td>`)
+	statsHref    = regexp.MustCompile(`href=['"]?([^'" >]+)`)
+	statsTag     = regexp.MustCompile(`<[^>]*>`)
+	statsCharset = regexp.MustCompile(`(?i)charset=['"]?([a-z0-9_-]+)`)
+)
+
+// statsFetcher downloads the pages of an edition, one at a time with a delay for the site limits the rate of
+// requests, retrying failed ones
+type statsFetcher struct {
+	client  *http.Client
+	delay   time.Duration
+	retries int
+	pages   int
+}
+
+// get downloads a page, which must be UTF-8 as are the editions of recent years; older ones in GBK are not
+// decoded
+func (f *statsFetcher) get(page string) ([]byte, error) {
+	var err error
+	for try := 0; try <= f.retries; try++ {
+		if f.pages > 0 || try > 0 {
+			time.Sleep(f.delay * time.Duration(try+1))
+		}
+		f.pages++
+		var resp *http.Response
+		resp, err = f.client.Get(page)
+		if err != nil {
+			continue
+		}
+		var body []byte
+		body, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		switch {
+		case err != nil:
+			continue
+		case resp.StatusCode == http.StatusNotFound:
+			return nil, dataErrorf("%s: %s", page, resp.Status)
+		case resp.StatusCode != http.StatusOK:
+			err = fmt.Errorf("%s: %s", page, resp.Status)
+			continue
+		}
+		if m := statsCharset.FindSubmatch(body); m != nil && !strings.EqualFold(string(m[1]), "utf-8") {
+			return nil, dataErrorf("%s: pages in %s are not supported, only UTF-8 ones", page, m[1])
+		}
+		return body, nil
+	}
+	return nil, &networkError{err}
+}
+
+// links lists the entries of a child page, with hrefs resolved against the page
+func (f *statsFetcher) links(page string) ([]statsLink, error) {
+	body, err := f.get(page)
+	if err != nil {
+		return nil, err
+	}
+	base, err := url.Parse(page)
+	if err != nil {
+		return nil, err
+	}
+	var links []statsLink
+	for _, row := range statsRow.FindAllSubmatch(body, -1) {
+		cells := statsCell.FindAllSubmatch(row[2], -1)
+		if len(cells) < 2 {
+			return nil, dataErrorf("%s: row of %d cells", page, len(cells))
+		}
+		text := func(cell []byte) string {
+			return strings.TrimSpace(html.UnescapeString(statsTag.ReplaceAllString(string(cell), "")))
+		}
+		l := statsLink{class: string(row[1]), code: text(cells[0][1]), name: text(cells[len(cells)-1][1])}
+		if m := statsHref.FindSubmatch(row[2]); m != nil {
+			ref, err := base.Parse(string(m[1]))
+			if err != nil {
+				return nil, dataErrorf("%s: %v", page, err)
+			}
+			l.href = ref.String()
+		}
+		links = append(links, l)
+	}
+	return links, nil
+}
+
+// fetchStats crawls the edition at index down to depth levels and returns the records of each level. Codes are
+// cut to the width of their level, e.g. 110101000000 of a county to 110101, and province codes of the index
+// links padded to it. A city listing towns without counties, such as 东莞市, gets a county of its own code and
+// name, as the input files have them.
+func fetchStats(f *statsFetcher, index string, depth int) ([][]statsRecord, error) {
+	levels := make([][]statsRecord, depth)
+	add := func(level int, l statsLink, parent string) statsRecord {
+		code := l.code
+		if w := codeSpecs[level].width; len(code) > w {
+			code = code[:w]
+		}
+		r := statsRecord{Code: code, Name: l.name, ParentCode: parent}
+		levels[level] = append(levels[level], r)
+		return r
+	}
+	var walk func(level int, page, parent string) error
+	walk = func(level int, page, parent string) error {
+		links, err := f.links(page)
+		if err != nil {
+			return err
+		}
+		for _, l := range links {
+			if level == 2 && l.class == "towntr" {
+				// towns right below a city, under a county of the city's own
+				if len(levels[2]) == 0 || levels[2][len(levels[2])-1].Code != parent {
+					city := levels[1][len(levels[1])-1]
+					add(2, statsLink{code: city.Code, name: city.Name}, parent)
+				}
+				if depth > 3 {
+					r := add(3, l, parent)
+					if l.href != "" && depth > 4 {
+						if err := walk(4, l.href, r.Code); err != nil {
+							return err
+						}
+					}
+				}
+				continue
+			}
+			r := add(level, l, parent)
+			if l.href != "" && level+1 < depth {
+				if err := walk(level+1, l.href, r.Code); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	body, err := f.get(index)
+	if err != nil {
+		return nil, err
+	}
+	base, err := url.Parse(index)
+	if err != nil {
+		return nil, err
+	}
+	provinces := statsProvince.FindAllSubmatch(body, -1)
+	if len(provinces) == 0 {
+		return nil, dataErrorf("%s: no provinces", index)
+	}
+	for _, m := range provinces {
+		code := string(m[1]) + strings.Repeat("0", codeSpecs[0].width-len(m[1]))
+		add(0, statsLink{code: code, name: strings.TrimSpace(string(m[2]))}, "")
+		if depth > 1 {
+			page, _ := base.Parse(string(m[1]) + ".html")
+			if err := walk(1, page.String(), code); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return levels, nil
+}
+
+// writeStats writes the records of each level into the input file of the level in dir
+func writeStats(dir string, levels [][]statsRecord) error {
+	for i, records := range levels {
+		err := writeFileAtomic(filepath.Join(dir, inputLevels()[i].file), func(w io.Writer) error {
+			enc := json.NewEncoder(w)
+			enc.SetEscapeHTML(false)
+			return enc.Encode(records)
+		}, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runFetch downloads an edition of the division codes from the National Bureau of Statistics and writes it as
+// input files, so refreshing the data is one command
+func runFetch(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("division fetch", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	out := fs.String("out", dataDir, "data `directory` to write the input files into")
+	source := fs.String("source", statsURL, "base `URL` of the editions")
+	year := fs.String("year", "2023", "`edition` to download, the directory of the year under -source")
+	depth := fs.Int("depth", 4, "levels to download, 4 down to streets, 5 with villages, which takes tens of thousands of pages")
+	delay := fs.Duration("delay", 200*time.Millisecond, "pause between downloads, longer for each retry")
+	retries := fs.Int("retries", 3, "retries of a failed download")
+	timeout := fs.Duration("timeout", time.Minute, "timeout of each download")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: division fetch [-out dir] [-year edition] [-depth n] [-source url] [-delay d] [-retries n] [-timeout d]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 0 || *depth < 1 || *depth > len(inputLevels()) || *retries < 0 || *delay < 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	index := strings.TrimSuffix(*source, "/") + "/" + *year + "/index.html"
+	f := &statsFetcher{client: &http.Client{Timeout: *timeout}, delay: *delay, retries: *retries}
+	levels, err := fetchStats(f, index, *depth)
+	if err != nil {
+		fmt.Fprintln(stderr, "division fetch:", err)
+		return exitCode(err)
+	}
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		fmt.Fprintln(stderr, "division fetch:", err)
+		return exitIO
+	}
+	if err := writeStats(*out, levels); err != nil {
+		fmt.Fprintln(stderr, "division fetch:", err)
+		return exitIO
+	}
+	counts := make([]string, len(levels))
+	for i, records := range levels {
+		counts[i] = fmt.Sprintf("%d %s", len(records), levelName(inputLevels()[i]))
+	}
+	fmt.Fprintf(stderr, "division fetch: %s from %d pages into %s\n", strings.Join(counts, ", "), f.pages, *out)
+	return exitOK
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// statsPages are an edition of two provinces, with a city listing its towns without counties
+var statsPages = map[string]string{
+	"/2023/index.html": `<html><head><meta charset="utf-8"></head><body><table>
+<tr class="provincetr"><td><a href="11.html">北京市<br/></a></td><td><a href="44.html">广东省<br/></a></td></tr>
+</table></body></html>`,
+	"/2023/11.html": `<table><tr class="citytr"><td><a href="11/1101.html">110100000000</a></td><td><a href="11/1101.html">市辖区</a></td></tr></table>`,
+	"/2023/11/1101.html": `<table>
+<tr class="countytr"><td><a href="01/110101.html">110101000000</a></td><td><a href="01/110101.html">东城区</a></td></tr>
+<tr class="countytr"><td>110102000000</td><td>西城区</td></tr>
+</table>`,
+	"/2023/11/01/110101.html":       `<table><tr class="towntr"><td><a href="01/110101001.html">110101001000</a></td><td><a href="01/110101001.html">东华门街道</a></td></tr></table>`,
+	"/2023/11/01/01/110101001.html": `<table><tr class="villagetr"><td>110101001001</td><td>111</td><td>多福巷社区居委会</td></tr></table>`,
+	"/2023/44.html":                 `<table><tr class="citytr"><td><a href="44/4419.html">441900000000</a></td><td><a href="44/4419.html">东莞市</a></td></tr></table>`,
+	"/2023/44/4419.html": `<table>
+<tr class="towntr"><td><a href="19/441900003.html">441900003000</a></td><td><a href="19/441900003.html">东城街道</a></td></tr>
+<tr class="towntr"><td>441900004000</td><td>南城街道</td></tr>
+</table>`,
+	"/2023/44/19/441900003.html": `<table><tr class="villagetr"><td>441900003001</td><td>111</td><td>东城社区居委会</td></tr></table>`,
+}
+
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := statsPages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(page))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	var stderr bytes.Buffer
+	if code := run([]string{"fetch", "-source", server.URL, "-depth", "5", "-delay", "0", "-out", dir}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "2 provinces, 2 cities, 3 areas, 3 streets, 2 villages from 8 pages") {
+		t.Error(stderr.String())
+	}
+	for file, want := range map[string]string{
+		provincesFile: `[{"code":"110000","name":"北京市"},{"code":"440000","name":"广东省"}]`,
+		citiesFile:    `[{"code":"110100","name":"市辖区","parent_code":"110000"},{"code":"441900","name":"东莞市","parent_code":"440000"}]`,
+		areasFile: `[{"code":"110101","name":"东城区","parent_code":"110100"},{"code":"110102","name":"西城区","parent_code":"110100"},` +
+			`{"code":"441900","name":"东莞市","parent_code":"441900"}]`,
+		streetsFile: `[{"code":"110101001000","name":"东华门街道","parent_code":"110101"},` +
+			`{"code":"441900003000","name":"东城街道","parent_code":"441900"},{"code":"441900004000","name":"南城街道","parent_code":"441900"}]`,
+		villagesFile: `[{"code":"110101001001","name":"多福巷社区居委会","parent_code":"110101001000"},` +
+			`{"code":"441900003001","name":"东城社区居委会","parent_code":"441900003000"}]`,
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.TrimSpace(string(data)); got != want {
+			t.Errorf("%s:\n%s\nwant\n%s", file, got, want)
+		}
+	}
+
+	// shallower fetches leave the deeper files out, older editions in GBK are refused
+	dir = t.TempDir()
+	stderr.Reset()
+	if code := run([]string{"fetch", "-source", server.URL, "-depth", "3", "-delay", "0", "-out", dir}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	if _, err := os.Stat(filepath.Join(dir, streetsFile)); !os.IsNotExist(err) {
+		t.Error("streets fetched with -depth 3:", err)
+	}
+	statsPages["/2009/index.html"] = `<meta http-equiv="Content-Type" content="text/html; charset=gb2312">`
+	if code := run([]string{"fetch", "-source", server.URL, "-year", "2009", "-delay", "0", "-out", dir}, &stderr); code != exitData {
+		t.Error("exit code of gb2312 pages:", code)
+	}
+	if code := run([]string{"fetch", "-source", server.URL, "-year", "2000", "-delay", "0", "-out", dir}, &stderr); code != exitData {
+		t.Error("exit code of a missing edition:", code)
+	}
+	server.Close()
+	if code := run([]string{"fetch", "-source", server.URL, "-retries", "1", "-delay", "0", "-out", dir}, &stderr); code != exitNetwork {
+		t.Error("exit code of a closed server:", code)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// fixtureRecord is an input record as written to fixture files, provinces come without parent_code
+type fixtureRecord struct {
+	Code       string `json:"code"`
+	Name       string `json:"name"`
+	ParentCode string `json:"parent_code,omitempty"`
+}
+
+// fixtureOptions tell how many provinces of each kind a fixture takes and how it samples below them
+type fixtureOptions struct {
+	normal, municipalities, bare int
+	fanout, maxNodes             int
+	seed                         int64
+}
+
+// pick chooses n of candidates at random, in their own order
+func pick(r *rand.Rand, candidates []*Area, n int) []*Area {
+	if n > len(candidates) {
+		n = len(candidates)
+	}
+	chosen := r.Perm(len(candidates))[:n]
+	sort.Ints(chosen)
+	picked := make([]*Area, n)
+	for i, c := range chosen {
+		picked[i] = candidates[c]
+	}
+	return picked
+}
+
+// sampleTrees picks provinces of each kind, normal ones, municipalities and bare ones without children, then up
+// to fanout children of every picked node level by level, until maxNodes are picked. It returns the picked
+// codes by depth, the same options always pick the same nodes of the same trees.
+func sampleTrees(trees []*Area, opts fixtureOptions) ([]map[string]bool, error) {
+	var normal, municipalities, bare []*Area
+	for _, p := range trees {
+		switch {
+		case len(p.SubAreas) == 0:
+			bare = append(bare, p)
+		case divisionType(p.Code, nodeName(p)) == typeMunicipality:
+			municipalities = append(municipalities, p)
+		default:
+			normal = append(normal, p)
+		}
+	}
+	for _, k := range []struct {
+		kind       string
+		want, have int
+	}{{"normal", opts.normal, len(normal)}, {"municipality", opts.municipalities, len(municipalities)}, {"bare", opts.bare, len(bare)}} {
+		if k.want > k.have {
+			return nil, dataErrorf("%d %s provinces wanted but the data has %d", k.want, k.kind, k.have)
+		}
+	}
+
+	r := rand.New(rand.NewSource(opts.seed))
+	var picked []*Area
+	picked = append(picked, pick(r, normal, opts.normal)...)
+	picked = append(picked, pick(r, municipalities, opts.municipalities)...)
+	picked = append(picked, pick(r, bare, opts.bare)...)
+
+	codes := make([]map[string]bool, treeDepth(trees))
+	for i := range codes {
+		codes[i] = make(map[string]bool)
+	}
+	count := 0
+	level := picked
+	for depth := 0; len(level) > 0 && count < opts.maxNodes; depth++ {
+		var next []*Area
+		for _, a := range level {
+			if count == opts.maxNodes {
+				break
+			}
+			codes[depth][a.Code] = true
+			count++
+			next = append(next, pick(r, a.SubAreas, opts.fanout)...)
+		}
+		level = next
+	}
+	return codes, nil
+}
+
+// writeFixture writes the records of the loaded input files with picked codes into dir, in their input order
+func writeFixture(dir string, codes []map[string]bool) error {
+	for i, l := range inputLevels() {
+		records := []fixtureRecord{}
+		for _, n := range *l.nodes {
+			if i < len(codes) && codes[i][n.Code] {
+				records = append(records, fixtureRecord{n.Code, n.Name, n.ParentCode})
+			}
+		}
+		data, err := compactJSON(records)
+		if err != nil {
+			return err
+		}
+		err = writeFileAtomic(filepath.Join(dir, l.file), func(w io.Writer) error {
+			_, err := w.Write(data)
+			return err
+		}, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractFixture writes the input files of the data directory from, sampled by opts, into dir
+func extractFixture(from, dir string, opts fixtureOptions) error {
+	// loadTrees leaves the records of the input files loaded
+	trees, err := loadTrees(from)
+	if err != nil {
+		return err
+	}
+	codes, err := sampleTrees(trees, opts)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err = writeFixture(dir, codes); err != nil {
+		return err
+	}
+	args := []interface{}{"dir", dir}
+	for i, c := range codes {
+		args = append(args, levelName(inputLevels()[i]), len(c))
+	}
+	logger.Info("fixture written", args...)
+	return nil
+}
+
+// runFixture extracts a small subset of the input files for tests, keeping parents of all picked records
+func runFixture(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("division fixture", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	from := fs.String("from", dataDir, "data `directory` to extract from")
+	var opts fixtureOptions
+	fs.IntVar(&opts.normal, "normal", 2, "provinces with cities to pick, other than municipalities")
+	fs.IntVar(&opts.municipalities, "municipalities", 1, "municipalities to pick")
+	fs.IntVar(&opts.bare, "bare", 1, "provinces without any data below them to pick")
+	fs.IntVar(&opts.fanout, "fanout", 2, "children to pick of every picked node")
+	fs.IntVar(&opts.maxNodes, "max-nodes", 100, "nodes to pick at most")
+	fs.Int64Var(&opts.seed, "seed", 1, "seed of the random picks")
+	golden := fs.Bool("golden", false, "also generate division.sql of the fixture into the directory")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: division fixture [flags] dir")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 || opts.normal < 0 || opts.municipalities < 0 || opts.bare < 0 || opts.fanout < 1 || opts.maxNodes < 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	dir := fs.Arg(0)
+
+	if err := extractFixture(*from, dir, opts); err != nil {
+		fmt.Fprintln(stderr, "division fixture:", err)
+		return exitCode(err)
+	}
+
+	if *golden {
+		oldDir, oldSQL := dataDir, sqlFile
+		dataDir, sqlFile = dir, filepath.Join(dir, "division.sql")
+		defer func() { dataDir, sqlFile = oldDir, oldSQL }()
+		return runGenerate(nil, stderr)
+	}
+	return exitOK
+}
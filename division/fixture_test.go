@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// TestFixture extracts testdata/fixture from the data again, the flags must match the ones it was written with
+func TestFixture(t *testing.T) {
+	dir := t.TempDir()
+	var stderr bytes.Buffer
+	if code := run([]string{"fixture", "-golden", dir}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	for _, file := range []string{provincesFile, citiesFile, areasFile, streetsFile, "division.sql"} {
+		got, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := ioutil.ReadFile(filepath.Join("./testdata/fixture", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s differs from testdata/fixture:\n%s", file, got)
+		}
+	}
+
+	useStdout(t)
+	if code := run([]string{"verify", "./testdata/fixture/division.sql"}, &stderr); code != exitOK {
+		t.Error("exit code:", code)
+	}
+	if code := run([]string{"fixture", "-municipalities", "5", dir}, &stderr); code != exitData {
+		t.Error("exit code:", code)
+	}
+	if code := run([]string{"fixture", "-fanout", "0", dir}, &stderr); code != exitUsage {
+		t.Error("exit code:", code)
+	}
+}
+
+// TestFixtureGolden builds the committed fixture, which takes provinces of every kind and levels without data
+func TestFixtureGolden(t *testing.T) {
+	sqlFile := usePaths(t, "./testdata/fixture")
+	var stderr bytes.Buffer
+	if code := run(nil, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	got, err := ioutil.ReadFile(sqlFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ioutil.ReadFile("./testdata/fixture/division.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got:\n%s", got)
+	}
+}
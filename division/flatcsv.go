@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+)
+
+// genCSVFile writes the rows of the trees into the -out file as CSV with a header, for spreadsheets and bulk
+// loaders without sql: id, name, pid, depth, lft, rgt and the enabled optional columns, NULL as empty fields
+func genCSVFile(trees []*Area) error {
+	return writeFileAtomic(sqlFile, func(w io.Writer) error {
+		return writeCSV(w, trees)
+	}, nil)
+}
+
+func writeCSV(w io.Writer, trees []*Area) error {
+	bw := bufio.NewWriterSize(w, sqlBufferSize)
+	cw := csv.NewWriter(bw)
+	header := []string{"id", "name", "pid", "depth", "lft", "rgt"}
+	for _, c := range columns {
+		header = append(header, c.name)
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, p := range trees {
+		err := walkSubtree([]*Area{p}, func(path []*Area) error {
+			area := path[len(path)-1]
+			record := []string{area.Code, nodeName(area), area.ParentCode, itoa(int32(len(path))), itoa(area.Left), itoa(area.Right)}
+			for _, c := range columns {
+				v, err := c.get(path)
+				if err != nil {
+					return err
+				}
+				record = append(record, v)
+			}
+			return cw.Write(record)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
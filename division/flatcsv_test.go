@@ -0,0 +1,26 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestFormatCSV(t *testing.T) {
+	out := usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run([]string{"-format", "csv", "-columns", "lng,short_name"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "id,name,pid,depth,lft,rgt,lng,short_name\n" +
+		"110000,北京市,0,1,1,10,,北京\n" +
+		"110100,市辖区,110000,2,2,9,,市辖区\n"
+	if !strings.HasPrefix(string(data), want) || strings.Count(string(data), "\n") != 10 {
+		t.Error(string(data))
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// geojsonSplits are the values of -geojson-split, how exported features are split into files
+var geojsonSplits = []string{"province", "level"}
+
+// featureWriter streams features into a FeatureCollection
+type featureWriter struct {
+	w *bufio.Writer
+	n int
+}
+
+func newFeatureWriter(w io.Writer) *featureWriter {
+	fw := &featureWriter{w: bufio.NewWriter(w)}
+	fw.w.WriteString(`{"type":"FeatureCollection","features":[`)
+	return fw
+}
+
+// write appends the feature of the node at the end of path. Its geometry is the boundary, or the centroid
+// point when there is no boundary, or null.
+func (fw *featureWriter) write(path []*Area) error {
+	area := path[len(path)-1]
+	var geometry json.RawMessage
+	switch {
+	case area.Boundary != nil:
+		var err error
+		geometry, err = boundaries.geometry(area.Boundary)
+		if err != nil {
+			return err
+		}
+	case area.Centroid != nil:
+		geometry = json.RawMessage(`{"type":"Point","coordinates":[` + formatCoord(area.Centroid.lng) + `,` +
+			formatCoord(area.Centroid.lat) + `]}`)
+	}
+	feature := struct {
+		Type       string `json:"type"`
+		Properties struct {
+			Code       string `json:"code"`
+			Name       string `json:"name"`
+			Depth      int    `json:"depth"`
+			Left       int32  `json:"lft"`
+			Right      int32  `json:"rgt"`
+			ParentCode string `json:"parent_code"`
+		} `json:"properties"`
+		Geometry json.RawMessage `json:"geometry"`
+	}{Type: "Feature", Geometry: geometry}
+	props := &feature.Properties
+	props.Code, props.Name, props.Depth = area.Code, nodeName(area), len(path)
+	props.Left, props.Right, props.ParentCode = area.Left, area.Right, area.ParentCode
+
+	data, err := json.Marshal(feature)
+	if err != nil {
+		return dataErrorf("geometry of %s: %v", area.Code, err)
+	}
+	if fw.n > 0 {
+		fw.w.WriteByte(',')
+	}
+	fw.w.WriteByte('\n')
+	fw.n++
+	_, err = fw.w.Write(data)
+	return err
+}
+
+// close ends the FeatureCollection and flushes it
+func (fw *featureWriter) close() error {
+	fw.w.WriteString("\n]}\n")
+	return fw.w.Flush()
+}
+
+// genGeoJSON exports the trees as GeoJSON FeatureCollections into -geojson-dir, a file of each province
+// named by its code or a file of each level named level-<depth>. Features are streamed one by one and all
+// files are renamed into place together.
+func genGeoJSON(trees []*Area) error {
+	err := os.MkdirAll(geojsonDir, 0755)
+	if err != nil {
+		return err
+	}
+	var files atomicFiles
+	defer files.abort()
+	create := func(name string) (*featureWriter, error) {
+		tmp, err := files.create(filepath.Join(geojsonDir, name+".geojson"))
+		if err != nil {
+			return nil, err
+		}
+		return newFeatureWriter(tmp), nil
+	}
+
+	var writers []*featureWriter
+	var walk func(path []*Area) error
+	walk = func(path []*Area) error {
+		depth := len(path)
+		if geojsonSplit == "level" && len(writers) < depth {
+			fw, err := create("level-" + itoa(int32(depth)))
+			if err != nil {
+				return err
+			}
+			writers = append(writers, fw)
+		}
+		fw := writers[len(writers)-1] // of the province being walked
+		if geojsonSplit == "level" {
+			fw = writers[depth-1]
+		}
+		if err := fw.write(path); err != nil {
+			return err
+		}
+		for _, sub := range path[len(path)-1].SubAreas {
+			if err := walk(append(path, sub)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, p := range trees {
+		if geojsonSplit == "province" {
+			fw, err := create(p.Code)
+			if err != nil {
+				return err
+			}
+			writers = append(writers, fw)
+		}
+		if err := walk([]*Area{p}); err != nil {
+			return err
+		}
+	}
+
+	for _, fw := range writers {
+		if err := fw.close(); err != nil {
+			return err
+		}
+	}
+	if err = files.sync(); err != nil {
+		return err
+	}
+	return files.commit()
+}
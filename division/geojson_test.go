@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// readFeatures reads a FeatureCollection written by genGeoJSON
+func readFeatures(t *testing.T, name string) []map[string]interface{} {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var collection struct {
+		Type     string
+		Features []map[string]interface{}
+	}
+	if err := json.Unmarshal(data, &collection); err != nil || collection.Type != "FeatureCollection" {
+		t.Fatal(name, err, string(data))
+	}
+	return collection.Features
+}
+
+func TestGeoJSONByProvince(t *testing.T) {
+	usePaths(t, "./testdata/mini")
+	dir := t.TempDir()
+	var stderr bytes.Buffer
+	if code := run([]string{"-geojson-dir", dir, "-boundaries", "./testdata/boundaries/130100.json",
+		"-centroids", "./testdata/enrich/centroids.csv"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+
+	beijing := readFeatures(t, filepath.Join(dir, "110000.geojson"))
+	if len(beijing) != 5 {
+		t.Fatal("features:", len(beijing))
+	}
+	props := beijing[2]["properties"].(map[string]interface{})
+	if props["code"] != "110101" || props["name"] != "东城区" || props["depth"] != 3.0 || props["lft"] != 3.0 ||
+		props["rgt"] != 8.0 || props["parent_code"] != "110100" {
+		t.Error(props)
+	}
+	if g := beijing[0]["geometry"].(map[string]interface{}); g["type"] != "Point" {
+		t.Error("centroid:", g)
+	}
+	if beijing[2]["geometry"] != nil {
+		t.Error("geometry:", beijing[2]["geometry"])
+	}
+
+	hebei := readFeatures(t, filepath.Join(dir, "130000.geojson"))
+	if g := hebei[1]["geometry"].(map[string]interface{}); g["type"] != "MultiPolygon" {
+		t.Error("boundary over centroid:", g)
+	}
+}
+
+func TestGeoJSONByLevel(t *testing.T) {
+	usePaths(t, "./testdata/mini")
+	dir := t.TempDir()
+	var stderr bytes.Buffer
+	if code := run([]string{"-geojson-dir", dir, "-geojson-split", "level"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	for level, n := range map[string]int{"level-1": 2, "level-2": 2, "level-3": 2, "level-4": 3} {
+		if features := readFeatures(t, filepath.Join(dir, level+".geojson")); len(features) != n {
+			t.Error(level, "features:", len(features))
+		}
+	}
+
+	if code := run([]string{"-geojson-dir", dir, "-geojson-split", "city"}, &stderr); code != exitUsage {
+		t.Error("exit code:", code)
+	}
+}
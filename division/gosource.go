@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"go/token"
+	"io"
+	"strconv"
+)
+
+// goPackage is the package of the Go file of -format go
+var goPackage = "divisions"
+
+// genGoFile writes the trees into the -out file as Go source, e.g. divisions_gen.go, so services embed the
+// nested set without loading files at run time
+func genGoFile(trees []*Area) error {
+	return writeFileAtomic(sqlFile, func(w io.Writer) error {
+		return writeGoSource(w, trees)
+	}, nil)
+}
+
+// writeGoSource writes a gofmt formatted file of a Division type and the Divisions slice of all nodes in
+// preorder, ids and pids being the codes
+func writeGoSource(w io.Writer, trees []*Area) error {
+	bw := bufio.NewWriterSize(w, sqlBufferSize)
+	bw.WriteString(`// Code generated by division -format go; DO NOT EDIT.
+
+package ` + goPackage + `
+
+// Division is a node of the nested set of divisions
+type Division struct {
+	ID    int64
+	Name  string
+	PID   int64
+	Depth int
+	Lft   int
+	Rgt   int
+}
+
+// Divisions are the divisions in preorder, each followed by its subtree in [Lft, Rgt]
+var Divisions = []Division{
+`)
+	for _, p := range trees {
+		err := walkSubtree([]*Area{p}, func(path []*Area) error {
+			area := path[len(path)-1]
+			_, err := bw.WriteString("\t{" + area.Code + ", " + strconv.Quote(nodeName(area)) + ", " + area.ParentCode + ", " +
+				itoa(int32(len(path))) + ", " + itoa(area.Left) + ", " + itoa(area.Right) + "},\n")
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// isGoPackage tells names fit for the package clause of the generated file
+func isGoPackage(name string) bool {
+	return token.IsIdentifier(name) && name != "_"
+}
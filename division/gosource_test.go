@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"go/format"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestFormatGo(t *testing.T) {
+	out := usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run([]string{"-format", "go", "-go-package", "geo"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	formatted, err := format.Source(data)
+	if err != nil {
+		t.Fatal(err, string(data))
+	}
+	if !bytes.Equal(formatted, data) {
+		t.Error("not gofmt formatted:", string(data))
+	}
+	if !strings.Contains(string(data), "package geo\n") || !strings.Contains(string(data), "\t{110101002000, \"景山街道办事处\", 110101, 4, 6, 7},\n") {
+		t.Error(string(data))
+	}
+
+	usePaths(t, "./testdata/mini")
+	if code := run([]string{"-format", "go", "-go-package", "geo-data"}, &stderr); code != exitUsage {
+		t.Error("exit code:", code)
+	}
+}
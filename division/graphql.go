@@ -0,0 +1,455 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The GraphQL endpoint of serve -graphql answers queries of this schema, so clients fetch the subtree they need,
+// e.g. provinces with two levels of children, in one request:
+//
+//	type Query {
+//	  divisions: [Division!]!
+//	  division(code: String!): Division
+//	  search(q: String!, limit: Int = 10): [Division!]!
+//	}
+//	type Division {
+//	  code: String!  name: String!  fullName: String!  parentCode: String
+//	  depth: Int!  lft: Int!  rgt: Int!  childrenCount: Int!  rank: Int
+//	  parent: Division  ancestors: [Division!]!  children: [Division!]!
+//	}
+//
+// Only queries are supported, with aliases, arguments and variables; fragments, directives and introspection
+// other than __typename are not.
+
+// gqlField is a field of a selection set, with its alias and arguments
+type gqlField struct {
+	alias, name string
+	args        map[string]interface{}
+	selection   []*gqlField
+}
+
+// key is the name of the field in the response
+func (f *gqlField) key() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+// gqlParser reads a query document of a single operation
+type gqlParser struct {
+	src       string
+	pos       int
+	variables map[string]interface{}
+}
+
+func (p *gqlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("at %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+// skip passes over white space, commas and comments
+func (p *gqlParser) skip() {
+	for p.pos < len(p.src) {
+		switch c := p.src[p.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			p.pos++
+		case c == '#':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// peek returns the next punctuator, or 0 for a name, a value or the end
+func (p *gqlParser) peek() byte {
+	p.skip()
+	if p.pos < len(p.src) && strings.IndexByte("{}():$!=[]@.", p.src[p.pos]) >= 0 {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+func (p *gqlParser) expect(c byte) error {
+	if p.peek() != c {
+		return p.errorf("%q expected", c)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *gqlParser) name() (string, error) {
+	p.skip()
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := rune(p.src[p.pos])
+		if c != '_' && !unicode.IsLetter(c) && !(p.pos > start && unicode.IsDigit(c)) || c > unicode.MaxASCII {
+			break
+		}
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.errorf("name expected")
+	}
+	return p.src[start:p.pos], nil
+}
+
+// document reads `query Name($v: Type) { ... }` or a bare selection set
+func (p *gqlParser) document() ([]*gqlField, error) {
+	if p.peek() != '{' {
+		op, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if op != "query" {
+			return nil, fmt.Errorf("%s operations are not supported, only query", op)
+		}
+		if p.peek() == 0 {
+			if _, err := p.name(); err != nil {
+				return nil, err
+			}
+		}
+		if p.peek() == '(' {
+			// variable definitions, the values come with the request
+			for p.pos < len(p.src) && p.src[p.pos] != ')' {
+				p.pos++
+			}
+			if err := p.expect(')'); err != nil {
+				return nil, err
+			}
+		}
+	}
+	selection, err := p.selection()
+	if err != nil {
+		return nil, err
+	}
+	if p.skip(); p.pos < len(p.src) {
+		return nil, p.errorf("one operation expected")
+	}
+	return selection, nil
+}
+
+// selection reads a selection set in braces
+func (p *gqlParser) selection() ([]*gqlField, error) {
+	if err := p.expect('{'); err != nil {
+		return nil, err
+	}
+	var fields []*gqlField
+	for p.peek() != '}' {
+		if p.peek() == '.' || p.peek() == '@' {
+			return nil, p.errorf("fragments and directives are not supported")
+		}
+		f := &gqlField{}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		f.name = name
+		if p.peek() == ':' {
+			p.pos++
+			if f.name, err = p.name(); err != nil {
+				return nil, err
+			}
+			f.alias = name
+		}
+		if p.peek() == '(' {
+			p.pos++
+			f.args = make(map[string]interface{})
+			for p.peek() != ')' {
+				arg, err := p.name()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expect(':'); err != nil {
+					return nil, err
+				}
+				if f.args[arg], err = p.value(); err != nil {
+					return nil, err
+				}
+			}
+			p.pos++
+		}
+		if p.peek() == '{' {
+			if f.selection, err = p.selection(); err != nil {
+				return nil, err
+			}
+		}
+		fields = append(fields, f)
+	}
+	p.pos++
+	return fields, nil
+}
+
+// value reads a string, an integer or a variable
+func (p *gqlParser) value() (interface{}, error) {
+	switch p.peek() {
+	case '$':
+		p.pos++
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		v, ok := p.variables[name]
+		if !ok {
+			return nil, p.errorf("variable $%s not given", name)
+		}
+		if n, ok := v.(float64); ok {
+			return int(n), nil
+		}
+		return v, nil
+	case 0:
+	default:
+		return nil, p.errorf("string or integer expected")
+	}
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("value expected")
+	}
+	if p.src[p.pos] == '"' {
+		end := p.pos + 1
+		for end < len(p.src) && p.src[end] != '"' {
+			if p.src[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(p.src) {
+			return nil, p.errorf("unterminated string")
+		}
+		var s string
+		if err := json.Unmarshal([]byte(p.src[p.pos:end+1]), &s); err != nil {
+			return nil, p.errorf("bad string: %v", err)
+		}
+		p.pos = end + 1
+		return s, nil
+	}
+	start := p.pos
+	for p.pos < len(p.src) && (p.src[p.pos] == '-' || p.src[p.pos] >= '0' && p.src[p.pos] <= '9') {
+		p.pos++
+	}
+	n, err := strconv.Atoi(p.src[start:p.pos])
+	if err != nil {
+		return nil, p.errorf("string or integer expected")
+	}
+	return n, nil
+}
+
+// gqlObject is a JSON object keeping the order of the selection
+type gqlObject struct {
+	keys   []string
+	values []interface{}
+}
+
+func (o *gqlObject) set(key string, value interface{}) {
+	o.keys = append(o.keys, key)
+	o.values = append(o.values, value)
+}
+
+func (o *gqlObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		b.Write(key)
+		b.WriteByte(':')
+		value, err := json.Marshal(o.values[i])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// stringArg returns a required string argument of f
+func stringArg(f *gqlField, name string) (string, error) {
+	s, ok := f.args[name].(string)
+	if !ok {
+		return "", fmt.Errorf("%s: argument %s of type String! expected", f.key(), name)
+	}
+	return s, nil
+}
+
+// resolveQuery resolves the fields of the query type
+func (s *divisionServer) resolveQuery(selection []*gqlField) (*gqlObject, error) {
+	data := &gqlObject{}
+	for _, f := range selection {
+		var value interface{}
+		var err error
+		switch f.name {
+		case "__typename":
+			value = "Query"
+		case "divisions":
+			list := make([]interface{}, len(s.index.roots))
+			for i, a := range s.index.roots {
+				if list[i], err = s.resolveDivision(f, []*Area{a}, -1); err != nil {
+					return nil, err
+				}
+			}
+			value = list
+		case "division":
+			code, err := stringArg(f, "code")
+			if err != nil {
+				return nil, err
+			}
+			if a := s.index.ByCode(code); a != nil {
+				if value, err = s.resolveDivision(f, s.index.Path(a), -1); err != nil {
+					return nil, err
+				}
+			}
+		case "search":
+			q, err := stringArg(f, "q")
+			if err != nil {
+				return nil, err
+			}
+			limit := 10
+			if v, ok := f.args["limit"]; ok {
+				if limit, ok = v.(int); !ok || limit < 0 {
+					return nil, fmt.Errorf("%s: argument limit of type Int, 0 or more, expected", f.key())
+				}
+			}
+			list := []interface{}{}
+			for _, result := range s.index.Search(q, limit) {
+				d, err := s.resolveDivision(f, result.path, result.rank)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, d)
+			}
+			value = list
+		default:
+			return nil, fmt.Errorf("no field %s on type Query", f.name)
+		}
+		data.set(f.key(), value)
+	}
+	return data, nil
+}
+
+// resolveDivision resolves the selection of f on the node at the end of path, of the rank of a search or -1
+func (s *divisionServer) resolveDivision(f *gqlField, path []*Area, rank int) (*gqlObject, error) {
+	if len(f.selection) == 0 {
+		return nil, fmt.Errorf("%s: selection of Division fields expected", f.key())
+	}
+	a := path[len(path)-1]
+	o := &gqlObject{}
+	for _, sub := range f.selection {
+		var value interface{}
+		switch sub.name {
+		case "__typename":
+			value = "Division"
+		case "code":
+			value = a.Code
+		case "name":
+			value = nodeName(a)
+		case "fullName":
+			value = fullName(path, nodeName)
+		case "parentCode":
+			if len(path) > 1 {
+				value = path[len(path)-2].Code
+			}
+		case "depth":
+			value = len(path)
+		case "lft":
+			value = a.Left
+		case "rgt":
+			value = a.Right
+		case "childrenCount":
+			value = len(a.SubAreas)
+		case "rank":
+			if rank >= 0 {
+				value = rank
+			}
+		case "parent":
+			if len(path) > 1 {
+				d, err := s.resolveDivision(sub, path[:len(path)-1], -1)
+				if err != nil {
+					return nil, err
+				}
+				value = d
+			}
+		case "ancestors", "children":
+			list := []interface{}{}
+			var paths [][]*Area
+			if sub.name == "ancestors" {
+				for i := range path[:len(path)-1] {
+					paths = append(paths, path[:i+1])
+				}
+			} else {
+				for _, child := range a.SubAreas {
+					paths = append(paths, append(path[:len(path):len(path)], child))
+				}
+			}
+			for _, p := range paths {
+				d, err := s.resolveDivision(sub, p, -1)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, d)
+			}
+			value = list
+		default:
+			return nil, fmt.Errorf("no field %s on type Division", sub.name)
+		}
+		o.set(sub.key(), value)
+	}
+	return o, nil
+}
+
+// serveGraphQL answers a query of a GET query parameter or of a POST JSON body with its variables
+func (s *divisionServer) serveGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+	switch r.Method {
+	case http.MethodGet:
+		req.Query = r.URL.Query().Get("query")
+		if v := r.URL.Query().Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				writeGraphQLError(w, http.StatusBadRequest, "variables: %v", err)
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeGraphQLError(w, http.StatusBadRequest, "request: %v", err)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeGraphQLError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+		return
+	}
+
+	p := &gqlParser{src: req.Query, variables: req.Variables}
+	selection, err := p.document()
+	if err != nil {
+		writeGraphQLError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	data, err := s.resolveQuery(selection)
+	if err != nil {
+		writeGraphQLError(w, http.StatusOK, "%v", err)
+		return
+	}
+	writeJSON(w, map[string]interface{}{"data": data})
+}
+
+func writeGraphQLError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(map[string]interface{}{"errors": []map[string]string{{"message": fmt.Sprintf(format, args...)}}})
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestGraphQL(t *testing.T) {
+	trees, err := loadTrees("./testdata/mini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(&divisionServer{index: newAreaIndex(trees), graphql: true})
+	defer server.Close()
+
+	post := func(body string, status int) string {
+		t.Helper()
+		resp, err := http.Post(server.URL+"/graphql", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("%s: %s", body, resp.Status)
+		}
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSpace(string(b))
+	}
+
+	tests := []struct {
+		body, want string
+	}{
+		{`{"query": "{ divisions { code children { name childrenCount } } }"}`,
+			`{"data":{"divisions":[{"code":"110000","children":[{"name":"市辖区","childrenCount":1}]},` +
+				`{"code":"130000","children":[{"name":"石家庄市","childrenCount":1}]}]}}`},
+		{`{"query": "query ($c: String!) { d: division(code: $c) { fullName parentCode parent { code } ancestors { depth } } }",
+			"variables": {"c": "110101"}}`,
+			`{"data":{"d":{"fullName":"北京市市辖区东城区","parentCode":"110100","parent":{"code":"110100"},` +
+				`"ancestors":[{"depth":1},{"depth":2}]}}}`},
+		{`{"query": "{ division(code: \"999999\") { code } }"}`, `{"data":{"division":null}}`},
+		{`{"query": "{ search(q: \"河北 长安\", limit: 1) { code rank __typename } }"}`,
+			`{"data":{"search":[{"code":"130102","rank":2,"__typename":"Division"}]}}`},
+		{`{"query": "{ divisions { lat } }"}`, `{"errors":[{"message":"no field lat on type Division"}]}`},
+		{`{"query": "{ division { code } }"}`,
+			`{"errors":[{"message":"division: argument code of type String! expected"}]}`},
+	}
+	for _, test := range tests {
+		if got := post(test.body, http.StatusOK); got != test.want {
+			t.Errorf("%s:\ngot  %s\nwant %s", test.body, got, test.want)
+		}
+	}
+	for _, body := range []string{`{"query": "mutation { x }"}`, `{"query": "{ divisions { code }"}`, `{"query": "{ a } { b }"}`,
+		`{"query": "{ division(code: $c) { code } }"}`, `not json`} {
+		if got := post(body, http.StatusBadRequest); !strings.HasPrefix(got, `{"errors":`) {
+			t.Errorf("%s: %s", body, got)
+		}
+	}
+
+	resp, err := http.Get(server.URL + "/graphql?query=" + url.QueryEscape("{ division(code: \"130000\") { name } }"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if got := strings.TrimSpace(string(b)); got != `{"data":{"division":{"name":"河北省"}}}` {
+		t.Errorf("GET: %s", got)
+	}
+
+	// the endpoint is off without -graphql
+	off := httptest.NewServer(&divisionServer{index: newAreaIndex(trees)})
+	defer off.Close()
+	if resp, err := http.Get(off.URL + "/graphql"); err != nil {
+		t.Fatal(err)
+	} else if resp.Body.Close(); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("without -graphql: %s", resp.Status)
+	}
+}
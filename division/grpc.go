@@ -0,0 +1,131 @@
+//go:build grpc
+
+package main
+
+//go:generate protoc -I proto --go_out=. --go_opt=module=github.com/BionStt/nested/division --go-grpc_out=. --go-grpc_opt=module=github.com/BionStt/nested/division proto/division.proto proto/tree.proto
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/BionStt/nested/division/divisionpb"
+)
+
+func init() {
+	subcommands["serve-grpc"] = runServeGRPC
+}
+
+// grpcServer answers the RPCs of proto/division.proto from the index of the trees, like divisionServer
+type grpcServer struct {
+	divisionpb.UnimplementedDivisionServiceServer
+	index *areaIndex
+}
+
+func newDivisionPB(path []*Area) *divisionpb.Division {
+	d := newDivisionJSON(path)
+	return &divisionpb.Division{Code: d.Code, Name: d.Name, FullName: d.FullName, ParentCode: d.ParentCode,
+		Depth: int32(d.Depth), Lft: d.Lft, Rgt: d.Rgt, Children: int32(d.Children)}
+}
+
+// path finds the nodes from the root down to the division of code
+func (s *grpcServer) path(code string) ([]*Area, error) {
+	a := s.index.ByCode(code)
+	if a == nil {
+		return nil, status.Errorf(codes.NotFound, "division %s not found", code)
+	}
+	return s.index.Path(a), nil
+}
+
+func (s *grpcServer) Lookup(ctx context.Context, req *divisionpb.CodeRequest) (*divisionpb.Division, error) {
+	path, err := s.path(req.GetCode())
+	if err != nil {
+		return nil, err
+	}
+	return newDivisionPB(path), nil
+}
+
+func (s *grpcServer) Children(ctx context.Context, req *divisionpb.CodeRequest) (*divisionpb.DivisionList, error) {
+	path, err := s.path(req.GetCode())
+	if err != nil {
+		return nil, err
+	}
+	list := &divisionpb.DivisionList{}
+	for _, sub := range path[len(path)-1].SubAreas {
+		list.Divisions = append(list.Divisions, newDivisionPB(append(path[:len(path):len(path)], sub)))
+	}
+	return list, nil
+}
+
+func (s *grpcServer) Ancestors(ctx context.Context, req *divisionpb.CodeRequest) (*divisionpb.DivisionList, error) {
+	path, err := s.path(req.GetCode())
+	if err != nil {
+		return nil, err
+	}
+	list := &divisionpb.DivisionList{}
+	for i := range path[:len(path)-1] {
+		list.Divisions = append(list.Divisions, newDivisionPB(path[:i+1]))
+	}
+	return list, nil
+}
+
+func (s *grpcServer) Search(ctx context.Context, req *divisionpb.SearchRequest) (*divisionpb.DivisionList, error) {
+	if req.GetLimit() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "limit must not be negative, not %d", req.GetLimit())
+	}
+	limit := int(req.GetLimit())
+	if limit == 0 {
+		limit = 10
+	}
+	list := &divisionpb.DivisionList{}
+	for _, result := range s.index.Search(req.GetQuery(), limit) {
+		d := newDivisionPB(result.path)
+		d.Rank = int32(result.rank)
+		list.Divisions = append(list.Divisions, d)
+	}
+	return list, nil
+}
+
+// runServeGRPC loads the trees and serves the RPCs of proto/division.proto until the process is stopped
+func runServeGRPC(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("division serve-grpc", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	from := fs.String("from", dataDir, "data `directory` or generated sql file")
+	addr := fs.String("addr", "localhost:9090", "`address` to listen on")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: division serve-grpc [-from dir|file] [-addr host:port]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	trees, err := loadTrees(*from)
+	if err != nil {
+		fmt.Fprintln(stderr, "division serve-grpc:", err)
+		return exitCode(err)
+	}
+	lis, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Fprintln(stderr, "division serve-grpc:", err)
+		return exitIO
+	}
+	server := grpc.NewServer()
+	divisionpb.RegisterDivisionServiceServer(server, &grpcServer{index: newAreaIndex(trees)})
+	logger.Info("serving grpc", "addr", *addr, "from", *from)
+	if err := server.Serve(lis); err != nil {
+		fmt.Fprintln(stderr, "division serve-grpc:", err)
+		return exitIO
+	}
+	return exitOK
+}
@@ -0,0 +1,83 @@
+//go:build grpc
+
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/BionStt/nested/division/divisionpb"
+)
+
+func TestGRPCServer(t *testing.T) {
+	trees, err := loadTrees("./testdata/mini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &grpcServer{index: newAreaIndex(trees)}
+	ctx := context.Background()
+
+	d, err := s.Lookup(ctx, &divisionpb.CodeRequest{Code: "110101"})
+	if err != nil || d.Name != "东城区" || d.FullName != "北京市市辖区东城区" || d.ParentCode != "110100" || d.Depth != 3 ||
+		d.Children != 2 {
+		t.Error(d, err)
+	}
+	if _, err := s.Lookup(ctx, &divisionpb.CodeRequest{Code: "120000"}); status.Code(err) != codes.NotFound {
+		t.Error("lookup of a missing code:", err)
+	}
+	list, err := s.Children(ctx, &divisionpb.CodeRequest{Code: "110101"})
+	if err != nil || len(list.Divisions) != 2 || list.Divisions[1].Code != "110101002000" {
+		t.Error(list, err)
+	}
+	list, err = s.Ancestors(ctx, &divisionpb.CodeRequest{Code: "110101"})
+	if err != nil || len(list.Divisions) != 2 || list.Divisions[0].Code != "110000" || list.Divisions[1].Code != "110100" {
+		t.Error(list, err)
+	}
+	list, err = s.Search(ctx, &divisionpb.SearchRequest{Query: "河北 长安"})
+	if err != nil || len(list.Divisions) != 1 || list.Divisions[0].Code != "130102" {
+		t.Error(list, err)
+	}
+	if _, err := s.Search(ctx, &divisionpb.SearchRequest{Query: "x", Limit: -1}); status.Code(err) != codes.InvalidArgument {
+		t.Error("search of a negative limit:", err)
+	}
+}
+
+func TestGRPCClient(t *testing.T) {
+	trees, err := loadTrees("./testdata/mini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	divisionpb.RegisterDivisionServiceServer(server, &grpcServer{index: newAreaIndex(trees)})
+	go server.Serve(lis)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufconn", grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := divisionpb.NewDivisionServiceClient(conn)
+	ctx := context.Background()
+
+	d, err := client.Lookup(ctx, &divisionpb.CodeRequest{Code: "130102"})
+	if err != nil || d.Name != "长安区" || d.FullName != "河北省石家庄市长安区" || d.Depth != 3 {
+		t.Error(d, err)
+	}
+	if _, err := client.Children(ctx, &divisionpb.CodeRequest{Code: "999999"}); status.Code(err) != codes.NotFound {
+		t.Error("children of a missing code:", err)
+	}
+	list, err := client.Search(ctx, &divisionpb.SearchRequest{Query: "东城", Limit: 1})
+	if err != nil || len(list.Divisions) != 1 || list.Divisions[0].Code != "110101" {
+		t.Error(list, err)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+)
+
+// histTblName is the history table of the nested sets table
+func histTblName() string {
+	return tblName + "_history"
+}
+
+// historySchema creates the history table, a row is valid from valid_from until the day before valid_to
+func historySchema() string {
+	return "CREATE TABLE IF NOT EXISTS `" + histTblName() + "`(\n" +
+		"`code` BIGINT NOT NULL COMMENT 'division code',\n" +
+		"`name` VARCHAR(64) CHARACTER SET 'utf8' NOT NULL COMMENT 'division name',\n" +
+		"`pid` BIGINT NOT NULL COMMENT 'parent code',\n" +
+		"`depth` INT NOT NULL COMMENT 'Level',\n" +
+		"`lft` INT NOT NULL COMMENT 'left index',\n" +
+		"`rgt` INT NOT NULL COMMENT 'right index',\n" +
+		"`valid_from` DATE NOT NULL COMMENT 'first day of the row',\n" +
+		"`valid_to` DATE NULL COMMENT 'day the row was replaced, NULL while current',\n" +
+		"`version` VARCHAR(32) NOT NULL COMMENT 'dataset version of the row',\n" +
+		"  PRIMARY KEY (`code`, `depth`, `valid_from`),\n" +
+		"  INDEX `valid_index` (`valid_from` ASC, `valid_to` ASC),\n" +
+		"  INDEX `lft_index` (`lft` ASC))\n" +
+		"ENGINE = InnoDB DEFAULT CHARACTER SET = utf8 COMMENT = 'versions of nested sets';\n"
+}
+
+// history writes statements recording a new version of the trees in the history table. Rows of nodes whose
+// name, parent or keys changed, and of removed nodes, are closed on date; new rows are inserted for them and
+// for added nodes. Without older, every node gets a row.
+func history(w io.Writer, older, newer []*Area, version, date string) (closed, inserted int) {
+	oldKeys := keysOf(older)
+	match := matchNodes(older, newer)
+	kept := make(map[*Area]bool, len(match))
+	for _, s := range match {
+		kept[s] = true
+	}
+	newKeys := keysOf(newer)
+	_, newOrder := indexNodes(newer)
+
+	closeRow := func(a *Area) {
+		fmt.Fprintf(w, "UPDATE %s SET valid_to = '%s' WHERE code = %s AND lft = %d AND valid_to IS NULL;\n",
+			histTblName(), date, a.Code, oldKeys[a][0])
+		closed++
+	}
+	_, oldOrder := indexNodes(older)
+	for _, n := range oldOrder {
+		if !kept[n.area] {
+			closeRow(n.area)
+		}
+	}
+	changed := make(map[*Area]bool)
+	for _, n := range newOrder {
+		s := match[n.area]
+		if s == nil {
+			continue
+		}
+		if nodeName(s) != nodeName(n.area) || s.ParentCode != n.area.ParentCode || oldKeys[s] != newKeys[n.area] {
+			closeRow(s)
+			changed[n.area] = true
+		}
+	}
+	for _, n := range newOrder {
+		a, k := n.area, newKeys[n.area]
+		if match[a] != nil && !changed[a] {
+			continue
+		}
+		fmt.Fprintf(w, "INSERT INTO %s(code, name, pid, depth, lft, rgt, valid_from, valid_to, version) "+
+			"VALUES(%s, %s, %s, %d, %d, %d, '%s', NULL, %s);\n",
+			histTblName(), a.Code, quote(nodeName(a)), a.ParentCode, k[2], k[0], k[1], date, quote(version))
+		inserted++
+	}
+	return closed, inserted
+}
+
+// runHistory writes the history table schema, or statements recording a version of the division data in it
+func runHistory(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("division history", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	defer func(table string) { tblName = table }(tblName)
+	fs.Var(tableFlag{}, "table", "`name` of the nested sets table")
+	schema := fs.Bool("schema", false, "write the schema of the history table")
+	version := fs.String("version", "", "version `label` of the new rows, required")
+	date := fs.String("date", time.Now().Format("2006-01-02"), "first day of the new rows, and last day of the replaced ones, as YYYY-MM-DD")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: division history [-table name] -schema\n       division history [-table name] -version label [-date YYYY-MM-DD] [old] new")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *schema {
+		if fs.NArg() != 0 {
+			fs.Usage()
+			return exitUsage
+		}
+		_, err := io.WriteString(stdout, historySchema())
+		if err != nil {
+			fmt.Fprintln(stderr, "division history:", err)
+			return exitIO
+		}
+		return exitOK
+	}
+	if _, err := time.Parse("2006-01-02", *date); err != nil || *version == "" || fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	var trees [][]*Area
+	for _, source := range fs.Args() {
+		t, err := loadTrees(source)
+		if err != nil {
+			fmt.Fprintln(stderr, "division history:", err)
+			return exitCode(err)
+		}
+		assignKeys(t)
+		trees = append(trees, t)
+	}
+	if len(trees) == 1 {
+		trees = append([][]*Area{nil}, trees...)
+	}
+	var buf bytes.Buffer
+	closed, inserted := history(&buf, trees[0], trees[1], *version, *date)
+	fmt.Fprintf(stdout, "-- version %s from %s: %d rows closed, %d rows inserted\nBEGIN;\n", *version, *date, closed, inserted)
+	_, err := buf.WriteTo(stdout)
+	if err == nil {
+		_, err = io.WriteString(stdout, "COMMIT;\n")
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "division history:", err)
+		return exitIO
+	}
+	return exitOK
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	out := useStdout(t)
+	var stderr bytes.Buffer
+	script := `CREATE TABLE nested_history(code BIGINT NOT NULL, name VARCHAR(64) NOT NULL, pid BIGINT NOT NULL,
+depth INT NOT NULL, lft INT NOT NULL, rgt INT NOT NULL, valid_from DATE NOT NULL, valid_to DATE NULL,
+version VARCHAR(32) NOT NULL, PRIMARY KEY (code, depth, valid_from));
+`
+	for _, args := range [][]string{
+		{"history", "-version", "v1", "-date", "2020-01-01", "./testdata/mini"},
+		{"history", "-version", "v2", "-date", "2021-01-01", "./testdata/mini", "./testdata/mini2"},
+	} {
+		out.Reset()
+		if code := run(args, &stderr); code != exitOK {
+			t.Fatal("exit code:", code, stderr.String())
+		}
+		script += out.String()
+	}
+	if !strings.HasPrefix(out.String(), "-- version v2 from 2021-01-01: 3 rows closed, 3 rows inserted\nBEGIN;\n") ||
+		!strings.Contains(out.String(), "INSERT INTO nested_history(code, name, pid, depth, lft, rgt, valid_from, valid_to, version) "+
+			"VALUES(130100, '石家庄', 130000, 2, 12, 17, '2021-01-01', NULL, 'v2');") {
+		t.Error(out.String())
+	}
+
+	// the tree as of a date
+	asOf := func(date string) string {
+		return "SELECT code, name, pid, depth, lft, rgt FROM nested_history WHERE valid_from <= '" + date +
+			"' AND (valid_to IS NULL OR valid_to > '" + date + "') ORDER BY lft;\n"
+	}
+	got := sqlite(t, script+asOf("2019-12-31")+"SELECT '--';\n"+asOf("2020-12-31")+"SELECT '--';\n"+asOf("2021-01-01"))
+	want := "--\n" + sqliteRows(t, sqlFileOf(t, "./testdata/mini")) + "--\n" + sqliteRows(t, sqlFileOf(t, "./testdata/mini2"))
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	out.Reset()
+	if code := run([]string{"history", "-schema"}, &stderr); code != exitOK || !strings.HasPrefix(out.String(), "CREATE TABLE IF NOT EXISTS `nested_history`(") {
+		t.Error("exit code:", code, out.String())
+	}
+	for _, args := range [][]string{
+		{"history", "./testdata/mini"},
+		{"history", "-version", "v1", "-date", "2020-1-1", "./testdata/mini"},
+		{"history", "-version", "v1"},
+		{"history", "-schema", "./testdata/mini"},
+	} {
+		if code := run(args, &stderr); code != exitUsage {
+			t.Error(args, "exit code:", code)
+		}
+	}
+}
+
+// sqlFileOf generates the sql file of a data directory
+func sqlFileOf(t *testing.T, dir string) string {
+	name := usePaths(t, dir)
+	var stderr bytes.Buffer
+	if code := run(nil, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	return name
+}
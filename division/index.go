@@ -0,0 +1,60 @@
+package main
+
+import "sync"
+
+// areaIndex finds nodes by code and by name without walking the trees, built once after buildTrees
+type areaIndex struct {
+	roots    []*Area
+	codes    map[string][]*Area // in preorder, a code repeats below its node, e.g. 441900 of 东莞市
+	names    map[string][]*Area // in preorder, names repeat, e.g. 市辖区 or 东城区
+	parents  map[*Area]*Area
+	order    []*Area              // all nodes in preorder
+	keys     map[*Area]*searchKey // of Search, computed at the first one
+	keysOnce sync.Once
+}
+
+// newAreaIndex indexes the nodes of the trees
+func newAreaIndex(trees []*Area) *areaIndex {
+	x := &areaIndex{roots: trees, codes: make(map[string][]*Area), names: make(map[string][]*Area), parents: make(map[*Area]*Area)}
+	var walk func(parent *Area, areas []*Area)
+	walk = func(parent *Area, areas []*Area) {
+		for _, a := range areas {
+			x.order = append(x.order, a)
+			x.codes[a.Code] = append(x.codes[a.Code], a)
+			x.names[nodeName(a)] = append(x.names[nodeName(a)], a)
+			if parent != nil {
+				x.parents[a] = parent
+			}
+			walk(a, a.SubAreas)
+		}
+	}
+	walk(nil, trees)
+	return x
+}
+
+// ByCode returns the node of code, the shallowest of a repeated code, or nil
+func (x *areaIndex) ByCode(code string) *Area {
+	if areas := x.codes[code]; len(areas) > 0 {
+		return areas[0]
+	}
+	return nil
+}
+
+// ByName returns the nodes of name in preorder, or nil
+func (x *areaIndex) ByName(name string) []*Area {
+	return x.names[name]
+}
+
+// Path returns the nodes from the root down to a
+func (x *areaIndex) Path(a *Area) []*Area {
+	depth := 1
+	for p := x.parents[a]; p != nil; p = x.parents[p] {
+		depth++
+	}
+	path := make([]*Area, depth)
+	for i := depth - 1; i >= 0; i-- {
+		path[i] = a
+		a = x.parents[a]
+	}
+	return path
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestAreaIndex(t *testing.T) {
+	street := &Area{Code: "441900003000", Name: "东城街道"}
+	area := &Area{Code: "441900", Name: "东莞市", SubAreas: []*Area{street}}
+	city := &Area{Code: "441900", Name: "东莞市", SubAreas: []*Area{area}}
+	shenzhen := &Area{Code: "440300", Name: "深圳市"}
+	province := &Area{Code: "440000", Name: "广东省", SubAreas: []*Area{shenzhen, city}}
+	beijing := &Area{Code: "110101", Name: "东城区"}
+	x := newAreaIndex([]*Area{{Code: "110000", Name: "北京市", SubAreas: []*Area{beijing}}, province})
+
+	if a := x.ByCode("441900"); a != city {
+		t.Error("441900:", a)
+	}
+	if a := x.ByCode("120000"); a != nil {
+		t.Error("120000:", a)
+	}
+	if areas := x.ByName("东莞市"); len(areas) != 2 || areas[0] != city || areas[1] != area {
+		t.Error("东莞市:", areas)
+	}
+	if areas := x.ByName("东城"); areas != nil {
+		t.Error("东城:", areas)
+	}
+	path := x.Path(street)
+	if len(path) != 4 || path[0] != province || path[1] != city || path[2] != area || path[3] != street {
+		t.Error("path:", path)
+	}
+	if path := x.Path(province); len(path) != 1 || path[0] != province {
+		t.Error("path of a root:", path)
+	}
+}
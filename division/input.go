@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"unicode/utf8"
+)
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+// readJSONFile reads a JSON input file into v, tolerating a leading UTF-8 BOM. v is left untouched if the file is empty.
+func readJSONFile(name string, v interface{}) error {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	data, err = trimBOM(name, data)
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil // an empty file has no records
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return dataErrorf("%s: %v", name, err)
+	}
+	return nil
+}
+
+// trimBOM strips a leading UTF-8 BOM, and rejects UTF-16 encoded data which would decode as garbage
+func trimBOM(name string, data []byte) ([]byte, error) {
+	if bytes.HasPrefix(data, utf16LEBOM) || bytes.HasPrefix(data, utf16BEBOM) || looksUTF16(data) {
+		return nil, dataErrorf("%s: file is UTF-16 encoded, re-save as UTF-8", name)
+	}
+	return bytes.TrimPrefix(data, utf8BOM), nil
+}
+
+// looksUTF16 reports UTF-16 data without BOM, whose ASCII characters come with zero bytes
+func looksUTF16(data []byte) bool {
+	if len(data) < 2 {
+		return false
+	}
+	return (data[0] == 0 && data[1] != 0) || (data[0] != 0 && data[1] == 0)
+}
+
+// UnmarshalJSON decodes a record, remembering fields with invalid UTF-8 which json replaces silently, and
+// keeping the fields of -extra-fields
+func (n *flatNode) UnmarshalJSON(data []byte) error {
+	type record flatNode // without the method
+	var r record
+	if err := json.Unmarshal(data, &r); err != nil {
+		return err
+	}
+	*n = flatNode(r)
+	if utf8.Valid(data) && len(extraFields) == 0 {
+		return nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for k, v := range fields {
+		if !utf8.Valid(v) {
+			n.badUTF8 = append(n.badUTF8, k)
+		}
+	}
+	sort.Strings(n.badUTF8)
+	return n.readExtra(fields)
+}
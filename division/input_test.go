@@ -0,0 +1,25 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadBOM(t *testing.T) {
+	var nodes []flatNode
+	err := readJSONFile("./testdata/bom/provinces.json", &nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 || nodes[0].Code != "110000" {
+		t.Error(nodes)
+	}
+}
+
+func TestReadUTF16(t *testing.T) {
+	var nodes []flatNode
+	err := readJSONFile("./testdata/utf16/provinces.json", &nodes)
+	if err == nil || !strings.Contains(err.Error(), "re-save as UTF-8") {
+		t.Error(err)
+	}
+}
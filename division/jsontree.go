@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// outputFormats are the values of -format, what the -out file holds
+var outputFormats = []string{"sql", "json", "csv", "yaml", "go", "protobuf", "msgpack"}
+
+var outputFormat = "sql"
+
+func isOutputFormat(name string) bool {
+	for _, f := range outputFormats {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonNode is a node of the trees as written with -format json
+type jsonNode struct {
+	Code     string      `json:"code"`
+	Name     string      `json:"name"`
+	Depth    int         `json:"depth"`
+	Left     int32       `json:"lft"`
+	Right    int32       `json:"rgt"`
+	Columns  []jsonField `json:"-"` // the enabled optional columns, written between rgt and children
+	Children []*jsonNode `json:"children"`
+}
+
+// jsonField is the value of an optional column as JSON
+type jsonField struct {
+	name  string
+	value json.RawMessage
+}
+
+// newJSONNode copies the subtree at the end of path, leaves with an empty array of children
+func newJSONNode(path []*Area) (*jsonNode, error) {
+	a := path[len(path)-1]
+	n := &jsonNode{
+		Code:     a.Code,
+		Name:     nodeName(a),
+		Depth:    len(path),
+		Left:     a.Left,
+		Right:    a.Right,
+		Children: make([]*jsonNode, 0, len(a.SubAreas)),
+	}
+	for _, c := range columns {
+		v, err := c.get(path)
+		if err != nil {
+			return nil, err
+		}
+		n.Columns = append(n.Columns, jsonField{c.name, jsonValue(c, v)})
+	}
+	for _, sub := range a.SubAreas {
+		child, err := newJSONNode(append(path[:len(path):len(path)], sub))
+		if err != nil {
+			return nil, err
+		}
+		n.Children = append(n.Children, child)
+	}
+	return n, nil
+}
+
+// jsonValue is the value of a column as -format yaml writes it: empty values of nullable columns as null, text
+// as strings and numbers as they are. Values of neither, such as boundaries as sql expressions, are strings.
+func jsonValue(c column, v string) json.RawMessage {
+	switch {
+	case v == "" && c.null:
+		return json.RawMessage("null")
+	case c.text || !json.Valid([]byte(v)):
+		q, _ := marshalJSON(v)
+		return q
+	}
+	return json.RawMessage(v)
+}
+
+// MarshalJSON writes the fields in order, the optional columns after rgt
+func (n *jsonNode) MarshalJSON() ([]byte, error) {
+	type fields jsonNode // without the method
+	head, err := marshalJSON(struct {
+		*fields
+		Children *struct{} `json:"children,omitempty"` // in place of the children, written last
+	}{fields: (*fields)(n)})
+	if err != nil {
+		return nil, err
+	}
+	b := bytes.NewBuffer(head[:len(head)-1])
+	for _, f := range n.Columns {
+		b.WriteString(`,"` + f.name + `":`)
+		b.Write(f.value)
+	}
+	children, err := marshalJSON(n.Children)
+	if err != nil {
+		return nil, err
+	}
+	b.WriteString(`,"children":`)
+	b.Write(children)
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// marshalJSON is json.Marshal without escaping HTML, like the encoders of the output
+func marshalJSON(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(b.Bytes(), "\n"), nil
+}
+
+// genJSONFile writes the trees into the -out file as a JSON array of the provinces, each nesting its children
+func genJSONFile(trees []*Area) error {
+	return writeFileAtomic(sqlFile, func(w io.Writer) error {
+		return writeJSONTree(w, trees)
+	}, nil)
+}
+
+// writeJSONTree writes the trees as JSON, depths counting from 1 at the top like the depth column
+func writeJSONTree(w io.Writer, trees []*Area) error {
+	roots := make([]*jsonNode, 0, len(trees))
+	for _, p := range trees {
+		n, err := newJSONNode([]*Area{p})
+		if err != nil {
+			return err
+		}
+		roots = append(roots, n)
+	}
+	bw := bufio.NewWriterSize(w, sqlBufferSize)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(roots); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+)
+
+func TestFormatJSON(t *testing.T) {
+	out := usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run([]string{"-format", "json"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var roots []jsonNode
+	if err := json.Unmarshal(data, &roots); err != nil {
+		t.Fatal(err, string(data))
+	}
+	if len(roots) != 2 {
+		t.Fatal(string(data))
+	}
+	p := roots[0]
+	if p.Code != "110000" || p.Name != "北京市" || p.Depth != 1 || p.Left != 1 || p.Right != 10 || len(p.Children) != 1 {
+		t.Error(p)
+	}
+	street := p.Children[0].Children[0].Children[1]
+	if street.Code != "110101002000" || street.Depth != 4 || street.Left != 6 || street.Right != 7 || street.Children == nil {
+		t.Error(street)
+	}
+	if !bytes.Contains(data, []byte(`"rgt":7,"children":[]}`)) {
+		t.Error("leaves without empty children:", string(data))
+	}
+
+	out = usePaths(t, "./testdata/mini")
+	if code := run([]string{"-format", "json", "-columns", "lng,short_name,is_leaf"}, &stderr); code != exitOK {
+		t.Fatal("exit code with columns:", code, stderr.String())
+	}
+	if data, err = ioutil.ReadFile(out); err != nil {
+		t.Fatal(err)
+	}
+	leaf := `{"code":"110101002000","name":"景山街道办事处","depth":4,"lft":6,"rgt":7,"lng":null,"short_name":"景山","is_leaf":1,"children":[]}`
+	if !bytes.Contains(data, []byte(leaf)) || !bytes.HasPrefix(data, []byte(`[{"code":"110000","name":"北京市","depth":1,"lft":1,"rgt":10,"lng":null,"short_name":"北京","is_leaf":0,"children":[{`)) {
+		t.Error("columns:", string(data))
+	}
+	if err := json.Unmarshal(data, &roots); err != nil || len(roots) != 2 {
+		t.Error(err, string(data))
+	}
+
+	for _, args := range [][]string{{"-format", "xml"}, {"-format", "json", "-copy", "-dialect", "postgres"}} {
+		usePaths(t, "./testdata/mini")
+		if code := run(args, &stderr); code != exitUsage {
+			t.Error(args, "exit code:", code)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// lint rules, besides the validation rules
+const (
+	ruleEncoding       = "encoding"
+	ruleBadJSON        = "bad-json"
+	ruleBOM            = "bom"
+	ruleWhitespace     = "whitespace"
+	ruleParentMismatch = "parent-mismatch"
+)
+
+// lintRules are reported in this order, fixable ones are warnings and all the others errors
+var lintRules = []string{ruleEncoding, ruleBadJSON, ruleBadUTF8, ruleBadCode, ruleWrongLevel, ruleDuplicate,
+	ruleOrphan, ruleParentMismatch, ruleEmptyName, ruleBOM, ruleWhitespace}
+
+var fixableRules = map[string]bool{ruleBOM: true, ruleWhitespace: true}
+
+// lintFinding is a problem of an input file, or of a record of it by its position from 1
+type lintFinding struct {
+	finding
+	record int
+}
+
+func (f lintFinding) location() string {
+	if f.record == 0 {
+		return f.file
+	}
+	return fmt.Sprintf("%s record %d %s", f.file, f.record, f.code)
+}
+
+// lintFile is an input file with its records decoded as is, to be written back when fixed. Files which do not
+// decode cleanly are unsafe to write back and kept as they are.
+type lintFile struct {
+	name    string
+	raw     []byte
+	records []map[string]interface{}
+	fixed   bool
+	unsafe  bool
+}
+
+// linter checks the input files from top to bottom, so parents of each level are known
+type linter struct {
+	findings []lintFinding
+	seen     map[string]string // location by code
+	levels   []map[string]bool // codes by level
+	files    []*lintFile
+}
+
+func (l *linter) add(rule, file string, record int, code, format string, args ...interface{}) {
+	l.findings = append(l.findings, lintFinding{finding{rule, file, code, fmt.Sprintf(format, args...)}, record})
+}
+
+// lintLevel checks the file of a level, it returns nil when the file is missing
+func (l *linter) lintLevel(dir string, level int) (*lintFile, error) {
+	file := inputLevels()[level-1].file
+	codes := make(map[string]bool)
+	l.levels = append(l.levels, codes)
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, file))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	lf := &lintFile{name: file, raw: data, unsafe: !utf8.Valid(data)}
+	if bytes.HasPrefix(data, utf8BOM) {
+		l.add(ruleBOM, file, 0, "", "UTF-8 BOM")
+		lf.fixed = true
+	}
+	data, err = trimBOM(file, data)
+	if err != nil {
+		l.add(ruleEncoding, file, 0, "", "UTF-16 encoded")
+		lf.unsafe = true
+		return lf, nil
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return lf, nil
+	}
+	var nodes []flatNode
+	if err = json.Unmarshal(data, &nodes); err != nil {
+		l.add(ruleBadJSON, file, 0, "", "%v", err)
+		lf.unsafe = true
+		return lf, nil
+	}
+	if err = json.Unmarshal(data, &lf.records); err != nil {
+		return nil, err
+	}
+
+	var above map[string]bool
+	if level > 1 {
+		above = l.levels[level-2]
+	}
+	for i := range nodes {
+		n, r := &nodes[i], i+1
+		code := strings.TrimSpace(n.Code)
+		for _, f := range checkUTF8(file, n) {
+			l.findings = append(l.findings, lintFinding{f, r})
+		}
+		for _, field := range []string{"code", "name", "parent_code"} {
+			if s, ok := lf.records[i][field].(string); ok && strings.TrimSpace(s) != s {
+				l.add(ruleWhitespace, file, r, code, "whitespace around %s %q", field, s)
+				lf.records[i][field] = strings.TrimSpace(s)
+				lf.fixed = true
+			}
+		}
+		if strings.TrimSpace(n.Name) == "" {
+			l.add(ruleEmptyName, file, r, code, "empty or whitespace-only name")
+		}
+		// a city repeated as its own area is no duplicate
+		if at, ok := l.seen[code]; ok && (!above[code] || codes[code]) {
+			l.add(ruleDuplicate, file, r, code, "also at %s", at)
+		} else if !ok {
+			l.seen[code] = fmt.Sprintf("%s record %d", file, r)
+		}
+		codes[code] = true
+
+		switch fileLevel(code, level, above[code]) {
+		case 0:
+			l.add(ruleBadCode, file, r, code, "not a division code")
+			continue
+		case level:
+		default:
+			for _, f := range checkLevel(file, level, &flatNode{Code: code}, above) {
+				l.findings = append(l.findings, lintFinding{f, r})
+			}
+			continue
+		}
+		if level == 1 {
+			continue
+		}
+		parent := codePrefix(code, level-1)
+		if pc := strings.TrimSpace(n.ParentCode); pc != "" && pc != parent {
+			l.add(ruleParentMismatch, file, r, code, "parent_code %s, but the code belongs under %s", pc, parent)
+		}
+		if !l.levels[level-2][parent] {
+			l.add(ruleOrphan, file, r, code, "parent %s not in %s", parent, inputLevels()[level-2].file)
+		}
+	}
+	return lf, nil
+}
+
+// writeFixed writes the records of a fixed file into dir, compact like the input files, or the file as it is
+func (lf *lintFile) writeFixed(dir string) error {
+	return writeFileAtomic(filepath.Join(dir, lf.name), func(w io.Writer) error {
+		if !lf.fixed || lf.unsafe {
+			_, err := w.Write(lf.raw)
+			return err
+		}
+		data, err := compactJSON(lf.records)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}, nil)
+}
+
+// compactJSON encodes v on one line without a line break at the end, like the input files
+func compactJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// lintReport writes findings grouped by rule, and returns the number of errors and warnings
+func lintReport(w io.Writer, findings []lintFinding, fixed bool) (errors, warnings int) {
+	for _, rule := range lintRules {
+		var found []lintFinding
+		for _, f := range findings {
+			if f.rule == rule {
+				found = append(found, f)
+			}
+		}
+		if len(found) == 0 {
+			continue
+		}
+		kind := "error"
+		if fixableRules[rule] {
+			kind = "warning"
+			warnings += len(found)
+		} else {
+			errors += len(found)
+		}
+		if len(found) > 1 {
+			kind += "s"
+		}
+		if fixableRules[rule] && fixed {
+			kind += ", fixed"
+		}
+		fmt.Fprintf(w, "%s: %d %s\n", rule, len(found), kind)
+		for _, f := range found {
+			fmt.Fprintf(w, "  %s: %s\n", f.location(), f.msg)
+		}
+	}
+	return errors, warnings
+}
+
+// runLint checks the input files without building, and fixes BOMs and whitespace with -fix
+func runLint(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("division lint", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fix := fs.Bool("fix", false, "remove BOMs and whitespace around fields, in place unless -fix-dir is given")
+	fixDir := fs.String("fix-dir", "", "`directory` to write fixed copies of the input files into")
+	fs.Var(codeLevelsFlag{}, "code-levels", "comma separated `prefix:width` of the codes of each level")
+	defer func(specs []codeSpec) { codeSpecs = specs }(codeSpecs)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: division lint [-fix] [-fix-dir dir] [-code-levels spec] [data-dir]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() > 1 || (*fixDir != "" && !*fix) {
+		fs.Usage()
+		return exitUsage
+	}
+	dir := dataDir
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		fmt.Fprintln(stderr, "division lint:", err)
+		return exitIO
+	}
+
+	l := &linter{seen: make(map[string]string)}
+	for level := 1; level <= len(inputLevels()); level++ {
+		lf, err := l.lintLevel(dir, level)
+		if err != nil {
+			fmt.Fprintln(stderr, "division lint:", err)
+			return exitIO
+		}
+		if lf != nil {
+			l.files = append(l.files, lf)
+		}
+	}
+
+	if *fix {
+		out := dir
+		if *fixDir != "" {
+			out = *fixDir
+			if err := os.MkdirAll(out, 0755); err != nil {
+				fmt.Fprintln(stderr, "division lint:", err)
+				return exitIO
+			}
+		}
+		for _, lf := range l.files {
+			if lf.fixed && lf.unsafe {
+				fmt.Fprintf(stderr, "division lint: %s left unfixed, it is not valid UTF-8 JSON\n", lf.name)
+			}
+			if (!lf.fixed || lf.unsafe) && out == dir {
+				continue
+			}
+			if err := lf.writeFixed(out); err != nil {
+				fmt.Fprintln(stderr, "division lint:", err)
+				return exitIO
+			}
+		}
+	}
+
+	errors, warnings := lintReport(stdout, l.findings, *fix)
+	if errors > 0 {
+		fmt.Fprintf(stderr, "division lint: %d errors, %d warnings in %s\n", errors, warnings, dir)
+		return exitData
+	}
+	return exitOK
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const lintWant = `invalid-utf8: 1 error
+  streets.json record 1 110101001000: invalid UTF-8 in name
+bad-code: 1 error
+  areas.json record 2 11010x: not a division code
+wrong-level: 1 error
+  areas.json record 4 441900: code belongs to cities (level 2)
+duplicate: 1 error
+  cities.json record 3 130100: also at cities.json record 2
+orphan: 1 error
+  cities.json record 4 150100: parent 150000 not in provinces.json
+parent-mismatch: 1 error
+  cities.json record 2 130100: parent_code 110000, but the code belongs under 130000
+empty-name: 1 error
+  areas.json record 3 110105: empty or whitespace-only name
+bom: 1 warning
+  provinces.json: UTF-8 BOM
+whitespace: 3 warnings
+  provinces.json record 1 110000: whitespace around name " 北京市"
+  areas.json record 3 110105: whitespace around name "  "
+  areas.json record 5 130102: whitespace around code "130102 "
+`
+
+func TestLint(t *testing.T) {
+	out := useStdout(t)
+	var stderr bytes.Buffer
+	if code := run([]string{"lint", "./testdata/lint"}, &stderr); code != exitData {
+		t.Error("exit code:", code)
+	}
+	if out.String() != lintWant {
+		t.Error(out.String())
+	}
+	if want := "division lint: 7 errors, 4 warnings in ./testdata/lint\n"; stderr.String() != want {
+		t.Error(stderr.String())
+	}
+
+	out.Reset()
+	if code := run([]string{"lint", "./testdata/mini"}, &stderr); code != exitOK || out.Len() > 0 {
+		t.Error("exit code:", code, out.String())
+	}
+	for _, args := range [][]string{{"lint", "-fix-dir", "x", "./testdata/mini"}, {"lint", "a", "b"}} {
+		if code := run(args, &stderr); code != exitUsage {
+			t.Error(args, "exit code:", code)
+		}
+	}
+}
+
+func TestLintFix(t *testing.T) {
+	out := useStdout(t)
+	var stderr bytes.Buffer
+	fixed := t.TempDir()
+	if code := run([]string{"lint", "-fix", "-fix-dir", fixed, "./testdata/lint"}, &stderr); code != exitData {
+		t.Error("exit code:", code)
+	}
+	if !strings.Contains(out.String(), "whitespace: 3 warnings, fixed\n") {
+		t.Error(out.String())
+	}
+	read := func(dir, file string) string {
+		b, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	if got, want := read(fixed, provincesFile), `[{"code":"110000","name":"北京市"},{"code":"130000","name":"河北省"}]`; got != want {
+		t.Error(got)
+	}
+	for _, file := range []string{citiesFile, streetsFile} {
+		if read(fixed, file) != read("./testdata/lint", file) {
+			t.Error(file, "changed")
+		}
+	}
+
+	// fixed in place, errors are left alone
+	for _, file := range []string{provincesFile, citiesFile, areasFile} {
+		if err := os.Remove(filepath.Join(fixed, file)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(fixed, provincesFile), []byte("\xEF\xBB\xBF[{\"code\":\"110000\",\"name\":\"北京市 \"}]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if code := run([]string{"lint", "-fix", fixed}, &stderr); code != exitData {
+		t.Error("exit code:", code)
+	}
+	if got, want := read(fixed, provincesFile), `[{"code":"110000","name":"北京市"}]`; got != want {
+		t.Error(got)
+	}
+	out.Reset()
+	if code := run([]string{"lint", fixed}, &stderr); code != exitData {
+		t.Error("exit code:", code)
+	}
+	if want := "invalid-utf8: 1 error\n  streets.json record 1 110101001000: invalid UTF-8 in name\n" +
+		"orphan: 1 error\n  streets.json record 1 110101001000: parent 110101 not in areas.json\n"; out.String() != want {
+		t.Error(out.String())
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// dsn is the data source of -dsn, to load the trees into instead of writing the sql file
+var dsn string
+
+// driverNames are the database/sql drivers of the dialects. Drivers are registered by the driver_*.go files
+// built with the tag of the dialect, e.g. go build -tags postgres, or by a file of the same kind for the others.
+var driverNames = map[string]string{
+	"mysql":      "mysql",
+	"postgres":   "postgres",
+	"sqlite":     "sqlite",
+	"mssql":      "sqlserver",
+	"oracle":     "oracle",
+	"clickhouse": "clickhouse",
+	"duckdb":     "duckdb",
+}
+
+// hasDriver tells whether the driver of the dialect is built in
+func hasDriver() bool {
+	for _, d := range sql.Drivers() {
+		if d == driverNames[dialect] {
+			return true
+		}
+	}
+	return false
+}
+
+// placeholder is the parameter n of a prepared statement, from 1
+func placeholder(n int) string {
+	switch dialect {
+	case "postgres", "duckdb":
+		return "$" + strconv.Itoa(n)
+	case "mssql":
+		return "@p" + strconv.Itoa(n)
+	case "oracle":
+		return ":" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// loadStmt inserts a row through a prepared statement, with -on-conflict as in the sql file
+func loadStmt() string {
+	names := insertColumns()
+	params := make([]string, len(names))
+	for i, name := range names {
+		names[i] = quoteIdent(name)
+		params[i] = placeholder(i + 1)
+	}
+	return "INSERT INTO " + tableRef() + "(" + strings.Join(names, ", ") + ") VALUES(" + strings.Join(params, ", ") + ")" +
+		conflictClause()
+}
+
+// execStmts runs the statements of a script, one a line, leaving out the GO separators of SQL Server and the
+// semicolons, which drivers do not take
+func execStmts(exec func(query string, args ...interface{}) (sql.Result, error), script string) error {
+	for _, line := range strings.Split(script, "\n") {
+		stmt := strings.TrimSuffix(strings.TrimSpace(line), ";")
+		if stmt == "" || stmt == "GO" {
+			continue
+		}
+		if _, err := exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadDB inserts the trees into the database of -dsn one row at a time through a prepared statement, in one
+// transaction or in one of every -commit-every rows. The table is created first with -with-schema and emptied
+// in the first transaction with -clean, like by the sql file.
+func loadDB(trees []*Area) (rows int, err error) {
+	db, err := sql.Open(driverNames[dialect], dsn)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	if withSchema {
+		if err = execStmts(db.Exec, schemaStmts()); err != nil {
+			return 0, err
+		}
+	}
+
+	var tx *sql.Tx
+	var stmt *sql.Stmt
+	begin := func() (err error) {
+		if tx, err = db.Begin(); err != nil {
+			return err
+		}
+		stmt, err = tx.Prepare(loadStmt())
+		return err
+	}
+	commit := func() error {
+		stmt.Close()
+		return tx.Commit()
+	}
+	if err = begin(); err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+	if clean != "" {
+		if err = execStmts(tx.Exec, cleanStmt()); err != nil {
+			return 0, err
+		}
+	}
+
+	args := make([]interface{}, 6+len(columns))
+	for _, p := range trees {
+		err = walkSubtree([]*Area{p}, func(path []*Area) error {
+			area := path[len(path)-1]
+			args[0], args[1], args[2] = area.Code, nodeName(area), area.ParentCode
+			args[3], args[4], args[5] = len(path), area.Left, area.Right
+			for i, c := range columns {
+				v, err := c.get(path)
+				if err != nil {
+					return err
+				}
+				if v == "" && c.null {
+					args[6+i] = nil
+				} else {
+					args[6+i] = v
+				}
+			}
+			if _, err := stmt.Exec(args...); err != nil {
+				return err
+			}
+			if rows++; commitEvery > 0 && rows%commitEvery == 0 {
+				if err := commit(); err != nil {
+					return err
+				}
+				return begin()
+			}
+			return nil
+		})
+		if err != nil {
+			return rows, err
+		}
+	}
+	return rows, commit()
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// recorder is a database/sql driver recording the statements run through it, queries return rows
+type recorder struct {
+	log  []string
+	rows [][]driver.Value
+}
+
+var testDB = &recorder{}
+
+func init() {
+	sql.Register("recorder", testDB)
+}
+
+func (r *recorder) Open(name string) (driver.Conn, error) {
+	return &recorderConn{r}, nil
+}
+
+type recorderConn struct {
+	r *recorder
+}
+
+func (c *recorderConn) Prepare(query string) (driver.Stmt, error) {
+	if strings.HasPrefix(query, "INSERT") {
+		c.r.log = append(c.r.log, "prepare "+query)
+	}
+	return &recorderStmt{c.r, query}, nil
+}
+
+func (c *recorderConn) Close() error {
+	return nil
+}
+
+func (c *recorderConn) Begin() (driver.Tx, error) {
+	c.r.log = append(c.r.log, "begin")
+	return c, nil
+}
+
+func (c *recorderConn) Commit() error {
+	c.r.log = append(c.r.log, "commit")
+	return nil
+}
+
+func (c *recorderConn) Rollback() error {
+	c.r.log = append(c.r.log, "rollback")
+	return nil
+}
+
+type recorderStmt struct {
+	r     *recorder
+	query string
+}
+
+func (s *recorderStmt) Close() error {
+	return nil
+}
+
+func (s *recorderStmt) NumInput() int {
+	return strings.Count(s.query, "?")
+}
+
+func (s *recorderStmt) Exec(args []driver.Value) (driver.Result, error) {
+	values := make([]string, len(args))
+	for i, a := range args {
+		values[i] = fmt.Sprint(a)
+	}
+	switch {
+	case strings.HasPrefix(s.query, "INSERT"):
+		s.r.log = append(s.r.log, "insert "+strings.Join(values, ","))
+		return driver.RowsAffected(1), nil
+	case len(args) > 0:
+		s.r.log = append(s.r.log, "exec "+strings.Join(values, ","))
+	default:
+		s.r.log = append(s.r.log, "exec "+s.query)
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (s *recorderStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.r.log = append(s.r.log, "query "+s.query)
+	return &recorderRows{s.r.rows}, nil
+}
+
+// recorderRows returns the rows of the recorder, as many columns as the first row has
+type recorderRows struct {
+	rows [][]driver.Value
+}
+
+func (r *recorderRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return nil
+	}
+	return make([]string, len(r.rows[0]))
+}
+
+func (r *recorderRows) Close() error {
+	return nil
+}
+
+func (r *recorderRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func useRecorder(t *testing.T) {
+	old := driverNames["sqlite"]
+	driverNames["sqlite"] = "recorder"
+	testDB.log, testDB.rows = nil, nil
+	t.Cleanup(func() { driverNames["sqlite"] = old })
+}
+
+func TestLoadDB(t *testing.T) {
+	out := usePaths(t, "./testdata/mini")
+	useRecorder(t)
+	var stderr bytes.Buffer
+	args := []string{"-dialect", "sqlite", "-dsn", "test", "-with-schema", "-clean", "delete", "-commit-every", "5", "-columns", "lng"}
+	if code := run(args, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Error("sql file written:", err)
+	}
+	log := testDB.log
+	if len(log) != 4+1+1+1+5+1+1+1+4+1 {
+		t.Fatal(strings.Join(log, "\n"))
+	}
+	if !strings.HasPrefix(log[0], `exec CREATE TABLE IF NOT EXISTS "nested"(`) || log[4] != "begin" || log[6] != `exec DELETE FROM "nested"` {
+		t.Error(strings.Join(log[:7], "\n"))
+	}
+	if log[5] != `prepare INSERT INTO "nested"("id", "node", "pid", "depth", "lft", "rgt", "lng") VALUES(?, ?, ?, ?, ?, ?, ?)` {
+		t.Error(log[5])
+	}
+	if log[7] != "insert 110000,北京市,0,1,1,10,<nil>" || log[12] != "commit" || log[13] != "begin" || log[len(log)-1] != "commit" {
+		t.Error(strings.Join(log, "\n"))
+	}
+}
+
+func TestLoadDBNoDriver(t *testing.T) {
+	usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run([]string{"-dialect", "oracle", "-dsn", "test"}, &stderr); code != exitUsage {
+		t.Error("exit code:", code)
+	}
+	if !strings.Contains(stderr.String(), "build with -tags oracle") {
+		t.Error(stderr.String())
+	}
+}
+
+func TestPlaceholders(t *testing.T) {
+	defer func(d string) { dialect = d }(dialect)
+	for d, want := range map[string]string{"mysql": "?", "postgres": "$3", "mssql": "@p3", "oracle": ":3"} {
+		dialect = d
+		if p := placeholder(3); p != want {
+			t.Error(d, p)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// loadDataFile is the csv file of -load-data, which the sql file loads with LOAD DATA LOCAL INFILE in place of
+// the inserts
+var loadDataFile string
+
+// loadDataStmt loads the csv file into the table. Fields are read as written by genLoadDataCSV: text in double
+// quotes with quotes in it doubled and no escapes, and NULL unquoted. -on-conflict update replaces existing rows
+// and nothing ignores them.
+func loadDataStmt() string {
+	names := insertColumns()
+	for i, name := range names {
+		names[i] = quoteIdent(name)
+	}
+	var conflict string
+	switch onConflict {
+	case "update":
+		conflict = " REPLACE"
+	case "nothing":
+		conflict = " IGNORE"
+	}
+	return "LOAD DATA LOCAL INFILE " + quoteText(filepath.ToSlash(loadDataFile)) + conflict + " INTO TABLE " + tableRef() +
+		` CHARACTER SET utf8mb4 FIELDS TERMINATED BY ',' OPTIONALLY ENCLOSED BY '"' ESCAPED BY '' LINES TERMINATED BY '\n'` +
+		" (" + strings.Join(names, ", ") + ");\n"
+}
+
+// quoteCSV quotes a text field of the csv file of -load-data, unquoted fields being NULL or numbers
+func quoteCSV(s string) string {
+	return `"` + strings.Replace(s, `"`, `""`, -1) + `"`
+}
+
+// genLoadDataCSV writes the rows of the trees into the csv file of -load-data in preorder, without a header
+func genLoadDataCSV(trees []*Area) error {
+	var check func(string) error
+	if selfCheck {
+		check = func(tmp string) error {
+			return checkLoadDataCSV(tmp, trees)
+		}
+	}
+	return writeFileAtomic(loadDataFile, func(w io.Writer) error {
+		bw := bufio.NewWriter(w)
+		for _, p := range trees {
+			err := walkSubtree([]*Area{p}, func(path []*Area) error {
+				area := path[len(path)-1]
+				fields := []string{area.Code, quoteCSV(nodeName(area)), area.ParentCode, itoa(int32(len(path))),
+					itoa(area.Left), itoa(area.Right)}
+				for _, c := range columns {
+					v, err := c.get(path)
+					if err != nil {
+						return err
+					}
+					switch {
+					case v == "" && c.null:
+						v = "NULL"
+					case c.text:
+						v = quoteCSV(v)
+					}
+					fields = append(fields, v)
+				}
+				_, err := bw.WriteString(strings.Join(fields, ",") + "\n")
+				return err
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return bw.Flush()
+	}, check)
+}
+
+// checkLoadDataCSV reads the csv file of -load-data back into trees and compares them with the trees it was
+// written from, like checkSQLFile does the sql file
+func checkLoadDataCSV(name string, trees []*Area) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return internalErrorf("self-check: %v", err)
+	}
+	parsed, err := treeFromSQL([]insertStmt{{line: 1, columns: insertColumns(), values: records}})
+	if err != nil {
+		return internalErrorf("self-check: %v", err)
+	}
+	err = compareTrees(trees, parsed)
+	if err != nil {
+		return internalErrorf("self-check: %v", err)
+	}
+	return nil
+}
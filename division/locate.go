@@ -0,0 +1,183 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BionStt/nested"
+)
+
+// locateCell is the size in degrees of the tiles of the locator index
+const locateCell = 1.0
+
+// region is a node with its boundary
+type region struct {
+	path []*Area // from root to the node
+	*nested.Region
+}
+
+// locator finds divisions containing points, regions are indexed by the tiles their bounding boxes overlap
+type locator struct {
+	tiles map[[2]int][]*region
+}
+
+// newLocator indexes the boundaries of the trees
+func newLocator(trees []*Area) (*locator, error) {
+	l := &locator{tiles: make(map[[2]int][]*region)}
+	var walk func(path []*Area) error
+	walk = func(path []*Area) error {
+		area := path[len(path)-1]
+		if area.Boundary != nil {
+			raw, err := boundaries.geometry(area.Boundary)
+			if err != nil {
+				return err
+			}
+			polygons, err := nested.ParseGeometry(raw)
+			if err != nil {
+				return dataErrorf("boundary of %s: %v", area.Code, err)
+			}
+			if len(polygons) > 0 {
+				l.add(&region{append([]*Area(nil), path...), nested.NewRegion(polygons)})
+			}
+		}
+		for _, sub := range area.SubAreas {
+			if err := walk(append(path, sub)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, p := range trees {
+		if err := walk([]*Area{p}); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+func (l *locator) add(r *region) {
+	x0, y0 := tile(r.MinX, r.MinY)
+	x1, y1 := tile(r.MaxX, r.MaxY)
+	for x := x0; x <= x1; x++ {
+		for y := y0; y <= y1; y++ {
+			l.tiles[[2]int{x, y}] = append(l.tiles[[2]int{x, y}], r)
+		}
+	}
+}
+
+func tile(lng, lat float64) (int, int) {
+	return int(math.Floor(lng / locateCell)), int(math.Floor(lat / locateCell))
+}
+
+// locate returns the path from root to the deepest division containing the point, or nil if none does. Points
+// on a boundary are inside, so a point on the border of neighbors is in both of them, and the one with the
+// smallest code is chosen. Divisions without boundary, such as 市辖区 placeholders, are only on the path.
+func (l *locator) locate(lng, lat float64) []*Area {
+	x, y := tile(lng, lat)
+	var found []*region
+	for _, r := range l.tiles[[2]int{x, y}] {
+		if r.Contains(lng, lat) {
+			found = append(found, r)
+		}
+	}
+	if len(found) == 0 {
+		return nil
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if len(found[i].path) != len(found[j].path) {
+			return len(found[i].path) > len(found[j].path)
+		}
+		return found[i].path[len(found[i].path)-1].Code < found[j].path[len(found[j].path)-1].Code
+	})
+	return found[0].path
+}
+
+// loadLocator attaches the boundaries of a GeoJSON file or directory of them to the trees, as -boundaries
+// does, and indexes them
+func loadLocator(trees []*Area, path string) (*locator, error) {
+	defer func(old string) { boundariesPath = old }(boundariesPath)
+	boundariesPath = path
+	if err := loadBoundaries(trees); err != nil {
+		return nil, err
+	}
+	defer boundaries.close()
+	return newLocator(trees)
+}
+
+// parsePoint reads a point as lng,lat, the order of GeoJSON, e.g. 113.93,22.53
+func parsePoint(s string) (lng, lat float64, err error) {
+	parts := strings.Split(s, ",")
+	if len(parts) == 2 {
+		lng, err = strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		if err == nil {
+			lat, err = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		}
+		if err == nil && lng >= -180 && lng <= 180 && lat >= -90 && lat <= 90 {
+			return lng, lat, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("%q is not a point lng,lat", s)
+}
+
+// runLocate finds the divisions containing points, one an argument or a line of stdin, and prints the code and
+// full name of the deepest one separated by a tab
+func runLocate(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("division locate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	from := fs.String("from", dataDir, "data `directory` or generated sql file")
+	bounds := fs.String("boundaries", "", "GeoJSON `file or directory` of boundaries with codes in properties")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: division locate [-from dir|file] -boundaries file|dir [lng,lat...]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *bounds == "" {
+		fs.Usage()
+		return exitUsage
+	}
+
+	points := fs.Args()
+	if len(points) == 0 {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintln(stderr, "division locate:", err)
+			return exitIO
+		}
+		points = strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	}
+	trees, err := loadTrees(*from)
+	if err != nil {
+		fmt.Fprintln(stderr, "division locate:", err)
+		return exitCode(err)
+	}
+	l, err := loadLocator(trees, *bounds)
+	if err != nil {
+		fmt.Fprintln(stderr, "division locate:", err)
+		return exitCode(err)
+	}
+	// a point in no division prints empty fields, and like a point which does not parse exits with exitData
+	code := exitOK
+	for _, point := range points {
+		lng, lat, err := parsePoint(strings.TrimSuffix(point, "\r"))
+		if err != nil {
+			fmt.Fprintln(stderr, "division locate:", err)
+			code = exitData
+			continue
+		}
+		path := l.locate(lng, lat)
+		if path == nil {
+			fmt.Fprintln(stdout, "\t")
+			code = exitData
+			continue
+		}
+		fmt.Fprintf(stdout, "%s\t%s\n", path[len(path)-1].Code, fullName(path, nodeName))
+	}
+	return code
+}
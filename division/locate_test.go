@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocate(t *testing.T) {
+	usePaths(t, "./testdata/mini")
+	trees, err := loadTrees("./testdata/mini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := loadLocator(trees, "./testdata/locate/boundaries.geojson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if boundariesPath != "" {
+		t.Error("-boundaries left set:", boundariesPath)
+	}
+
+	cases := []struct {
+		lng, lat float64
+		want     string // codes from root
+	}{
+		{8, 8, "110000"},
+		{4, 2, "110000 110100"},
+		{1.5, 1.5, "110000"},               // in the hole of 110100
+		{3.5, 3.2, "110000 110100 110101"}, // second polygon of 110101
+		{0.5, 0.2, "110000 110100 110101"},
+		{15, 1, "130000"},
+		{13, 2, "130000 130100 130102"}, // 130100 has no boundary
+		{10, 0, "110000"},               // on the border of 110000 and 130000, the smaller code
+		{5, 3, "110000 110100"},
+		{30, 30, ""},
+		{-0.5, 5, ""},
+	}
+	for _, c := range cases {
+		var codes []string
+		for _, a := range l.locate(c.lng, c.lat) {
+			codes = append(codes, a.Code)
+		}
+		if got := strings.Join(codes, " "); got != c.want {
+			t.Errorf("%v, %v: %q, want %q", c.lng, c.lat, got, c.want)
+		}
+	}
+
+	for _, s := range []string{"113.93,22.53", " 113.93 , 22.53"} {
+		if lng, lat, err := parsePoint(s); err != nil || lng != 113.93 || lat != 22.53 {
+			t.Error(s, lng, lat, err)
+		}
+	}
+	for _, s := range []string{"", "113.93", "22.53,113.93,1", "a,b", "190,0", "0,-91"} {
+		if _, _, err := parsePoint(s); err == nil {
+			t.Errorf("%q parsed", s)
+		}
+	}
+}
+
+func TestRunLocate(t *testing.T) {
+	out := useStdout(t)
+	defer func(r io.Reader) { stdin = r }(stdin)
+	stdin = strings.NewReader("13,2\n30,30\n")
+	var stderr bytes.Buffer
+	args := []string{"locate", "-from", "./testdata/mini", "-boundaries", "./testdata/locate/boundaries.geojson"}
+	if code := run(args, &stderr); code != exitData {
+		t.Error("exit code with a point in no division:", code, stderr.String())
+	}
+	if want := "130102\t河北省石家庄市长安区\n\t\n"; out.String() != want {
+		t.Error(out.String())
+	}
+
+	out.Reset()
+	if code := run(append(args, "3.5,3.2"), &stderr); code != exitOK {
+		t.Error("exit code:", code, stderr.String())
+	}
+	if out.String() != "110101\t北京市市辖区东城区\n" {
+		t.Error(out.String())
+	}
+	stderr.Reset()
+	if code := run(append(args, "3.5"), &stderr); code != exitData || !strings.Contains(stderr.String(), "not a point") {
+		t.Error("exit code of a bad point:", code, stderr.String())
+	}
+	if code := run([]string{"locate", "-from", "./testdata/mini", "1,1"}, &stderr); code != exitUsage {
+		t.Error("exit code without -boundaries:", code)
+	}
+}
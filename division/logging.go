@@ -0,0 +1,36 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+)
+
+// logFormats are the values of -log-format
+var logFormats = []string{"text", "json"}
+
+// logger reports the build on stderr with key-value fields, text for people or JSON lines for tools wrapping
+// the build, e.g. to extract node counts and durations in CI
+var logger = newLogger(logOutput, "text", slog.LevelInfo)
+
+// logOutput is where runGenerate logs, replaced in tests
+var logOutput io.Writer = os.Stderr
+
+func newLogger(w io.Writer, format string, level slog.Level) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+	if format == "json" {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// logLevel is debug with -v, warnings and errors only with -q, otherwise info
+func logLevel(verbose, quiet bool) slog.Level {
+	switch {
+	case verbose:
+		return slog.LevelDebug
+	case quiet:
+		return slog.LevelWarn
+	}
+	return slog.LevelInfo
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func useLogOutput(t *testing.T) *bytes.Buffer {
+	var out bytes.Buffer
+	old := logOutput
+	logOutput = &out
+	t.Cleanup(func() { logOutput = old })
+	return &out
+}
+
+func TestLogJSON(t *testing.T) {
+	usePaths(t, "./testdata/mini")
+	out := useLogOutput(t)
+	var stderr bytes.Buffer
+	if code := run([]string{"-log-format", "json", "-v"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	events := make(map[string]map[string]interface{})
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var event map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatal(err, line)
+		}
+		events[event["msg"].(string)] = event
+	}
+	if e := events["loaded levels"]; e == nil || e["provinces"] != 2.0 || e["streets"] != 3.0 {
+		t.Error("loaded levels:", events["loaded levels"])
+	}
+	if e := events["tree depth"]; e == nil || e["level"] != "DEBUG" || e["depth"] != 4.0 {
+		t.Error("tree depth:", events["tree depth"])
+	}
+	if e := events["sql written"]; e == nil || e["duration"] == nil {
+		t.Error("sql written:", events["sql written"])
+	}
+}
+
+func TestLogQuiet(t *testing.T) {
+	usePaths(t, "./testdata/mini")
+	out := useLogOutput(t)
+	var stderr bytes.Buffer
+	if code := run([]string{"-q"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	if out.Len() > 0 {
+		t.Error(out.String())
+	}
+	if code := run([]string{"-q", "-v"}, &stderr); code != exitUsage {
+		t.Error("exit code:", code)
+	}
+	if code := run([]string{"-log-format", "xml"}, &stderr); code != exitUsage {
+		t.Error("exit code:", code)
+	}
+	// the default logger is restored after the run
+	if logger.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("debug enabled after the run")
+	}
+}
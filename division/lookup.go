@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// lookup table options, the table is written with -lookup-csv
+var (
+	lookupCSV    string
+	lookupShort  bool
+	lookupLeaves bool
+)
+
+// genLookupCSV writes the code and the full name of every node, or of leaves only with -lookup-leaves, in the
+// order of the trees. Names are joined like the full_name column, short names with -lookup-short. Rows are
+// streamed without a header.
+func genLookupCSV(trees []*Area) error {
+	name := nodeName
+	if lookupShort {
+		name = func(a *Area) string { return a.ShortName }
+	}
+	return writeFileAtomic(lookupCSV, func(w io.Writer) error {
+		cw := csv.NewWriter(w)
+		var walk func(path []*Area) error
+		walk = func(path []*Area) error {
+			area := path[len(path)-1]
+			if !lookupLeaves || len(area.SubAreas) == 0 {
+				if err := cw.Write([]string{area.Code, fullName(path, name)}); err != nil {
+					return err
+				}
+			}
+			for _, sub := range area.SubAreas {
+				if err := walk(append(path, sub)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		for _, p := range trees {
+			if err := walk([]*Area{p}); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}, nil)
+}
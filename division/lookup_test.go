@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestLookupCSV(t *testing.T) {
+	usePaths(t, "./testdata/mini")
+	name := filepath.Join(t.TempDir(), "lookup.csv")
+	for _, c := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-full-name-sep", "/"}, "110000,北京市\n110100,北京市/市辖区\n110101,北京市/市辖区/东城区\n" +
+			"110101001000,北京市/市辖区/东城区/东华门街道办事处\n110101002000,北京市/市辖区/东城区/景山街道办事处\n" +
+			"130000,河北省\n130100,河北省/石家庄市\n130102,河北省/石家庄市/长安区\n" +
+			"130102001000,河北省/石家庄市/长安区/建北街道办事处\n"},
+		{[]string{"-lookup-short", "-lookup-leaves", "-full-name-skip-placeholders"},
+			"110101001000,北京东城东华门\n110101002000,北京东城景山\n130102001000,河北石家庄长安建北\n"},
+	} {
+		var stderr bytes.Buffer
+		if code := run(append([]string{"-lookup-csv", name}, c.args...), &stderr); code != exitOK {
+			t.Fatal("exit code:", code, stderr.String())
+		}
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != c.want {
+			t.Error(c.args, string(data))
+		}
+	}
+}
@@ -0,0 +1,497 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// migration modes
+const (
+	migrateAuto        = "auto"
+	migrateIncremental = "incremental"
+	migrateFull        = "full"
+)
+
+// migration builds sql statements transforming a table of one version of the trees into another. The table is
+// simulated by sim, whose keys are reassigned after each structural change, and statements are derived from keys
+// before and after the change.
+//
+// Keys are shifted through negative values, -(key + delta) and then negated back, so no statement ever writes
+// a key held by another row, even when lft and rgt are unique and constraints are checked row by row.
+type migration struct {
+	buf     bytes.Buffer
+	sim     []*Area
+	parents map[*Area]*Area
+	removed map[*Area]bool // nodes of sim not in the new version
+	rows    int            // rows written by the statements so far
+	limit   int            // rows to give up at, 0 for no limit
+}
+
+// errTooExtensive stops an incremental migration which writes more rows than rewriting all keys
+var errTooExtensive = fmt.Errorf("incremental migration too extensive")
+
+// copyTrees copies the nodes of trees, without keys
+func copyTrees(trees []*Area) []*Area {
+	copies := make([]*Area, len(trees))
+	for i, a := range trees {
+		copies[i] = &Area{Code: a.Code, Name: nodeName(a), ParentCode: a.ParentCode, SubAreas: copyTrees(a.SubAreas)}
+	}
+	return copies
+}
+
+// matchNodes maps nodes of the new trees to nodes of the old ones by code as diffTrees does, nodes of a repeated
+// code are matched in preorder
+func matchNodes(older, newer []*Area) map[*Area]*Area {
+	oldIndex, _ := indexNodes(older)
+	_, newOrder := indexNodes(newer)
+	seen := make(map[string]int)
+	match := make(map[*Area]*Area)
+	for _, n := range newOrder {
+		i := seen[n.area.Code]
+		seen[n.area.Code]++
+		if i < len(oldIndex[n.area.Code]) {
+			match[n.area] = oldIndex[n.area.Code][i].area
+		}
+	}
+	return match
+}
+
+// nodeKeys are lft, rgt and depth of every node by pointer
+type nodeKeys map[*Area][3]int32
+
+func keysOf(trees []*Area) nodeKeys {
+	keys := make(nodeKeys)
+	var walk func(areas []*Area, depth int32)
+	walk = func(areas []*Area, depth int32) {
+		for _, a := range areas {
+			keys[a] = [3]int32{a.Left, a.Right, depth}
+			walk(a.SubAreas, depth+1)
+		}
+	}
+	walk(trees, 1)
+	return keys
+}
+
+func (m *migration) emit(format string, args ...interface{}) {
+	fmt.Fprintf(&m.buf, format+"\n", args...)
+}
+
+// write counts rows written by a statement
+func (m *migration) write(rows int) error {
+	m.rows += rows
+	if m.limit > 0 && m.rows > m.limit {
+		return errTooExtensive
+	}
+	return nil
+}
+
+// restructure applies a structural change to sim, and shifts keys of the rows which exist before and after it
+func (m *migration) restructure(change func()) error {
+	before := keysOf(m.sim)
+	change()
+	assignKeys(m.sim)
+	after := keysOf(m.sim)
+	for i, col := range []string{"lft", "rgt"} {
+		err := m.shift(col, i, before, after)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shift moves values of a key column to their new places in runs of the same delta
+func (m *migration) shift(col string, i int, before, after nodeKeys) error {
+	type move struct{ from, to int32 }
+	var moves []move
+	for a, b := range before {
+		if k, ok := after[a]; ok {
+			moves = append(moves, move{b[i], k[i]})
+		}
+	}
+	sort.Slice(moves, func(i, j int) bool { return moves[i].from < moves[j].from })
+
+	shifted := 0
+	for start := 0; start < len(moves); {
+		delta := moves[start].to - moves[start].from
+		end := start + 1
+		for end < len(moves) && moves[end].to-moves[end].from == delta {
+			end++
+		}
+		if delta != 0 {
+			m.emit("UPDATE %s SET %s = -(%s %s) WHERE %s BETWEEN %d AND %d;", tblName, col, col, signed(delta),
+				col, moves[start].from, moves[end-1].from)
+			shifted += end - start
+		}
+		start = end
+	}
+	if shifted == 0 {
+		return nil
+	}
+	m.emit("UPDATE %s SET %s = -%s WHERE %s < 0;", tblName, col, col, col)
+	return m.write(2 * shifted)
+}
+
+// signed formats a delta to add, e.g. "+ 2" or "- 2"
+func signed(delta int32) string {
+	if delta < 0 {
+		return "- " + itoa(-delta)
+	}
+	return "+ " + itoa(delta)
+}
+
+// siblings returns the children of parent, or the roots for nil
+func (m *migration) siblings(parent *Area) *[]*Area {
+	if parent == nil {
+		return &m.sim
+	}
+	return &parent.SubAreas
+}
+
+// inPlace tells whether a is a child of parent following prev, or first for nil prev, regardless of removed
+// siblings which go away at last
+func (m *migration) inPlace(a, parent, prev *Area) bool {
+	if m.parents[a] != parent {
+		return false
+	}
+	var last *Area
+	for _, s := range *m.siblings(parent) {
+		if s == a {
+			return last == prev
+		}
+		if !m.removed[s] {
+			last = s
+		}
+	}
+	return false
+}
+
+// place puts the subtree of a as a child of parent following prev, or first for nil prev
+func (m *migration) place(a, parent, prev *Area) {
+	list := m.siblings(parent)
+	i := 0
+	for j, s := range *list {
+		if s == prev {
+			i = j + 1
+		}
+	}
+	*list = append(*list, nil)
+	copy((*list)[i+1:], (*list)[i:])
+	(*list)[i] = a
+	m.parents[a] = parent
+}
+
+// detach takes the subtree of a out of its parent
+func (m *migration) detach(a *Area) {
+	list := m.siblings(m.parents[a])
+	for i, s := range *list {
+		if s == a {
+			*list = append((*list)[:i], (*list)[i+1:]...)
+			return
+		}
+	}
+}
+
+func (m *migration) setParents(parent *Area, areas []*Area) {
+	for _, a := range areas {
+		m.parents[a] = parent
+		m.setParents(a, a.SubAreas)
+	}
+}
+
+func quote(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// insertRows inserts the subtree of a with its keys
+func (m *migration) insertRows(a *Area, depth int32) error {
+	m.emit("INSERT INTO %s(id, node, pid, depth, lft, rgt) VALUES(%s, %s, %s, %d, %d, %d);", tblName,
+		a.Code, quote(a.Name), a.ParentCode, depth, a.Left, a.Right)
+	if err := m.write(1); err != nil {
+		return err
+	}
+	for _, sub := range a.SubAreas {
+		if err := m.insertRows(sub, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addedBlock copies an added node with its added descendants, other descendants are moved in later
+func addedBlock(a *Area, match map[*Area]*Area, sims map[*Area]*Area) *Area {
+	block := &Area{Code: a.Code, Name: nodeName(a), ParentCode: a.ParentCode}
+	sims[a] = block
+	for _, sub := range a.SubAreas {
+		if match[sub] == nil {
+			block.SubAreas = append(block.SubAreas, addedBlock(sub, match, sims))
+		}
+	}
+	return block
+}
+
+// deleteRemoved deletes removed subtrees of sim, all of them or only those without surviving descendants
+func (m *migration) deleteRemoved(all bool) error {
+	var subtrees []*Area
+	var walk func(areas []*Area) bool
+	walk = func(areas []*Area) bool {
+		whole := true
+		for _, a := range areas {
+			subWhole := walk(a.SubAreas)
+			if m.removed[a] && !m.removed[m.parents[a]] && (subWhole || all) {
+				subtrees = append(subtrees, a)
+			}
+			whole = whole && subWhole && m.removed[a]
+		}
+		return whole
+	}
+	walk(m.sim)
+
+	for _, a := range subtrees {
+		keys := keysOf(m.sim)
+		m.emit("DELETE FROM %s WHERE lft BETWEEN %d AND %d;", tblName, keys[a][0], keys[a][1])
+		if err := m.write(len(keysOf([]*Area{a}))); err != nil {
+			return err
+		}
+		err := m.restructure(func() { m.detach(a) })
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// incrementalMigration changes only rows which differ: renames, deletes of removed subtrees, inserts of added ones
+// and moves of subtrees into the places of the new version, shifting keys in between
+func incrementalMigration(older, newer []*Area, limit int) (*migration, error) {
+	m := &migration{sim: copyTrees(older), parents: make(map[*Area]*Area), removed: make(map[*Area]bool), limit: limit}
+	assignKeys(m.sim)
+	m.setParents(nil, m.sim)
+	match := matchNodes(m.sim, newer)
+	kept := make(map[*Area]bool, len(match))
+	for _, s := range match {
+		kept[s] = true
+	}
+	for s := range m.parents {
+		if !kept[s] {
+			m.removed[s] = true
+		}
+	}
+
+	keys := keysOf(m.sim)
+	_, newOrder := indexNodes(newer)
+	for _, n := range newOrder {
+		if s := match[n.area]; s != nil && s.Name != nodeName(n.area) {
+			m.emit("UPDATE %s SET node = %s WHERE id = %s AND lft = %d;", tblName, quote(nodeName(n.area)), s.Code, keys[s][0])
+			if err := m.write(1); err != nil {
+				return nil, err
+			}
+			s.Name = nodeName(n.area)
+		}
+	}
+
+	err := m.deleteRemoved(false)
+	if err != nil {
+		return nil, err
+	}
+
+	// place every node of the new version in preorder, so its parent and preceding sibling are in place already
+	sims := make(map[*Area]*Area, len(newOrder))
+	var placeAll func(parent *Area, areas []*Area) error
+	placeAll = func(parent *Area, areas []*Area) error {
+		var prev *Area
+		for _, a := range areas {
+			s, simParent := match[a], sims[parent]
+			switch {
+			case sims[a] != nil:
+				// inserted with an added ancestor
+			case s == nil:
+				block := addedBlock(a, match, sims)
+				err := m.restructure(func() { m.place(block, simParent, sims[prev]) })
+				if err != nil {
+					return err
+				}
+				m.setParents(simParent, []*Area{block})
+				err = m.insertRows(block, keysOf(m.sim)[simParent][2]+1)
+				if err != nil {
+					return err
+				}
+			default:
+				sims[a] = s
+				if m.inPlace(s, simParent, sims[prev]) {
+					break
+				}
+				oldDepth := keysOf(m.sim)[s][2]
+				err := m.restructure(func() {
+					m.detach(s)
+					m.place(s, simParent, sims[prev])
+				})
+				if err != nil {
+					return err
+				}
+				k := keysOf(m.sim)[s]
+				if k[2] != oldDepth {
+					m.emit("UPDATE %s SET depth = depth %s WHERE lft BETWEEN %d AND %d;", tblName, signed(k[2]-oldDepth), k[0], k[1])
+					if err = m.write(int(k[1]-k[0]+1) / 2); err != nil {
+						return err
+					}
+				}
+				if s.ParentCode != a.ParentCode {
+					m.emit("UPDATE %s SET pid = %s WHERE id = %s AND lft = %d;", tblName, a.ParentCode, s.Code, k[0])
+					if err = m.write(1); err != nil {
+						return err
+					}
+					s.ParentCode = a.ParentCode
+				}
+			}
+			err := placeAll(a, a.SubAreas)
+			if err != nil {
+				return err
+			}
+			prev = a
+		}
+		return nil
+	}
+	err = placeAll(nil, newer)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.deleteRemoved(true)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// fullMigration rewrites keys of all rows: removed rows are deleted, keys of the rest moved to negative values
+// and set one row after another, and added rows inserted
+func fullMigration(older, newer []*Area) *migration {
+	m := &migration{}
+	oldKeys := keysOf(older)
+	match := matchNodes(older, newer)
+	kept := make(map[*Area]bool, len(match))
+	for _, s := range match {
+		kept[s] = true
+	}
+	_, oldOrder := indexNodes(older)
+	for _, n := range oldOrder {
+		if !kept[n.area] {
+			m.emit("DELETE FROM %s WHERE id = %s AND lft = %d;", tblName, n.area.Code, oldKeys[n.area][0])
+			m.rows++
+		}
+	}
+	m.emit("UPDATE %s SET lft = -lft, rgt = -rgt;", tblName)
+	m.rows += len(match)
+
+	newKeys := keysOf(newer)
+	_, newOrder := indexNodes(newer)
+	for _, n := range newOrder {
+		a, k := n.area, newKeys[n.area]
+		s := match[a]
+		if s == nil {
+			m.emit("INSERT INTO %s(id, node, pid, depth, lft, rgt) VALUES(%s, %s, %s, %d, %d, %d);", tblName,
+				a.Code, quote(nodeName(a)), a.ParentCode, k[2], k[0], k[1])
+			m.rows++
+			continue
+		}
+		set := fmt.Sprintf("lft = %d, rgt = %d", k[0], k[1])
+		if nodeName(s) != nodeName(a) {
+			set = "node = " + quote(nodeName(a)) + ", " + set
+		}
+		if s.ParentCode != a.ParentCode || oldKeys[s][2] != k[2] {
+			set = fmt.Sprintf("pid = %s, depth = %d, %s", a.ParentCode, k[2], set)
+		}
+		m.emit("UPDATE %s SET %s WHERE id = %s AND lft = %d;", tblName, set, a.Code, -oldKeys[s][0])
+		m.rows++
+	}
+	return m
+}
+
+// fullRows is the number of rows written by rewriting all keys
+func fullRows(older, newer []*Area) int {
+	_, oldOrder := indexNodes(older)
+	_, newOrder := indexNodes(newer)
+	return len(oldOrder) + len(newOrder)
+}
+
+// runMigrate writes sql which migrates a table loaded with one version of the division data into another
+func runMigrate(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("division migrate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	defer func(table string) { tblName = table }(tblName)
+	fs.Var(tableFlag{}, "table", "`name` of the nested sets table")
+	mode := fs.String("mode", migrateAuto, "incremental, full rewrite of all keys, or auto to rewrite when incremental writes more rows")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: division migrate [-table name] [-mode auto|incremental|full] old new")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 2 || (*mode != migrateAuto && *mode != migrateIncremental && *mode != migrateFull) {
+		fs.Usage()
+		return exitUsage
+	}
+
+	older, err := loadTrees(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(stderr, "division migrate:", err)
+		return exitCode(err)
+	}
+	newer, err := loadTrees(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintln(stderr, "division migrate:", err)
+		return exitCode(err)
+	}
+	assignKeys(older)
+	assignKeys(newer)
+
+	var m *migration
+	full := fullRows(older, newer)
+	if *mode != migrateFull {
+		limit := full
+		if *mode == migrateIncremental {
+			limit = 0
+		}
+		m, err = incrementalMigration(older, newer, limit)
+		switch {
+		case err == errTooExtensive:
+			fmt.Fprintf(stderr, "division migrate: incremental migration writes more than the %d rows of rewriting all keys, rewriting them instead\n", full)
+			*mode = migrateFull
+		case err != nil:
+			fmt.Fprintln(stderr, "division migrate:", err)
+			return exitCode(err)
+		default:
+			if err = compareTrees(newer, m.sim); err != nil {
+				fmt.Fprintln(stderr, "division migrate: internal error:", err)
+				return exitInternal
+			}
+			*mode = migrateIncremental
+		}
+	}
+	if *mode == migrateFull {
+		m = fullMigration(older, newer)
+	}
+
+	changes := diffTrees(older, newer)
+	summary := make(map[string]int)
+	for _, c := range changes {
+		summary[c.Kind]++
+	}
+	fmt.Fprintf(stdout, "-- %s migration of %s from %s to %s\n-- changes: %s\n-- rows written: %d\nBEGIN;\n",
+		*mode, tblName, fs.Arg(0), fs.Arg(1), counts(summary, true), m.rows)
+	_, err = m.buf.WriteTo(stdout)
+	if err == nil {
+		_, err = io.WriteString(stdout, "COMMIT;\n")
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "division migrate:", err)
+		return exitIO
+	}
+	return exitOK
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTreesSQL writes inserts of trees into a temp sql file
+func writeTreesSQL(t *testing.T, trees []*Area) string {
+	assignKeys(trees)
+	var buf bytes.Buffer
+	for _, p := range trees {
+		if err := genSQL(&buf, []*Area{p}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	name := filepath.Join(t.TempDir(), "trees.sql")
+	if err := os.WriteFile(name, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+// sqlite runs a script in a new database and returns its output, tests are skipped without the sqlite3 shell
+func sqlite(t *testing.T, script string) string {
+	sqlite, err := exec.LookPath("sqlite3")
+	if err != nil {
+		t.Skip("sqlite3 not found")
+	}
+	cmd := exec.Command(sqlite, "-bail", filepath.Join(t.TempDir(), "nested.db"))
+	cmd.Stdin = strings.NewReader(script)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	return string(out)
+}
+
+// sqliteRows loads sql files into a table with unique keys and lists its rows in the order of lft
+func sqliteRows(t *testing.T, files ...string) string {
+	script := `CREATE TABLE nested(id BIGINT NOT NULL PRIMARY KEY, node VARCHAR(64) NOT NULL, pid BIGINT NOT NULL,
+depth INT NOT NULL, lft INT NOT NULL, rgt INT NOT NULL);
+CREATE UNIQUE INDEX lft_index ON nested(lft);
+CREATE UNIQUE INDEX rgt_index ON nested(rgt);
+`
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		script += string(b)
+	}
+	return sqlite(t, script+"SELECT id, node, pid, depth, lft, rgt FROM nested ORDER BY lft;\n")
+}
+
+// checkMigration applies the migration from older to newer and compares the table with one loaded from newer
+func checkMigration(t *testing.T, older, newer string, args ...string) string {
+	out := useStdout(t)
+	var stderr bytes.Buffer
+	if code := run(append(append([]string{"migrate"}, args...), older, newer), &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	migration := filepath.Join(t.TempDir(), "migration.sql")
+	if err := os.WriteFile(migration, out.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(out.String(), "COMMIT;\n") {
+		t.Error(out.String())
+	}
+	if got, want := sqliteRows(t, older, migration), sqliteRows(t, newer); got != want {
+		t.Errorf("migrated:\n%s\nwant:\n%s\nmigration:\n%s", got, want, out.String())
+	}
+	return out.String() + stderr.String()
+}
+
+func TestMigrateVersions(t *testing.T) {
+	older := usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run(nil, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	newer := usePaths(t, "./testdata/mini2")
+	if code := run(nil, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+
+	out := checkMigration(t, older, newer)
+	for _, s := range []string{"-- incremental migration", "-- changes: added 1, removed 1, renamed 1, moved 0",
+		"UPDATE nested SET node = '石家庄' WHERE id = 130100", "DELETE FROM nested WHERE lft BETWEEN"} {
+		if !strings.Contains(out, s) {
+			t.Error(s, "missing in", out)
+		}
+	}
+	checkMigration(t, newer, older)
+	if out = checkMigration(t, older, newer, "-mode", "full"); !strings.Contains(out, "-- full migration") {
+		t.Error(out)
+	}
+}
+
+func TestMigrateMoves(t *testing.T) {
+	older := writeTreesSQL(t, []*Area{
+		{Code: "1", Name: "a", ParentCode: "0", SubAreas: []*Area{
+			{Code: "11", Name: "b", ParentCode: "1", SubAreas: []*Area{{Code: "111", Name: "b1", ParentCode: "11"}}},
+			{Code: "12", Name: "c", ParentCode: "1", SubAreas: []*Area{
+				{Code: "121", Name: "c1", ParentCode: "12"},
+				{Code: "122", Name: "c2", ParentCode: "12"},
+			}},
+		}},
+		{Code: "2", Name: "d", ParentCode: "0", SubAreas: []*Area{
+			{Code: "21", Name: "e", ParentCode: "2"},
+			{Code: "22", Name: "f", ParentCode: "2"},
+		}},
+	})
+	newer := writeTreesSQL(t, []*Area{
+		{Code: "1", Name: "a", ParentCode: "0", SubAreas: []*Area{
+			{Code: "12", Name: "C", ParentCode: "1", SubAreas: []*Area{
+				{Code: "122", Name: "c2", ParentCode: "12"},
+				{Code: "121", Name: "c1", ParentCode: "12"},
+			}},
+			{Code: "11", Name: "b", ParentCode: "1", SubAreas: []*Area{{Code: "21", Name: "e", ParentCode: "11"}}},
+			{Code: "13", Name: "g", ParentCode: "1", SubAreas: []*Area{
+				{Code: "111", Name: "b1", ParentCode: "13"},
+				{Code: "131", Name: "h", ParentCode: "13"},
+			}},
+		}},
+	})
+	out := checkMigration(t, older, newer, "-mode", "incremental")
+	for _, s := range []string{"-- changes: added 2, removed 2, renamed 1, moved 2", "UPDATE nested SET pid = 13 WHERE id = 111",
+		"UPDATE nested SET depth = depth + 1 WHERE", "INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(131, 'h', 13, 3,"} {
+		if !strings.Contains(out, s) {
+			t.Error(s, "missing in", out)
+		}
+	}
+	checkMigration(t, newer, older, "-mode", "incremental")
+	checkMigration(t, older, newer, "-mode", "full")
+	checkMigration(t, newer, older, "-mode", "full")
+}
+
+func TestMigrateTooExtensive(t *testing.T) {
+	root := func(codes ...string) []*Area {
+		r := &Area{Code: "1", Name: "r", ParentCode: "0"}
+		for _, c := range codes {
+			r.SubAreas = append(r.SubAreas, &Area{Code: c, Name: c, ParentCode: "1"})
+		}
+		return []*Area{r}
+	}
+	older := writeTreesSQL(t, root("11", "12", "13", "14", "15", "16"))
+	newer := writeTreesSQL(t, root("16", "15", "14", "13", "12", "11"))
+	out := checkMigration(t, older, newer)
+	if !strings.Contains(out, "-- full migration") || !strings.Contains(out, "rewriting them instead") {
+		t.Error(out)
+	}
+	out = checkMigration(t, older, newer, "-mode", "incremental")
+	if !strings.Contains(out, "-- incremental migration") {
+		t.Error(out)
+	}
+}
+
+func TestMigrateUsage(t *testing.T) {
+	var stderr bytes.Buffer
+	for _, args := range [][]string{{"migrate", "./testdata/mini"}, {"migrate", "-mode", "fast", "./testdata/mini", "./testdata/mini2"}} {
+		if code := run(args, &stderr); code != exitUsage {
+			t.Error(args, "exit code:", code)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"io"
+	"path/filepath"
+	"regexp"
+)
+
+// migrationFormats are the values of -migration-format: up and down files of golang-migrate, or the annotated
+// single file of goose
+var migrationFormats = []string{"migrate", "goose"}
+
+var (
+	migrationsDir    string
+	migrationFormat  = "migrate"
+	migrationVersion = "0001"
+)
+
+var migrationVersionPattern = regexp.MustCompile(`^[0-9]+$`)
+
+// migrationName is the name of the migration, the version and the table seeded
+func migrationName() string {
+	return migrationVersion + "_" + tblName
+}
+
+// downStmt reverts the up migration, dropping the table it created with -with-schema, or else deleting the
+// rows it inserted
+func downStmt() string {
+	if withSchema {
+		if dialect == "oracle" {
+			return "DROP TABLE " + tableRef() + ";\n"
+		}
+		return "DROP TABLE IF EXISTS " + tableRef() + ";\n"
+	}
+	return "DELETE FROM " + tableRef() + ";\n"
+}
+
+// genMigrations writes the sql into -migrations, as 0001_nested.up.sql and 0001_nested.down.sql for
+// golang-migrate, or as 0001_nested.sql with its up and down sections for goose. Up migrations are checked
+// like the sql file.
+func genMigrations(trees []*Area) error {
+	var check func(string) error
+	if selfCheck {
+		check = func(tmp string) error {
+			return checkSQLFile(tmp, trees)
+		}
+	}
+	if migrationFormat == "goose" {
+		return writeFileAtomic(filepath.Join(migrationsDir, migrationName()+".sql"), func(w io.Writer) error {
+			if _, err := io.WriteString(w, "-- +goose Up\n"); err != nil {
+				return err
+			}
+			if err := writeSQL(w, trees); err != nil {
+				return err
+			}
+			_, err := io.WriteString(w, "\n-- +goose Down\n"+downStmt())
+			return err
+		}, check)
+	}
+
+	// both files or none
+	var files atomicFiles
+	defer files.abort()
+	up, err := files.create(filepath.Join(migrationsDir, migrationName()+".up.sql"))
+	if err != nil {
+		return err
+	}
+	down, err := files.create(filepath.Join(migrationsDir, migrationName()+".down.sql"))
+	if err != nil {
+		return err
+	}
+	if err = writeSQL(up, trees); err != nil {
+		return err
+	}
+	if _, err = io.WriteString(down, downStmt()); err != nil {
+		return err
+	}
+	if err = files.sync(); err != nil {
+		return err
+	}
+	if check != nil {
+		if err = check(up.Name()); err != nil {
+			return err
+		}
+	}
+	return files.commit()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrations(t *testing.T) {
+	out := usePaths(t, "./testdata/mini")
+	dir := t.TempDir()
+	var stderr bytes.Buffer
+	if code := run([]string{"-migrations", dir, "-migration-version", "0003"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	sql, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	up, err := ioutil.ReadFile(filepath.Join(dir, "0003_nested.up.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(up, sql) {
+		t.Error("up migration differs from the sql file:", string(up))
+	}
+	down, err := ioutil.ReadFile(filepath.Join(dir, "0003_nested.down.sql"))
+	if err != nil || string(down) != "DELETE FROM nested;\n" {
+		t.Error(string(down), err)
+	}
+}
+
+func TestGooseMigration(t *testing.T) {
+	usePaths(t, "./testdata/mini")
+	dir := t.TempDir()
+	var stderr bytes.Buffer
+	args := []string{"-migrations", dir, "-migration-format", "goose", "-dialect", "postgres", "-with-schema"}
+	if code := run(args, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "0001_nested.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(data)
+	if !strings.HasPrefix(s, "-- +goose Up\nCREATE TABLE IF NOT EXISTS \"nested\"(") ||
+		!strings.HasSuffix(s, ");\n\n-- +goose Down\nDROP TABLE IF EXISTS \"nested\";\n") {
+		t.Error(s)
+	}
+}
+
+func TestMigrationFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{"-migrations", "x", "-migration-format", "flyway"},
+		{"-migrations", "x", "-migration-version", "v1"},
+	} {
+		usePaths(t, "./testdata/mini")
+		var stderr bytes.Buffer
+		if code := run(args, &stderr); code != exitUsage {
+			t.Error(args, "exit code:", code)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+)
+
+// genMsgpackFile writes the nodes of the trees into the -out file as MessagePack, for clients short of
+// bandwidth: an array of a row of each node in preorder, itself an array of code, name, pid, depth, lft and rgt,
+// codes as strings. Rows are arrays rather than maps to leave the field names out of every node.
+func genMsgpackFile(trees []*Area) error {
+	return writeFileAtomic(sqlFile, func(w io.Writer) error {
+		return writeMsgpack(w, trees)
+	}, nil)
+}
+
+func writeMsgpack(w io.Writer, trees []*Area) error {
+	count := 0
+	for _, p := range trees {
+		walkSubtree([]*Area{p}, func([]*Area) error {
+			count++
+			return nil
+		})
+	}
+	bw := bufio.NewWriterSize(w, sqlBufferSize)
+	b := appendMsgpackArray(nil, count)
+	for _, p := range trees {
+		err := walkSubtree([]*Area{p}, func(path []*Area) error {
+			area := path[len(path)-1]
+			b = appendMsgpackArray(b, 6)
+			b = appendMsgpackString(b, area.Code)
+			b = appendMsgpackString(b, nodeName(area))
+			b = appendMsgpackString(b, area.ParentCode)
+			b = appendMsgpackUint(b, uint32(len(path)))
+			b = appendMsgpackUint(b, uint32(area.Left))
+			b = appendMsgpackUint(b, uint32(area.Right))
+			_, err := bw.Write(b)
+			b = b[:0]
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// appendMsgpackArray appends the header of an array of n items, in the shortest of the array formats
+func appendMsgpackArray(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x90|byte(n))
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16(append(b, 0xdc), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(b, 0xdd), uint32(n))
+}
+
+func appendMsgpackString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= 0xff:
+		b = append(b, 0xd9, byte(n))
+	case n <= 0xffff:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+func appendMsgpackUint(b []byte, v uint32) []byte {
+	switch {
+	case v < 0x80:
+		return append(b, byte(v))
+	case v <= 0xff:
+		return append(b, 0xcc, byte(v))
+	case v <= 0xffff:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(v))
+	}
+	return binary.BigEndian.AppendUint32(append(b, 0xce), v)
+}
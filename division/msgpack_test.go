@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+// decodeMsgpack reads the arrays, strings and unsigned integers written by writeMsgpack
+func decodeMsgpack(t *testing.T, b []byte) (interface{}, []byte) {
+	t.Helper()
+	array := func(n int, b []byte) (interface{}, []byte) {
+		items := make([]interface{}, n)
+		for i := range items {
+			items[i], b = decodeMsgpack(t, b)
+		}
+		return items, b
+	}
+	switch c := b[0]; {
+	case c < 0x80:
+		return uint32(c), b[1:]
+	case c&0xf0 == 0x90:
+		return array(int(c&0x0f), b[1:])
+	case c&0xe0 == 0xa0:
+		n := int(c & 0x1f)
+		return string(b[1 : 1+n]), b[1+n:]
+	case c == 0xcc:
+		return uint32(b[1]), b[2:]
+	case c == 0xcd:
+		return uint32(binary.BigEndian.Uint16(b[1:])), b[3:]
+	case c == 0xce:
+		return binary.BigEndian.Uint32(b[1:]), b[5:]
+	case c == 0xd9:
+		n := int(b[1])
+		return string(b[2 : 2+n]), b[2+n:]
+	case c == 0xdc:
+		return array(int(binary.BigEndian.Uint16(b[1:])), b[3:])
+	}
+	t.Fatalf("unexpected format % x", b[0])
+	return nil, nil
+}
+
+func TestFormatMsgpack(t *testing.T) {
+	out := usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run([]string{"-format", "msgpack"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, rest := decodeMsgpack(t, data)
+	rows, ok := v.([]interface{})
+	if !ok || len(rows) != 9 || len(rest) != 0 {
+		t.Fatalf("%v % x", v, rest)
+	}
+	for i, want := range map[int][]interface{}{
+		0: {"110000", "北京市", "0", uint32(1), uint32(1), uint32(10)},
+		4: {"110101002000", "景山街道办事处", "110101", uint32(4), uint32(6), uint32(7)},
+		8: {"130102001000", "建北街道办事处", "130102", uint32(4), uint32(14), uint32(15)},
+	} {
+		if !reflect.DeepEqual(rows[i], want) {
+			t.Errorf("row %d: %v, want %v", i, rows[i], want)
+		}
+	}
+
+	// the headers of larger values
+	for _, n := range []uint32{0x7f, 0x80, 0xff, 0x100, 0xffff, 0x10000} {
+		if v, _ := decodeMsgpack(t, appendMsgpackUint(nil, n)); v != n {
+			t.Errorf("%d decoded as %v", n, v)
+		}
+	}
+	long := string(bytes.Repeat([]byte("街"), 20))
+	if v, _ := decodeMsgpack(t, appendMsgpackString(nil, long)); v != long {
+		t.Errorf("%q decoded as %v", long, v)
+	}
+	if v, _ := decodeMsgpack(t, append(appendMsgpackArray(nil, 16), bytes.Repeat([]byte{1}, 16)...)); len(v.([]interface{})) != 16 {
+		t.Error(v)
+	}
+}
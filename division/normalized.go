@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+)
+
+// normalized output options, the tables are written with -normalized
+var (
+	normalizedFile string
+	normalizedKeys bool
+)
+
+// levelTable is the table of the nodes at depth, named like the input file of the level, e.g. cities
+func levelTable(depth int) string {
+	return levelName(inputLevels()[depth-1])
+}
+
+// levelSchema creates the table of the nodes at depth, with a foreign key to the table above for all but the top
+func levelSchema(depth int) string {
+	var ddl strings.Builder
+	ddl.WriteString("CREATE TABLE IF NOT EXISTS `" + levelTable(depth) + "`(\n" +
+		"`id` BIGINT NOT NULL COMMENT 'node ID',\n" +
+		"`node` VARCHAR(64) CHARACTER SET 'utf8' NOT NULL COMMENT 'node name',\n")
+	if depth > 1 {
+		ddl.WriteString("`pid` BIGINT NOT NULL COMMENT 'parent ID',\n")
+	}
+	if normalizedKeys {
+		ddl.WriteString("`lft` INT NOT NULL COMMENT 'left index',\n" +
+			"`rgt` INT NOT NULL COMMENT 'right index',\n")
+	}
+	for _, c := range columns {
+		ddl.WriteString("`" + c.name + "` " + c.ddl + ",\n")
+	}
+	ddl.WriteString("  PRIMARY KEY (`id`)")
+	if depth > 1 {
+		ddl.WriteString(",\n  FOREIGN KEY (`pid`) REFERENCES `" + levelTable(depth-1) + "` (`id`)")
+	}
+	if normalizedKeys {
+		ddl.WriteString(",\n  INDEX `lft_index` (`lft` ASC)")
+	}
+	ddl.WriteString(")\nENGINE = InnoDB DEFAULT CHARACTER SET = utf8 COMMENT = '" + levelTable(depth) + " of the divisions';\n")
+	return ddl.String()
+}
+
+// genNormalized writes a table of each level into -normalized, the schemas and then the inserts of each level
+// from the top, so that parents exist before the foreign keys of their children refer to them. Tables follow
+// the depth of the nodes, which is one level higher below dropped placeholders.
+func genNormalized(w io.Writer, trees []*Area) error {
+	depth := treeDepth(trees)
+	for d := 1; d <= depth; d++ {
+		if _, err := io.WriteString(w, levelSchema(d)+"\n"); err != nil {
+			return err
+		}
+	}
+	for d := 1; d <= depth; d++ {
+		prefix := "INSERT INTO `" + levelTable(d) + "`(id, node"
+		if d > 1 {
+			prefix += ", pid"
+		}
+		if normalizedKeys {
+			prefix += ", lft, rgt"
+		}
+		for _, c := range columns {
+			prefix += ", " + c.name
+		}
+		prefix += ") VALUES("
+
+		for _, p := range trees {
+			err := walkSubtree([]*Area{p}, func(path []*Area) error {
+				if len(path) != d {
+					return nil
+				}
+				area := path[len(path)-1]
+				sql := bytes.NewBufferString(prefix)
+				sql.WriteString(area.Code)
+				sql.WriteString(", '")
+				sql.WriteString(nodeName(area))
+				sql.WriteString("'")
+				if d > 1 {
+					sql.WriteString(", ")
+					sql.WriteString(area.ParentCode)
+				}
+				if normalizedKeys {
+					sql.WriteString(", ")
+					sql.WriteString(itoa(area.Left))
+					sql.WriteString(", ")
+					sql.WriteString(itoa(area.Right))
+				}
+				if err := writeColumns(sql, path); err != nil {
+					return err
+				}
+				sql.WriteString(");\n")
+				_, err := w.Write(sql.Bytes())
+				return err
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// genNormalizedFile writes the normalized tables into -normalized through a temp file
+func genNormalizedFile(trees []*Area) error {
+	return writeFileAtomic(normalizedFile, func(w io.Writer) error {
+		return genNormalized(w, trees)
+	}, nil)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormalized(t *testing.T) {
+	usePaths(t, "./testdata/mini")
+	name := filepath.Join(t.TempDir(), "normalized.sql")
+	var stderr bytes.Buffer
+	if code := run([]string{"-normalized", name, "-columns", "is_leaf"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sql := string(data)
+	for _, want := range []string{
+		"CREATE TABLE IF NOT EXISTS `provinces`(\n`id` BIGINT NOT NULL COMMENT 'node ID',\n" +
+			"`node` VARCHAR(64) CHARACTER SET 'utf8' NOT NULL COMMENT 'node name',\n`is_leaf` ",
+		"`pid` BIGINT NOT NULL COMMENT 'parent ID',\n`is_leaf` ",
+		"  PRIMARY KEY (`id`),\n  FOREIGN KEY (`pid`) REFERENCES `areas` (`id`))\n" +
+			"ENGINE = InnoDB DEFAULT CHARACTER SET = utf8 COMMENT = 'streets of the divisions';\n",
+		"INSERT INTO `provinces`(id, node, is_leaf) VALUES(110000, '北京市', 0);\n" +
+			"INSERT INTO `provinces`(id, node, is_leaf) VALUES(130000, '河北省', 0);\n" +
+			"INSERT INTO `cities`(id, node, pid, is_leaf) VALUES(110100, '市辖区', 110000, 0);\n",
+		"INSERT INTO `streets`(id, node, pid, is_leaf) VALUES(130102001000, '建北街道办事处', 130102, 1);\n",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Error(want, "missing in", sql)
+		}
+	}
+	if strings.Contains(sql, "lft") {
+		t.Error("keys without -normalized-keys:", sql)
+	}
+
+	// every row comes after the row of its parent in the table above
+	tables := []string{"provinces", "cities", "areas", "streets"}
+	inserted := make(map[string]bool)
+	for _, line := range strings.Split(sql, "\n") {
+		if !strings.HasPrefix(line, "INSERT INTO `") {
+			continue
+		}
+		table := line[len("INSERT INTO `"):strings.Index(line, "`(")]
+		values := strings.Split(line[strings.Index(line, "VALUES(")+len("VALUES("):], ", ")
+		if table != "provinces" {
+			for i, tbl := range tables {
+				if tbl == table && !inserted[tables[i-1]+" "+values[2]] {
+					t.Error("parent not inserted before", line)
+				}
+			}
+		}
+		inserted[table+" "+values[0]] = true
+	}
+	if len(inserted) != 9 {
+		t.Error(len(inserted), "rows inserted")
+	}
+
+	if code := run([]string{"-normalized", name, "-normalized-keys"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	if data, err = ioutil.ReadFile(name); err != nil {
+		t.Fatal(err)
+	}
+	if want := "INSERT INTO `areas`(id, node, pid, lft, rgt) VALUES(110101, '东城区', 110100, 3, 8);\n"; !strings.Contains(string(data), want) {
+		t.Error(want, "missing in", string(data))
+	}
+}
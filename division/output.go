@@ -0,0 +1,87 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// atomicFiles writes outputs to temp files in their destination directories, which are renamed over the
+// targets together by commit, so a failed run never leaves truncated or partial outputs behind
+type atomicFiles struct {
+	tmps  []*os.File
+	names []string
+}
+
+// create opens a temp file for target name
+func (a *atomicFiles) create(name string) (*os.File, error) {
+	tmp, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name)+".tmp")
+	if err != nil {
+		return nil, err
+	}
+	a.tmps = append(a.tmps, tmp)
+	a.names = append(a.names, name)
+	if err = tmp.Chmod(0644); err != nil {
+		return nil, err
+	}
+	return tmp, nil
+}
+
+// sync flushes and closes all temp files, which are ready to be checked or committed
+func (a *atomicFiles) sync() error {
+	for _, tmp := range a.tmps {
+		if err := tmp.Sync(); err != nil {
+			return err
+		}
+		if err := tmp.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// commit renames all temp files over their targets. Renames are not transactional, but all content is
+// synced before the first one so only a crash between renames could mix versions.
+func (a *atomicFiles) commit() error {
+	for i, tmp := range a.tmps {
+		if err := os.Rename(tmp.Name(), a.names[i]); err != nil {
+			return err
+		}
+	}
+	a.tmps, a.names = nil, nil
+	return nil
+}
+
+// abort removes temp files which are not committed
+func (a *atomicFiles) abort() {
+	for _, tmp := range a.tmps {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}
+	a.tmps, a.names = nil, nil
+}
+
+// writeFileAtomic writes name through a temp file, which is renamed over name only after write and the
+// optional check of the temp file succeed
+func writeFileAtomic(name string, write func(w io.Writer) error, check func(tmp string) error) (err error) {
+	var files atomicFiles
+	defer files.abort()
+
+	tmp, err := files.create(name)
+	if err != nil {
+		return err
+	}
+	if err = write(tmp); err != nil {
+		return err
+	}
+	if err = files.sync(); err != nil {
+		return err
+	}
+	if check != nil {
+		if err = check(tmp.Name()); err != nil {
+			return err
+		}
+	}
+	return files.commit()
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomicFailure(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "division.sql")
+	if err := ioutil.WriteFile(name, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	errWrite := errors.New("disk full")
+	err := writeFileAtomic(name, func(w io.Writer) error {
+		if _, err := io.WriteString(w, "INSERT INTO nested"); err != nil {
+			return err
+		}
+		return errWrite
+	}, nil)
+	if err != errWrite {
+		t.Error(err)
+	}
+
+	data, err := ioutil.ReadFile(name)
+	if err != nil || string(data) != "old\n" {
+		t.Error(string(data), err)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Error("temp files left:", len(files))
+	}
+}
+
+func TestWriteFileAtomicPanic(t *testing.T) {
+	dir := t.TempDir()
+	func() {
+		defer func() { recover() }()
+		writeFileAtomic(filepath.Join(dir, "division.sql"), func(w io.Writer) error {
+			io.WriteString(w, "INSERT INTO nested")
+			panic("bug")
+		}, nil)
+	}()
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Error("files left:", len(files))
+	}
+}
+
+func TestAtomicFilesAllOrNothing(t *testing.T) {
+	dir := t.TempDir()
+	var files atomicFiles
+	for _, name := range []string{"a.sql", "b.sql"} {
+		f, err := files.create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(f, name)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 2 {
+		t.Fatal(len(files))
+	}
+	files.abort()
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Error("files left:", len(files))
+	}
+}
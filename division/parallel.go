@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+)
+
+// jobs is the number of provinces serialized at once with -jobs. Keys are assigned before, all provinces being
+// numbered in one sequence, and the sql of each is written in the order of the trees whatever finishes first.
+var jobs = runtime.NumCPU()
+
+// stmtBuffer keeps the statements written to it, each Write being one, to be replayed in order
+type stmtBuffer struct {
+	buf  bytes.Buffer
+	ends []int
+}
+
+func (b *stmtBuffer) Write(stmt []byte) (int, error) {
+	n, _ := b.buf.Write(stmt)
+	b.ends = append(b.ends, b.buf.Len())
+	return n, nil
+}
+
+// replay writes the statements to w, one Write each as they were written, so a stmtWriter still counts them
+func (b *stmtBuffer) replay(w io.Writer) error {
+	data, start := b.buf.Bytes(), 0
+	for _, end := range b.ends {
+		if _, err := w.Write(data[start:end]); err != nil {
+			return err
+		}
+		start = end
+	}
+	return nil
+}
+
+// genParallel serializes each tree with gen on a goroutine of its own, up to jobs of them at once, and writes
+// the statements to w in the order of the trees. At most jobs provinces are held in memory; the first error in
+// the order of the trees is returned, as by serializing them one after another.
+func genParallel(w io.Writer, trees []*Area, gen func(io.Writer, []*Area) error) error {
+	type result struct {
+		stmts *stmtBuffer
+		err   error
+	}
+	results := make([]chan result, len(trees))
+	for i := range results {
+		results[i] = make(chan result, 1)
+	}
+	slots := make(chan struct{}, jobs)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for i, p := range trees {
+			select {
+			case slots <- struct{}{}:
+			case <-done:
+				return
+			}
+			go func(i int, p *Area) {
+				var b stmtBuffer
+				err := gen(&b, []*Area{p})
+				results[i] <- result{&b, err}
+			}(i, p)
+		}
+	}()
+
+	for _, ch := range results {
+		r := <-ch
+		<-slots
+		if r.err != nil {
+			return r.err
+		}
+		if err := r.stmts.replay(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode"
+)
+
+// keys of the prompt besides typed runes
+const (
+	keyRune = iota
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyEnter
+	keyBackspace
+	keyEsc
+	keyCancel // Ctrl-C or Ctrl-D
+)
+
+type key struct {
+	kind int
+	r    rune
+}
+
+// readKey decodes a key press of a terminal in raw mode, or of scripted input. Escape sequences of arrow keys
+// arrive in one read, so an escape with nothing buffered after it is the escape key itself.
+func readKey(r *bufio.Reader) (key, error) {
+	c, _, err := r.ReadRune()
+	if err != nil {
+		return key{}, err
+	}
+	switch c {
+	case '\r', '\n':
+		return key{kind: keyEnter}, nil
+	case 0x7f, 0x08:
+		return key{kind: keyBackspace}, nil
+	case 0x03, 0x04:
+		return key{kind: keyCancel}, nil
+	case 0x1b:
+		if r.Buffered() < 2 {
+			return key{kind: keyEsc}, nil
+		}
+		if b, _ := r.Peek(1); b[0] != '[' && b[0] != 'O' {
+			return key{kind: keyEsc}, nil
+		}
+		seq := make([]byte, 2)
+		if _, err = io.ReadFull(r, seq); err != nil {
+			return key{}, err
+		}
+		switch seq[1] {
+		case 'A':
+			return key{kind: keyUp}, nil
+		case 'B':
+			return key{kind: keyDown}, nil
+		case 'C':
+			return key{kind: keyRight}, nil
+		case 'D':
+			return key{kind: keyLeft}, nil
+		}
+		return readKey(r) // another sequence, ignored
+	}
+	return key{kind: keyRune, r: c}, nil
+}
+
+// errCanceled is returned when the prompt is left without a pick
+var errCanceled = errors.New("canceled")
+
+// pickerRows is how many items the prompt shows at once
+const pickerRows = 10
+
+// picker walks down the trees level by level. Each level lists the children of the node picked last, filtered
+// by the typed text, with the node itself above them to pick it instead of going further down.
+type picker struct {
+	roots  []*Area
+	path   []*Area
+	filter string
+	items  []*Area // nil for the node itself
+	cursor int
+	keys   map[*Area]string // code, name and pinyin matched by filters
+}
+
+func newPicker(roots []*Area) *picker {
+	p := &picker{roots: roots, keys: make(map[*Area]string)}
+	p.refresh()
+	return p
+}
+
+// matchKey is what a filter matches: the code, the name, the pinyin and its initials, e.g. "hebei" and "hb"
+func (p *picker) matchKey(a *Area) string {
+	k, ok := p.keys[a]
+	if !ok {
+		syllables := pinyin(nodeName(a))
+		var initials strings.Builder
+		for _, s := range syllables {
+			if s != "" {
+				initials.WriteString(s[:1])
+			}
+		}
+		k = strings.ToLower(a.Code + " " + nodeName(a) + " " + strings.Join(syllables, "") + " " + initials.String())
+		p.keys[a] = k
+	}
+	return k
+}
+
+// refresh lists the items of the current level matching the filter
+func (p *picker) refresh() {
+	level := p.roots
+	p.items = nil
+	if len(p.path) > 0 {
+		level = p.path[len(p.path)-1].SubAreas
+		if p.filter == "" {
+			p.items = append(p.items, nil)
+		}
+	}
+	filter := strings.ToLower(p.filter)
+	for _, a := range level {
+		if strings.Contains(p.matchKey(a), filter) {
+			p.items = append(p.items, a)
+		}
+	}
+	if p.cursor >= len(p.items) {
+		p.cursor = len(p.items) - 1
+	}
+	if p.cursor < 0 {
+		p.cursor = 0
+	}
+}
+
+// down goes into the children of a with the cursor on the first one, or picks a when it has none
+func (p *picker) down(a *Area) bool {
+	p.path = append(p.path, a)
+	if len(a.SubAreas) == 0 {
+		return true
+	}
+	p.filter, p.cursor = "", 1
+	p.refresh()
+	return false
+}
+
+// back clears the filter, or goes up a level with the cursor on the node left
+func (p *picker) back() {
+	if p.filter != "" {
+		p.filter = ""
+		p.refresh()
+		return
+	}
+	if len(p.path) == 0 {
+		return
+	}
+	left := p.path[len(p.path)-1]
+	p.path = p.path[:len(p.path)-1]
+	p.refresh()
+	for i, a := range p.items {
+		if a == left {
+			p.cursor = i
+		}
+	}
+}
+
+// handle applies a key and tells whether a node is picked, which is then at the end of path
+func (p *picker) handle(k key) (bool, error) {
+	switch k.kind {
+	case keyUp:
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case keyDown:
+		if p.cursor < len(p.items)-1 {
+			p.cursor++
+		}
+	case keyEnter, keyRight:
+		if len(p.items) == 0 {
+			break
+		}
+		a := p.items[p.cursor]
+		if a == nil {
+			return true, nil // the node itself
+		}
+		if k.kind == keyRight && len(a.SubAreas) == 0 {
+			break
+		}
+		return p.down(a), nil
+	case keyLeft, keyEsc:
+		p.back()
+	case keyBackspace:
+		if p.filter == "" {
+			p.back()
+			break
+		}
+		runes := []rune(p.filter)
+		p.filter = string(runes[:len(runes)-1])
+		p.refresh()
+	case keyCancel:
+		return false, errCanceled
+	case keyRune:
+		if unicode.IsPrint(k.r) {
+			p.filter += string(k.r)
+			p.cursor = 0
+			p.refresh()
+		}
+	}
+	return false, nil
+}
+
+// render draws the prompt, lines end with \r\n for terminals in raw mode
+func (p *picker) render(w io.Writer) {
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	names := make([]string, len(p.path))
+	for i, a := range p.path {
+		names[i] = nodeName(a)
+	}
+	fmt.Fprintf(&b, "%s> %s\r\n", strings.Join(names, " / "), p.filter)
+	start := 0
+	if p.cursor >= pickerRows {
+		start = p.cursor - pickerRows + 1
+	}
+	for i := start; i < len(p.items) && i < start+pickerRows; i++ {
+		mark := "  "
+		if i == p.cursor {
+			mark = "> "
+		}
+		a := p.items[i]
+		switch {
+		case a == nil:
+			fmt.Fprintf(&b, "%s= %s %s\r\n", mark, p.path[len(p.path)-1].Code, names[len(names)-1])
+		case len(a.SubAreas) > 0:
+			fmt.Fprintf(&b, "%s%s %s (%d)\r\n", mark, a.Code, nodeName(a), len(a.SubAreas))
+		default:
+			fmt.Fprintf(&b, "%s%s %s\r\n", mark, a.Code, nodeName(a))
+		}
+	}
+	if len(p.items) == 0 {
+		b.WriteString("  no match\r\n")
+	}
+	b.WriteString("\r\ntype to filter by name, pinyin or code, enter to pick, left or backspace to go back, ctrl-c to quit\r\n")
+	io.WriteString(w, b.String())
+}
+
+// prompt runs the picker on keys until a node is picked, and returns the path to it
+func prompt(p *picker, in io.Reader, screen io.Writer) ([]*Area, error) {
+	r := bufio.NewReader(in)
+	for {
+		p.render(screen)
+		k, err := readKey(r)
+		if err == io.EOF {
+			return nil, errCanceled
+		}
+		if err != nil {
+			return nil, err
+		}
+		done, err := p.handle(k)
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			return p.path, nil
+		}
+	}
+}
+
+// rawTerminal switches the terminal of in to raw mode with stty, and returns a function restoring it. Input
+// which is not a terminal, which stty does not know, is left as it is.
+func rawTerminal(in io.Reader) (func(), error) {
+	f, ok := in.(*os.File)
+	if !ok {
+		return func() {}, nil
+	}
+	stty := func(args ...string) (string, error) {
+		cmd := exec.Command("stty", args...)
+		cmd.Stdin = f
+		out, err := cmd.Output()
+		return strings.TrimSpace(string(out)), err
+	}
+	state, err := stty("-g")
+	if err != nil {
+		return func() {}, nil
+	}
+	if _, err = stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+	return func() { stty(state) }, nil
+}
+
+// runPick lets people find a code by picking a province, then a city and so on, and prints the code and the
+// full name of the pick on stdout. The prompt is drawn on stderr, so the pick could be captured.
+func runPick(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("division pick", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	from := fs.String("from", dataDir, "data `directory` or generated sql file")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: division pick [-from dir|file]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	trees, err := loadTrees(*from)
+	if err != nil {
+		fmt.Fprintln(stderr, "division pick:", err)
+		return exitCode(err)
+	}
+	restore, err := rawTerminal(stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, "division pick:", err)
+		return exitIO
+	}
+	path, err := prompt(newPicker(trees), stdin, stderr)
+	restore()
+	fmt.Fprint(stderr, "\x1b[H\x1b[2J")
+	if err == errCanceled {
+		return exitCanceled
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "division pick:", err)
+		return exitIO
+	}
+	fmt.Fprintf(stdout, "%s\t%s\n", path[len(path)-1].Code, fullName(path, nodeName))
+	return exitOK
+}
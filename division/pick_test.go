@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestReadKey(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("a石\x1b[A\x1b[B\x1b[C\x1b[D\r\x7f\x03\x1b"))
+	want := []key{{keyRune, 'a'}, {keyRune, '石'}, {kind: keyUp}, {kind: keyDown}, {kind: keyRight}, {kind: keyLeft},
+		{kind: keyEnter}, {kind: keyBackspace}, {kind: keyCancel}, {kind: keyEsc}}
+	for _, w := range want {
+		if k, err := readKey(r); err != nil || k != w {
+			t.Error(k, err, "want", w)
+		}
+	}
+}
+
+// usePick runs the pick subcommand on scripted keys, and returns the exit code and what it printed on stdout
+func usePick(t *testing.T, keys string) (int, string) {
+	out := useStdout(t)
+	old := stdin
+	stdin = strings.NewReader(keys)
+	defer func() { stdin = old }()
+	code := run([]string{"pick", "-from", "./testdata/mini"}, ioutil.Discard)
+	return code, out.String()
+}
+
+func TestPick(t *testing.T) {
+	for _, c := range []struct {
+		keys string
+		code int
+		out  string
+	}{
+		// filtered by pinyin initials down to a street
+		{"hb\r\r\r\r", exitOK, "130102001000\t河北省石家庄市长安区建北街道办事处\n"},
+		// by pinyin and code, then the node itself
+		{"beijing\r\r11010\r\x1b[A\r", exitOK, "110101\t北京市市辖区东城区\n"},
+		// arrow keys, and backspace going up a level
+		{"\x1b[B\r\x7f\x1b[D\x1b[A\x1b[C\x1b[B\r\r\x1b[B\r", exitOK, "110101002000\t北京市市辖区东城区景山街道办事处\n"},
+		// a filter cleared by escape
+		{"xyz\x1b\x1b[B\r\x1b[A\r", exitOK, "130000\t河北省\n"},
+		{"hb\r\x03", exitCanceled, ""},
+		{"hb\r", exitCanceled, ""},
+	} {
+		code, out := usePick(t, c.keys)
+		if code != c.code || out != c.out {
+			t.Errorf("%q: exit code %d, %q", c.keys, code, out)
+		}
+	}
+}
+
+func TestPickerRender(t *testing.T) {
+	trees, err := loadTrees("./testdata/mini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := newPicker(trees)
+	p.handle(key{kind: keyEnter})
+	var screen bytes.Buffer
+	p.render(&screen)
+	for _, s := range []string{"北京市> \r\n", "  = 110000 北京市\r\n", "> 110100 市辖区 (1)\r\n"} {
+		if !strings.Contains(screen.String(), s) {
+			t.Errorf("%q missing in %q", s, screen.String())
+		}
+	}
+	p.handle(key{kind: keyRune, r: 'z'})
+	screen.Reset()
+	p.render(&screen)
+	if !strings.Contains(screen.String(), "北京市> z\r\n  no match\r\n") {
+		t.Errorf("%q", screen.String())
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// releaseURL is where the input files of a tag of the source repository are published
+const releaseURL = "https://raw.githubusercontent.com/modood/Administrative-divisions-of-China/%s/dist/"
+
+var (
+	// dataRelease is the tag or base URL of -data-release, the input files are downloaded from
+	dataRelease string
+	// dataSHA256 is the checksum of -data-sha256, the input files must have
+	dataSHA256 string
+)
+
+// sha256Pattern is what -data-sha256 takes, as printed by sha256sum
+var sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// releaseBase is the base URL of the input files of a release, a tag of the source repository unless it is a
+// URL itself
+func releaseBase(ref string) string {
+	if strings.Contains(ref, "://") {
+		return ref
+	}
+	return fmt.Sprintf(releaseURL, ref)
+}
+
+// dataChecksum hashes the input files present in dir, each by name, size and content, from top to bottom of
+// the hierarchy, so the checksum changes with any of the files as well as with a level missing
+func dataChecksum(dir string) (string, error) {
+	h := sha256.New()
+	for _, l := range inputLevels() {
+		f, err := os.Open(filepath.Join(dir, l.file))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		info, err := f.Stat()
+		if err == nil {
+			fmt.Fprintf(h, "%s %d\n", l.file, info.Size())
+			_, err = io.Copy(h, f)
+		}
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// pinData makes the data directory hold the release of -data-release, checked with -data-sha256. Input files
+// already matching the checksum are kept, otherwise the release is downloaded next to them and replaces them
+// once it matches, so builds run against the same data wherever they run. Without -data-release the files in
+// place are only checked.
+func pinData() error {
+	if dataRelease == "" {
+		if dataSHA256 == "" {
+			return nil
+		}
+		sum, err := dataChecksum(dataDir)
+		if err != nil {
+			return err
+		}
+		if sum != dataSHA256 {
+			return dataErrorf("%s has checksum %s, not %s of -data-sha256", dataDir, sum, dataSHA256)
+		}
+		return nil
+	}
+	if dataSHA256 != "" {
+		if sum, err := dataChecksum(dataDir); err == nil && sum == dataSHA256 {
+			logger.Debug("pinned data in place", "dir", dataDir, "sha256", sum)
+			return nil
+		}
+	}
+
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp(dataDir, ".release-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	base := releaseBase(dataRelease)
+	if err := fetchUpstream(&http.Client{Timeout: time.Minute}, base, tmp); err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(tmp, provincesFile)); os.IsNotExist(err) {
+		return dataErrorf("no %s in release %s at %s", provincesFile, dataRelease, base)
+	}
+	sum, err := dataChecksum(tmp)
+	if err != nil {
+		return err
+	}
+	switch {
+	case dataSHA256 == "":
+		logger.Warn("release not pinned, pass its checksum with -data-sha256", "release", dataRelease, "sha256", sum)
+	case sum != dataSHA256:
+		return dataErrorf("release %s has checksum %s, not %s of -data-sha256", dataRelease, sum, dataSHA256)
+	}
+	for _, l := range inputLevels() {
+		name := filepath.Join(dataDir, l.file)
+		err := os.Rename(filepath.Join(tmp, l.file), name)
+		if os.IsNotExist(err) {
+			err = os.Remove(name)
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	logger.Info("release downloaded", "release", dataRelease, "dir", dataDir, "sha256", sum)
+	return nil
+}
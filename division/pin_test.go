@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDataRelease(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir("./testdata")))
+	defer server.Close()
+	sum, err := dataChecksum("./testdata/mini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other, _ := dataChecksum("./testdata/mini2"); other == sum || !sha256Pattern.MatchString(sum) {
+		t.Fatal("checksums:", sum, other)
+	}
+
+	dir := filepath.Join(t.TempDir(), "data")
+	usePaths(t, dir)
+	var stderr bytes.Buffer
+	if code := run([]string{"-data-release", server.URL + "/mini/", "-data-sha256", sum}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	if got, _ := dataChecksum(dir); got != sum {
+		t.Error("checksum of the downloaded files:", got)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 4 {
+		t.Error("left in the data directory:", entries)
+	}
+
+	// a release of another checksum leaves the data as it was, the pinned data is not downloaded again
+	if code := run([]string{"-data-release", server.URL + "/mini2/", "-data-sha256", sum[1:] + "0"}, &stderr); code != exitData {
+		t.Error("exit code of a checksum mismatch:", code)
+	}
+	if got, _ := dataChecksum(dir); got != sum {
+		t.Error("checksum after a mismatch:", got)
+	}
+	server.Close()
+	if code := run([]string{"-data-release", server.URL + "/mini/", "-data-sha256", sum}, &stderr); code != exitOK {
+		t.Error("exit code of pinned data in place:", code, stderr.String())
+	}
+	if code := run([]string{"-data-release", server.URL + "/mini/"}, &stderr); code != exitNetwork {
+		t.Error("exit code of a closed server:", code)
+	}
+
+	// without a release the data in place is checked
+	usePaths(t, "./testdata/mini2")
+	if code := run([]string{"-data-sha256", sum}, &stderr); code != exitData {
+		t.Error("exit code of other data:", code)
+	}
+	if code := run([]string{"-data-sha256", "abc"}, &stderr); code != exitUsage {
+		t.Error("exit code of a bad checksum:", code)
+	}
+	if releaseBase("v2.7.0") != "https://raw.githubusercontent.com/modood/Administrative-divisions-of-China/v2.7.0/dist/" {
+		t.Error(releaseBase("v2.7.0"))
+	}
+}
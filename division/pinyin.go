@@ -0,0 +1,149 @@
+package main
+
+import (
+	_ "embed"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// pinyinTable lists toneless pinyin and its characters per line, generated by tools/pinyin.py
+//
+//go:embed pinyin.txt
+var pinyinTable string
+
+var (
+	pinyinOnce  sync.Once
+	charPinyins map[rune]string
+)
+
+// charReadings overrides characters whose reading in place names differs from the common one
+var charReadings = map[rune]string{
+	'长': "chang", // 长沙, 长春
+	'厦': "xia",   // 厦门
+	'都': "du",    // 成都, 都匀
+	'藏': "zang",  // 西藏
+	'枞': "zong",  // 枞阳
+	'涡': "guo",   // 涡阳
+	'勒': "le",    // 锡林郭勒
+	'什': "shi",   // 喀什
+	'地': "di",    // 基地, 林地
+}
+
+// wordReadings overrides readings of words, which take precedence over characters
+var wordReadings = map[string][]string{
+	"重庆": {"chong", "qing"},
+	"六安": {"lu", "an"},
+	"六合": {"lu", "he"},
+	"番禺": {"pan", "yu"},
+	"单县": {"shan", "xian"},
+	"铅山": {"yan", "shan"},
+	"蔚县": {"yu", "xian"},
+	"黄陂": {"huang", "pi"},
+	"东阿": {"dong", "e"},
+	"长子": {"zhang", "zi"},
+	"覃塘": {"qin", "tang"},
+	"乐亭": {"lao", "ting"},
+	"洪洞": {"hong", "tong"},
+}
+
+// maxWordLen is the length in characters of the longest word in wordReadings
+const maxWordLen = 2
+
+func loadPinyin() {
+	charPinyins = make(map[rune]string)
+	for _, line := range strings.Split(pinyinTable, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		for _, c := range fields[1] {
+			charPinyins[c] = fields[0]
+		}
+	}
+	for c, py := range charReadings {
+		charPinyins[c] = py
+	}
+}
+
+// pinyin returns toneless syllables of name, one per character. Characters without pinyin, e.g. latin
+// letters or digits, are returned as they are.
+func pinyin(name string) []string {
+	pinyinOnce.Do(loadPinyin)
+	runes := []rune(name)
+	syllables := make([]string, 0, len(runes))
+	for i := 0; i < len(runes); {
+		if word, n := matchWord(runes[i:]); n > 0 {
+			syllables = append(syllables, word...)
+			i += n
+			continue
+		}
+		if py, ok := charPinyins[runes[i]]; ok {
+			syllables = append(syllables, py)
+		} else {
+			syllables = append(syllables, string(runes[i]))
+		}
+		i++
+	}
+	return syllables
+}
+
+// matchWord finds the longest word of wordReadings at the start of runes
+func matchWord(runes []rune) ([]string, int) {
+	for n := maxWordLen; n > 1; n-- {
+		if len(runes) < n {
+			continue
+		}
+		if word, ok := wordReadings[string(runes[:n])]; ok {
+			return word, n
+		}
+	}
+	return nil, 0
+}
+
+// initial returns the upper case first letter of the pinyin of name, e.g. "C" for 长沙市, or "" if the name
+// does not start with a letter or a character with known pinyin
+func initial(name string) string {
+	if name == "" {
+		return ""
+	}
+	first, _ := utf8.DecodeRuneInString(pinyin(name)[0])
+	if first > unicode.MaxASCII || !unicode.IsLetter(first) {
+		return ""
+	}
+	return string(unicode.ToUpper(first))
+}
+
+// namePinyin spells name in lower case toneless pinyin, syllables separated by spaces so that names sort and
+// match by syllable, e.g. "chang sha shi" for 长沙市. Latin letters and digits are kept, full-width digits as
+// ASCII ones, punctuation such as the brackets of 磐石经济开发区（省级） is left out.
+func namePinyin(name string) string {
+	syllables := pinyin(name)
+	words := syllables[:0]
+	for _, s := range syllables {
+		s = strings.Map(func(r rune) rune {
+			switch {
+			case r <= unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+				return unicode.ToLower(r)
+			case r >= '０' && r <= '９':
+				return r - '０' + '0'
+			}
+			return -1
+		}, s)
+		if s != "" {
+			words = append(words, s)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// pinyinInitials abbreviates name by the first letters of its pinyin syllables, e.g. "hzs" for 杭州市, as
+// typed in typeahead pickers
+func pinyinInitials(name string) string {
+	var b strings.Builder
+	for _, word := range strings.Fields(namePinyin(name)) {
+		b.WriteByte(word[0])
+	}
+	return b.String()
+}
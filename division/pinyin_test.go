@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPinyin(t *testing.T) {
+	for name, want := range map[string]string{
+		"北京市":  "bei jing shi",
+		"长沙市":  "chang sha shi",
+		"长子县":  "zhang zi xian",
+		"重庆市":  "chong qing shi",
+		"六安市":  "lu an shi",
+		"QQ专区": "Q Q zhuan qu",
+	} {
+		if py := strings.Join(pinyin(name), " "); py != want {
+			t.Error(name, py)
+		}
+	}
+}
+
+func TestInitial(t *testing.T) {
+	for name, want := range map[string]string{
+		"北京市":  "B",
+		"长沙市":  "C",
+		"重庆市":  "C",
+		"厦门市":  "X",
+		"成都市":  "C",
+		"qq":   "Q",
+		"":     "",
+		"1号区域": "",
+	} {
+		if i := initial(name); i != want {
+			t.Errorf("%s: %q", name, i)
+		}
+	}
+}
+
+func TestNamePinyin(t *testing.T) {
+	for name, want := range map[string]string{
+		"长沙市":         "chang sha shi",
+		"大兴生物医药产业基地":  "da xing sheng wu yi yao chan ye ji di",
+		"磐石经济开发区（省级）": "pan shi jing ji kai fa qu sheng ji",
+		"八五一０农场":      "ba wu yi 0 nong chang",
+		"QQ专区":        "q q zhuan qu",
+	} {
+		if py := namePinyin(name); py != want {
+			t.Errorf("%s: %q", name, py)
+		}
+	}
+
+	for name, want := range map[string]string{
+		"杭州市":    "hzs",
+		"六安市":    "las",
+		"八五一０农场": "bwy0nc",
+		"（）":     "",
+	} {
+		if py := pinyinInitials(name); py != want {
+			t.Errorf("%s: %q", name, py)
+		}
+	}
+
+	usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run([]string{"-columns", "pinyin,pinyin_initials"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := os.ReadFile(sqlFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "VALUES(130102, '长安区', 130100, 3, 13, 16, 'chang an qu', 'caq');"; !strings.Contains(string(data), want) {
+		t.Error(want, "missing in", string(data))
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+)
+
+// field numbers of proto/tree.proto
+const (
+	pbTreeRoots     = 1
+	pbNodeCode      = 1
+	pbNodeName      = 2
+	pbNodeDepth     = 3
+	pbNodeLeft      = 4
+	pbNodeRight     = 5
+	pbNodeChildren  = 6
+	pbWireVarint    = 0
+	pbWireDelimited = 2
+)
+
+// genProtobufFile writes the trees into the -out file as a Tree message of proto/tree.proto, in the binary wire
+// format, which is encoded here so the build needs no protobuf package
+func genProtobufFile(trees []*Area) error {
+	return writeFileAtomic(sqlFile, func(w io.Writer) error {
+		return writeProtobufTree(w, trees)
+	}, nil)
+}
+
+func writeProtobufTree(w io.Writer, trees []*Area) error {
+	bw := bufio.NewWriterSize(w, sqlBufferSize)
+	for _, p := range trees {
+		if _, err := bw.Write(appendPBBytes(nil, pbTreeRoots, appendPBNode(nil, p, 1))); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// appendPBNode appends the TreeNode of the subtree of a at depth. Children are encoded before their parent
+// takes them, as a message is prefixed with its length.
+func appendPBNode(b []byte, a *Area, depth int) []byte {
+	b = appendPBBytes(b, pbNodeCode, []byte(a.Code))
+	b = appendPBBytes(b, pbNodeName, []byte(nodeName(a)))
+	b = appendPBVarint(b, pbNodeDepth, uint64(depth))
+	b = appendPBVarint(b, pbNodeLeft, uint64(a.Left))
+	b = appendPBVarint(b, pbNodeRight, uint64(a.Right))
+	var child []byte
+	for _, sub := range a.SubAreas {
+		child = appendPBNode(child[:0], sub, depth+1)
+		b = appendPBBytes(b, pbNodeChildren, child)
+	}
+	return b
+}
+
+func appendPBVarint(b []byte, field int, v uint64) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3|pbWireVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+func appendPBBytes(b []byte, field int, v []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3|pbWireDelimited)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
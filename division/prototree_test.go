@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"testing"
+)
+
+// decodePBNode reads a TreeNode of proto/tree.proto into a jsonNode
+func decodePBNode(t *testing.T, b []byte) *jsonNode {
+	t.Helper()
+	n := &jsonNode{}
+	for len(b) > 0 {
+		key, k := binary.Uvarint(b)
+		v, l := binary.Uvarint(b[k:])
+		if k <= 0 || l <= 0 {
+			t.Fatalf("bad varint in % x", b)
+		}
+		b = b[k+l:]
+		if key&7 == pbWireDelimited {
+			value := b[:v]
+			b = b[v:]
+			switch key >> 3 {
+			case pbNodeCode:
+				n.Code = string(value)
+			case pbNodeName:
+				n.Name = string(value)
+			case pbNodeChildren:
+				n.Children = append(n.Children, decodePBNode(t, value))
+			}
+			continue
+		}
+		switch key >> 3 {
+		case pbNodeDepth:
+			n.Depth = int(v)
+		case pbNodeLeft:
+			n.Left = int32(v)
+		case pbNodeRight:
+			n.Right = int32(v)
+		}
+	}
+	return n
+}
+
+func TestFormatProtobuf(t *testing.T) {
+	out := usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run([]string{"-format", "protobuf"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a Tree is its roots, each field 1 of a TreeNode
+	var roots []*jsonNode
+	for b := data; len(b) > 0; {
+		if b[0] != pbTreeRoots<<3|pbWireDelimited {
+			t.Fatalf("unexpected key % x", b[0])
+		}
+		n, k := binary.Uvarint(b[1:])
+		roots = append(roots, decodePBNode(t, b[1+k:1+k+int(n)]))
+		b = b[1+k+int(n):]
+	}
+	if len(roots) != 2 {
+		t.Fatal(roots)
+	}
+	p := roots[0]
+	if p.Code != "110000" || p.Name != "北京市" || p.Depth != 1 || p.Left != 1 || p.Right != 10 || len(p.Children) != 1 {
+		t.Error(p)
+	}
+	street := p.Children[0].Children[0].Children[1]
+	if street.Code != "110101002000" || street.Depth != 4 || street.Left != 6 || street.Right != 7 || street.Children != nil {
+		t.Error(street)
+	}
+	if roots[1].Name != "河北省" || roots[1].Children[0].Children[0].Code != "130102" {
+		t.Error(roots[1])
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/BionStt/nested"
+)
+
+// keyFix is a row whose depth and keys are rebuilt, with the values found in the table
+type keyFix struct {
+	id                 int64
+	depth, left, right int32
+	was                [3]int32
+}
+
+// rebuildKeys reads id and pid of the rows of the table of -dsn, builds the trees again with siblings in the order
+// of their lft, and returns the rows whose depth, lft or rgt differ from the ones built, in the order of the
+// trees. Rows of duplicate ids, missing parents or cycles of parents fail the rebuild.
+func rebuildKeys(db *sql.DB) ([]keyFix, error) {
+	rows, err := db.Query("SELECT " + strings.Join([]string{quoteIdent("id"), quoteIdent("pid"), quoteIdent("depth"),
+		quoteIdent("lft"), quoteIdent("rgt")}, ", ") + " FROM " + tableRef() + " ORDER BY " + quoteIdent("lft") + ", " +
+		quoteIdent("id"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var records []nested.Record
+	found := make(map[int64][3]int32)
+	for rows.Next() {
+		var id int64
+		var pid sql.NullInt64
+		var keys [3]int32
+		if err := rows.Scan(&id, &pid, &keys[0], &keys[1], &keys[2]); err != nil {
+			return nil, err
+		}
+		records = append(records, nested.Record{ID: id, ParentID: pid.Int64})
+		found[id] = keys
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tree, err := nested.Build(records)
+	if err != nil {
+		return nil, dataErrorf("%s: %v", tblName, err)
+	}
+	if 2*int64(len(records))*int64(keyStep) > math.MaxInt32 {
+		return nil, dataErrorf("%d rows take keys up to %d with -key-step %d, more than lft and rgt hold", len(records),
+			2*int64(len(records))*int64(keyStep), keyStep)
+	}
+	roots := make([]nested.Nester, len(tree.Roots))
+	for i, n := range tree.Roots {
+		roots[i] = n
+	}
+	nested.AssignKeysSpaced(roots, keyStep)
+
+	var fixes []keyFix
+	tree.Walk(func(n *nested.TreeNode) error {
+		if was := found[n.ID]; was != [3]int32{n.Depth, n.Left, n.Right} {
+			fixes = append(fixes, keyFix{n.ID, n.Depth, n.Left, n.Right, was})
+		}
+		return nil
+	})
+	return fixes, nil
+}
+
+// updateKeys writes the rebuilt depth and keys back in one transaction, through a prepared statement
+func updateKeys(db *sql.DB, fixes []keyFix) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+	stmt, err := tx.Prepare("UPDATE " + tableRef() + " SET " + quoteIdent("depth") + " = " + placeholder(1) + ", " +
+		quoteIdent("lft") + " = " + placeholder(2) + ", " + quoteIdent("rgt") + " = " + placeholder(3) +
+		" WHERE " + quoteIdent("id") + " = " + placeholder(4))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, f := range fixes {
+		if _, err = stmt.Exec(f.depth, f.left, f.right, f.id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// runRebuild recomputes depth, lft and rgt of a table from its id and pid, e.g. after keys drifted with manual
+// edits, and writes the corrections back. The corrected rows are listed on stdout.
+func runRebuild(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("division rebuild", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	defer func(table, d, schema string, step int32) {
+		tblName, dialect, dbSchema, keyStep = table, d, schema, step
+	}(tblName, dialect, dbSchema, keyStep)
+	fs.Var(tableFlag{}, "table", "`name` of the nested sets table")
+	fs.StringVar(&dialect, "dialect", "mysql", "database of the table, "+strings.Join(dialects, " or "))
+	fs.StringVar(&dbSchema, "db-schema", "", "`schema` of the table")
+	source := fs.String("dsn", "", "`data source` of the database, required")
+	step := fs.Int("key-step", 1, "distance of the keys, as generated with -key-step")
+	dryRun := fs.Bool("dry-run", false, "list the corrections without writing them")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: division rebuild [-table name] [-dialect name] [-db-schema schema] [-key-step n] [-dry-run] -dsn source")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 0 || *source == "" || !isDialect(dialect) || dbSchema != "" && !tableName.MatchString(dbSchema) ||
+		*step < 1 || *step > math.MaxInt32/2 {
+		fs.Usage()
+		return exitUsage
+	}
+	if !hasDriver() {
+		fmt.Fprintf(stderr, "division rebuild: no %s driver built in, build with -tags %s\n", driverNames[dialect], dialect)
+		return exitUsage
+	}
+	keyStep = int32(*step)
+
+	db, err := sql.Open(driverNames[dialect], *source)
+	if err != nil {
+		fmt.Fprintln(stderr, "division rebuild:", err)
+		return exitIO
+	}
+	defer db.Close()
+	fixes, err := rebuildKeys(db)
+	if err != nil {
+		fmt.Fprintln(stderr, "division rebuild:", err)
+		return exitCode(err)
+	}
+	for _, f := range fixes {
+		fmt.Fprintf(stdout, "%d: depth %d, lft %d, rgt %d, was %d, %d, %d\n", f.id, f.depth, f.left, f.right, f.was[0], f.was[1], f.was[2])
+	}
+	if *dryRun || len(fixes) == 0 {
+		return exitOK
+	}
+	if err := updateKeys(db, fixes); err != nil {
+		fmt.Fprintln(stderr, "division rebuild:", err)
+		return exitIO
+	}
+	fmt.Fprintf(stderr, "division rebuild: %d rows corrected\n", len(fixes))
+	return exitOK
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"strings"
+	"testing"
+)
+
+func TestRebuild(t *testing.T) {
+	useRecorder(t)
+	stdout := useStdout(t)
+	// 1 [1, 6] holding 11 and 12, and 2 [7, 8], with the keys of 12 and 2 drifted and 2 at the wrong depth
+	table := [][]driver.Value{
+		{int64(1), int64(0), int64(1), int64(1), int64(6)},
+		{int64(11), int64(1), int64(2), int64(2), int64(3)},
+		{int64(12), int64(1), int64(2), int64(4), int64(9)},
+		{int64(2), nil, int64(2), int64(10), int64(11)},
+	}
+	testDB.rows = table
+	var stderr bytes.Buffer
+	if code := run([]string{"rebuild", "-dialect", "sqlite", "-table", "geo", "-dsn", "test"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	if want := "12: depth 2, lft 4, rgt 5, was 2, 4, 9\n2: depth 1, lft 7, rgt 8, was 2, 10, 11\n"; stdout.String() != want {
+		t.Error(stdout.String())
+	}
+	want := []string{
+		`query SELECT "id", "pid", "depth", "lft", "rgt" FROM "geo" ORDER BY "lft", "id"`,
+		"begin", "exec 2,4,5,12", "exec 1,7,8,2", "commit",
+	}
+	if strings.Join(testDB.log, "\n") != strings.Join(want, "\n") {
+		t.Error(strings.Join(testDB.log, "\n"))
+	}
+
+	// a dry run writes nothing, a step spaces the keys
+	testDB.log, testDB.rows = nil, table
+	stdout.Reset()
+	if code := run([]string{"rebuild", "-dialect", "sqlite", "-dsn", "test", "-dry-run", "-key-step", "10"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	if len(testDB.log) != 1 || !strings.HasPrefix(stdout.String(), "1: depth 1, lft 10, rgt 60, was 1, 1, 6\n") {
+		t.Error(testDB.log, stdout.String())
+	}
+
+	testDB.rows = append(table[1:], []driver.Value{int64(1), int64(12), int64(1), int64(1), int64(6)})
+	if code := run([]string{"rebuild", "-dialect", "sqlite", "-dsn", "test"}, &stderr); code != exitData {
+		t.Error("cycle exit code:", code)
+	}
+	for _, args := range [][]string{{"-dialect", "sqlite"}, {"-dialect", "sqlite", "-dsn", "test", "extra"}, {"-dsn", "test"}} {
+		if code := run(append([]string{"rebuild"}, args...), &stderr); code != exitUsage {
+			t.Error(args, "exit code:", code)
+		}
+	}
+}
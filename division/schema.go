@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// columnTypes are the types of -with-schema by the MySQL types of the column definitions, VARCHAR for text of a
+// size, which goes in place of %d, and TEXT for longer text. MySQL tables take the definitions as they are.
+var columnTypes = map[string]map[string]string{
+	"postgres":   {"BIGINT": "BIGINT", "INT": "INTEGER", "INT UNSIGNED": "BIGINT", "TINYINT": "SMALLINT", "DOUBLE": "DOUBLE PRECISION", "VARCHAR": "VARCHAR(%d)", "TEXT": "TEXT"},
+	"sqlite":     {"BIGINT": "INTEGER", "INT": "INTEGER", "INT UNSIGNED": "INTEGER", "TINYINT": "INTEGER", "DOUBLE": "REAL", "VARCHAR": "TEXT", "TEXT": "TEXT"},
+	"mssql":      {"BIGINT": "BIGINT", "INT": "INT", "INT UNSIGNED": "BIGINT", "TINYINT": "TINYINT", "DOUBLE": "FLOAT", "VARCHAR": "NVARCHAR(%d)", "TEXT": "NVARCHAR(MAX)"},
+	"oracle":     {"BIGINT": "NUMBER(19)", "INT": "NUMBER(10)", "INT UNSIGNED": "NUMBER(10)", "TINYINT": "NUMBER(3)", "DOUBLE": "BINARY_DOUBLE", "VARCHAR": "NVARCHAR2(%d)", "TEXT": "NCLOB"},
+	"clickhouse": {"BIGINT": "Int64", "INT": "Int32", "INT UNSIGNED": "UInt32", "TINYINT": "UInt8", "DOUBLE": "Float64", "VARCHAR": "String", "TEXT": "String"},
+	"duckdb":     {"BIGINT": "BIGINT", "INT": "INTEGER", "INT UNSIGNED": "UINTEGER", "TINYINT": "UTINYINT", "DOUBLE": "DOUBLE", "VARCHAR": "VARCHAR", "TEXT": "VARCHAR"},
+}
+
+var textSize = regexp.MustCompile(`^(?:VAR)?CHAR\((\d+)\)`)
+
+// mysqlType is the type of a column definition as a key of columnTypes, with the size of VARCHAR
+func mysqlType(c column) (string, int) {
+	ddl := strings.ToUpper(c.ddl)
+	if c.text {
+		if m := textSize.FindStringSubmatch(ddl); m != nil {
+			size, _ := strconv.Atoi(m[1])
+			return "VARCHAR", size
+		}
+		return "TEXT", 0
+	}
+	for _, t := range []string{"DOUBLE", "TINYINT", "INT UNSIGNED", "BIGINT"} {
+		if strings.HasPrefix(ddl, t) {
+			return t, 0
+		}
+	}
+	if strings.HasPrefix(ddl, "LONGTEXT") {
+		return "TEXT", 0 // boundaries, written by functions of -geometry-format
+	}
+	return "INT", 0
+}
+
+// columnDef defines a column of -with-schema, Nullable in ClickHouse and NOT NULL elsewhere unless it takes NULL
+func columnDef(name, mysql string, size int, null bool) string {
+	t := columnTypes[dialect][mysql]
+	if strings.Contains(t, "%d") {
+		t = fmt.Sprintf(t, size)
+	}
+	switch {
+	case dialect == "clickhouse" && null:
+		t = "Nullable(" + t + ")"
+	case dialect != "clickhouse" && !null:
+		t += " NOT NULL"
+	}
+	return quoteIdent(name) + " " + t
+}
+
+// indexedColumns are indexed like in createtable.sql
+var indexedColumns = []string{"depth", "lft", "rgt"}
+
+// tableIndexes are the indexedColumns, and id_path when enabled, for the prefix queries of descendants of a
+// materialized path
+func tableIndexes() []string {
+	if hasColumn(columns, "id_path") {
+		return append(indexedColumns[:len(indexedColumns):len(indexedColumns)], "id_path")
+	}
+	return indexedColumns
+}
+
+// indexKey is the key of the index of column c. PostgreSQL serves LIKE prefixes of id_path from an index of
+// the pattern operators only, unless the database has the C collation.
+func indexKey(c string) string {
+	if c == "id_path" && dialect == "postgres" {
+		return quoteIdent(c) + " varchar_pattern_ops"
+	}
+	return quoteIdent(c)
+}
+
+// mysqlSchema is createtable.sql with the table name and the enabled optional columns
+func mysqlSchema() string {
+	defs := []string{
+		"`id` BIGINT NOT NULL COMMENT 'node ID'",
+		"`node` VARCHAR(64) CHARACTER SET 'utf8' NOT NULL COMMENT 'node name'",
+		"`pid` BIGINT NOT NULL COMMENT 'parent ID'",
+		"`depth` INT NOT NULL COMMENT 'Level'",
+		"`lft` INT NOT NULL COMMENT 'left index'",
+		"`rgt` INT NOT NULL COMMENT 'right index'",
+	}
+	for _, c := range columns {
+		defs = append(defs, "`"+c.name+"` "+c.ddl)
+	}
+	defs = append(defs, "PRIMARY KEY (`id`)")
+	for _, c := range tableIndexes() {
+		defs = append(defs, "INDEX `"+c+"_index` (`"+c+"` ASC)")
+	}
+	return "CREATE TABLE IF NOT EXISTS `" + tblName + "`(" + strings.Join(defs, ", ") +
+		") ENGINE = InnoDB DEFAULT CHARACTER SET = utf8 COMMENT = 'nested sets model';\n"
+}
+
+// schemaStmts creates the table of -with-schema and its indexes before the inserts, each statement in one line,
+// which the self-check skips like other statements around inserts. ClickHouse rows are ordered by lft, so
+// subtrees are read as ranges of the sorting key, and DuckDB scans ranges fast without indexes, which would
+// keep upserts from updating the keys. Indexes of the other databases but MySQL are named after the table, as
+// their names are not kept per table. Oracle has no IF NOT EXISTS, so that a rerun reports the table
+// exists, which sqlplus goes on after.
+func schemaStmts() string {
+	if dialect == "mysql" {
+		return mysqlSchema()
+	}
+	defs := []string{
+		columnDef("id", "BIGINT", 0, false),
+		columnDef("node", "VARCHAR", 64, false),
+		columnDef("pid", "BIGINT", 0, false),
+		columnDef("depth", "INT", 0, false),
+		columnDef("lft", "INT", 0, false),
+		columnDef("rgt", "INT", 0, false),
+	}
+	for _, c := range columns {
+		t, size := mysqlType(c)
+		defs = append(defs, columnDef(c.name, t, size, c.null))
+	}
+	create := "CREATE TABLE IF NOT EXISTS "
+	switch dialect {
+	case "clickhouse":
+		return create + tableRef() + "(" + strings.Join(defs, ", ") + ") ENGINE = MergeTree ORDER BY " + quoteIdent("lft") + ";\n"
+	case "mssql":
+		defs = append(defs, "PRIMARY KEY ("+quoteIdent("id")+")")
+		for _, c := range tableIndexes() {
+			defs = append(defs, "INDEX "+quoteIdent(tblName+"_"+c+"_index")+" ("+quoteIdent(c)+")")
+		}
+		return "IF OBJECT_ID(N'" + strings.Replace(tableRef(), "'", "''", -1) + "', N'U') IS NULL CREATE TABLE " + tableRef() +
+			"(" + strings.Join(defs, ", ") + ");\nGO\n"
+	case "oracle":
+		create = "CREATE TABLE "
+	}
+	defs = append(defs, "PRIMARY KEY ("+quoteIdent("id")+")")
+	stmts := create + tableRef() + "(" + strings.Join(defs, ", ") + ");\n"
+	if dialect == "duckdb" {
+		return stmts
+	}
+	for _, c := range tableIndexes() {
+		index := "CREATE INDEX IF NOT EXISTS "
+		if dialect == "oracle" {
+			index = "CREATE INDEX "
+		}
+		stmts += index + indexRef(tblName+"_"+c+"_index") + " ON " + tableRef() + " (" + indexKey(c) + ");\n"
+	}
+	return stmts
+}
+
+// indexRef names an index, in the schema of the table for Oracle, whose indexes are created in the schema of
+// the user otherwise. PostgreSQL and DuckDB create indexes in the schema of the table and take no schema there.
+func indexRef(name string) string {
+	if dialect == "oracle" && dbSchema != "" {
+		return quoteIdent(dbSchema) + "." + quoteIdent(name)
+	}
+	return quoteIdent(name)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestWithSchema(t *testing.T) {
+	cases := []struct {
+		dialect string
+		want    []string
+	}{
+		{"mysql", []string{
+			"CREATE TABLE IF NOT EXISTS `nested`(`id` BIGINT NOT NULL COMMENT 'node ID', `node` VARCHAR(64) CHARACTER SET 'utf8' NOT NULL COMMENT 'node name', " +
+				"`pid` BIGINT NOT NULL COMMENT 'parent ID', `depth` INT NOT NULL COMMENT 'Level', `lft` INT NOT NULL COMMENT 'left index', " +
+				"`rgt` INT NOT NULL COMMENT 'right index', `initial` CHAR(1) NOT NULL DEFAULT '' COMMENT 'first letter of pinyin', " +
+				"`lng` DOUBLE NULL COMMENT 'longitude of centroid', PRIMARY KEY (`id`), INDEX `depth_index` (`depth` ASC), " +
+				"INDEX `lft_index` (`lft` ASC), INDEX `rgt_index` (`rgt` ASC)) ENGINE = InnoDB DEFAULT CHARACTER SET = utf8 COMMENT = 'nested sets model';",
+		}},
+		{"postgres", []string{
+			`CREATE TABLE IF NOT EXISTS "nested"("id" BIGINT NOT NULL, "node" VARCHAR(64) NOT NULL, "pid" BIGINT NOT NULL, "depth" INTEGER NOT NULL, ` +
+				`"lft" INTEGER NOT NULL, "rgt" INTEGER NOT NULL, "initial" VARCHAR(1) NOT NULL, "lng" DOUBLE PRECISION, PRIMARY KEY ("id"));`,
+			`CREATE INDEX IF NOT EXISTS "nested_depth_index" ON "nested" ("depth");`,
+			`CREATE INDEX IF NOT EXISTS "nested_lft_index" ON "nested" ("lft");`,
+			`CREATE INDEX IF NOT EXISTS "nested_rgt_index" ON "nested" ("rgt");`,
+		}},
+		{"mssql", []string{
+			"IF OBJECT_ID(N'[nested]', N'U') IS NULL CREATE TABLE [nested]([id] BIGINT NOT NULL, [node] NVARCHAR(64) NOT NULL, [pid] BIGINT NOT NULL, " +
+				"[depth] INT NOT NULL, [lft] INT NOT NULL, [rgt] INT NOT NULL, [initial] NVARCHAR(1) NOT NULL, [lng] FLOAT, PRIMARY KEY ([id]), " +
+				"INDEX [nested_depth_index] ([depth]), INDEX [nested_lft_index] ([lft]), INDEX [nested_rgt_index] ([rgt]));",
+			"GO",
+		}},
+	}
+	for _, c := range cases {
+		out := usePaths(t, "./testdata/mini")
+		var stderr bytes.Buffer
+		if code := run([]string{"-dialect", c.dialect, "-with-schema", "-columns", "initial,lng"}, &stderr); code != exitOK {
+			t.Fatal(c.dialect, "exit code:", code, stderr.String())
+		}
+		data, err := ioutil.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(string(data), "\n")
+		for i, want := range c.want {
+			if lines[i] != want {
+				t.Errorf("%s line %d: %s", c.dialect, i+1, lines[i])
+			}
+		}
+		if !strings.HasPrefix(lines[len(c.want)], "INSERT INTO ") {
+			t.Error(c.dialect, "no inserts after the schema:", lines[len(c.want)])
+		}
+	}
+}
+
+func TestOracleSchema(t *testing.T) {
+	defer func(d, s string) { dialect, dbSchema = d, s }(dialect, dbSchema)
+	dialect, dbSchema = "oracle", "geo"
+	stmts := strings.Split(schemaStmts(), "\n")
+	if !strings.HasPrefix(stmts[0], `CREATE TABLE "geo"."nested"("id" NUMBER(19) NOT NULL, "node" NVARCHAR2(64) NOT NULL, `) ||
+		stmts[1] != `CREATE INDEX "geo"."nested_depth_index" ON "geo"."nested" ("depth");` {
+		t.Error(stmts)
+	}
+}
+
+func TestIDPathIndex(t *testing.T) {
+	defer func(d string, c []column) { dialect, columns = d, c }(dialect, columns)
+	columns = addColumn(nil, "id_path")
+	dialect = "postgres"
+	if stmts := schemaStmts(); !strings.Contains(stmts, `CREATE INDEX IF NOT EXISTS "nested_id_path_index" ON "nested" ("id_path" varchar_pattern_ops);`+"\n") {
+		t.Error(stmts)
+	}
+	dialect = "mysql"
+	if stmts := schemaStmts(); !strings.Contains(stmts, "INDEX `rgt_index` (`rgt` ASC), INDEX `id_path_index` (`id_path` ASC))") {
+		t.Error(stmts)
+	}
+	if len(indexedColumns) != 3 {
+		t.Error(indexedColumns)
+	}
+}
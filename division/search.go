@@ -0,0 +1,162 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ranks of matches of a search term, lower is better
+const (
+	rankName         = iota // 广西壮族自治区
+	rankShortName           // 广西, the name without its suffix
+	rankAlias               // 桂, the abbreviation of a province
+	rankPrefix              // 广西壮
+	rankSameShort           // 广西省, another suffix of the same short name
+	rankContains            // 壮族
+	rankPinyin              // guangxi or gx, the pinyin without spaces or its initials, of the name or short name
+	rankPinyinPrefix        // guang
+)
+
+// searchKey is what a node is matched by, computed for all nodes at the first search
+type searchKey struct {
+	name, short, alias string
+	pinyin, initials   []string // of the name and of the short name
+}
+
+// searchResult is a node found by a search with the rank of its match, the sum of the ranks of the terms
+type searchResult struct {
+	path []*Area
+	rank int
+}
+
+// rank matches a search term against a node, -1 if it does not match
+func (k *searchKey) rank(term string) int {
+	switch {
+	case term == k.name:
+		return rankName
+	case term == k.short:
+		return rankShortName
+	case term == k.alias:
+		return rankAlias
+	case strings.HasPrefix(k.name, term):
+		return rankPrefix
+	case shortName(term) == k.short:
+		return rankSameShort
+	case strings.Contains(k.name, term):
+		return rankContains
+	}
+	lower := strings.ToLower(term)
+	for _, py := range append(k.pinyin, k.initials...) {
+		if lower == py {
+			return rankPinyin
+		}
+	}
+	for _, py := range append(k.pinyin, k.initials...) {
+		if strings.HasPrefix(py, lower) {
+			return rankPinyinPrefix
+		}
+	}
+	return -1
+}
+
+// indexKeys computes the search keys of all nodes
+func (x *areaIndex) indexKeys() {
+	x.keys = make(map[*Area]*searchKey, len(x.order))
+	for _, a := range x.order {
+		name := nodeName(a)
+		k := &searchKey{name: name, short: shortName(name)}
+		for _, n := range []string{name, k.short} {
+			k.pinyin = append(k.pinyin, strings.Replace(namePinyin(n), " ", "", -1))
+			k.initials = append(k.initials, pinyinInitials(n))
+		}
+		if x.parents[a] == nil {
+			k.alias = provinceAbbrs[a.Code]
+		}
+		x.keys[a] = k
+	}
+}
+
+// Search finds nodes matching user input, tolerating partial names, missing or other suffixes, abbreviations of
+// provinces and pinyin. Terms separated by spaces narrow the matches of the last term to those below matches
+// of the terms before it, in order, e.g. "广东 南山". Results are ranked by how well the terms match, then by
+// depth, then in preorder, and cut to limit. Searches may run concurrently.
+func (x *areaIndex) Search(query string, limit int) []searchResult {
+	terms := strings.Fields(query)
+	if len(terms) == 0 {
+		return nil
+	}
+	x.keysOnce.Do(x.indexKeys)
+
+	var results []searchResult
+	for _, a := range x.order {
+		rank := x.keys[a].rank(terms[len(terms)-1])
+		if rank < 0 {
+			continue
+		}
+		path := x.Path(a)
+		// earlier terms match ancestors, the nearest first
+		above := len(path) - 1
+		for i := len(terms) - 2; i >= 0 && rank >= 0; i-- {
+			r := -1
+			for above > 0 && r < 0 {
+				above--
+				r = x.keys[path[above]].rank(terms[i])
+			}
+			if r < 0 {
+				rank = -1
+			} else {
+				rank += r
+			}
+		}
+		if rank >= 0 {
+			results = append(results, searchResult{path, rank})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].rank != results[j].rank {
+			return results[i].rank < results[j].rank
+		}
+		return len(results[i].path) < len(results[j].path)
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// runSearch prints the nodes matching a query, best first, as code and full name separated by a tab
+func runSearch(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("division search", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	from := fs.String("from", dataDir, "data `directory` or generated sql file")
+	limit := fs.Int("limit", 10, "candidates to print at most, 0 for all")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: division search [-from dir|file] [-limit n] term...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 || *limit < 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	trees, err := loadTrees(*from)
+	if err != nil {
+		fmt.Fprintln(stderr, "division search:", err)
+		return exitCode(err)
+	}
+	results := newAreaIndex(trees).Search(strings.Join(fs.Args(), " "), *limit)
+	if len(results) == 0 {
+		fmt.Fprintf(stderr, "division search: nothing matches %q\n", strings.Join(fs.Args(), " "))
+		return exitData
+	}
+	for _, r := range results {
+		fmt.Fprintf(stdout, "%s\t%s\n", r.path[len(r.path)-1].Code, fullName(r.path, nodeName))
+	}
+	return exitOK
+}
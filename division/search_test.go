@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	trees, err := loadTrees("./testdata/mini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := newAreaIndex(trees)
+	for query, want := range map[string]string{
+		"北京市":     "110000",
+		"北京":      "110000",
+		"北京省":     "110000",
+		"冀":       "130000",
+		"东城":      "110101",
+		"东华门街道":   "110101001000",
+		"河北 长安":   "130102",
+		"changan": "130102",
+		"dcq":     "110101",
+		"BJ":      "110000",
+		"街道":      "110101001000,110101002000,130102001000",
+		"北京 街道":   "110101001000,110101002000",
+		"北京 长安":   "",
+		"上海":      "",
+	} {
+		var codes []string
+		for _, r := range x.Search(query, 0) {
+			codes = append(codes, r.path[len(r.path)-1].Code)
+		}
+		if got := strings.Join(codes, ","); got != want {
+			t.Errorf("%s: %s, want %s", query, got, want)
+		}
+	}
+	if results := x.Search("街道", 2); len(results) != 2 {
+		t.Error("results beyond the limit:", len(results))
+	}
+
+	out := useStdout(t)
+	var stderr bytes.Buffer
+	if code := run([]string{"search", "-from", "./testdata/mini", "-limit", "1", "东城"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	if out.String() != "110101\t北京市市辖区东城区\n" {
+		t.Error(out.String())
+	}
+	if code := run([]string{"search", "-from", "./testdata/mini", "上海"}, &stderr); code != exitData {
+		t.Error("exit code of no match:", code)
+	}
+	if code := run([]string{"search", "-from", "./testdata/mini"}, &stderr); code != exitUsage {
+		t.Error("exit code without terms:", code)
+	}
+}
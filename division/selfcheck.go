@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// checkSQLFile parses a generated sql file back into trees and compares them with the trees it was generated from
+func checkSQLFile(name string, trees []*Area) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	stmts, err := parseSQL(f)
+	if err != nil {
+		return internalErrorf("self-check: %v", err)
+	}
+	parsed, err := treeFromSQL(stmts)
+	if err != nil {
+		return internalErrorf("self-check: %v", err)
+	}
+	err = compareTrees(trees, parsed)
+	if err != nil {
+		return internalErrorf("self-check: %v", err)
+	}
+	return nil
+}
+
+// treeFromSQL rebuilds trees from parsed insert statements in preorder, the parent of each node is the last
+// node inserted one level above it. Ids are not required to be unique.
+func treeFromSQL(stmts []insertStmt) ([]*Area, error) {
+	var trees []*Area
+	var path []*Area // last node inserted at each depth
+	for _, stmt := range stmts {
+		index := make(map[string]int, len(stmt.columns))
+		for i, c := range stmt.columns {
+			index[c] = i
+		}
+		for _, col := range []string{"id", "node", "pid", "depth", "lft", "rgt"} {
+			if _, ok := index[col]; !ok {
+				return nil, dataErrorf("line %d: column %s missing", stmt.line, col)
+			}
+		}
+
+		for _, v := range stmt.values {
+			depth, err1 := strconv.Atoi(v[index["depth"]])
+			left, err2 := strconv.ParseInt(v[index["lft"]], 10, 32)
+			right, err3 := strconv.ParseInt(v[index["rgt"]], 10, 32)
+			if err1 != nil || err2 != nil || err3 != nil {
+				return nil, dataErrorf("line %d: depth, lft and rgt must be integers", stmt.line)
+			}
+			area := &Area{
+				Code:       v[index["id"]],
+				Name:       v[index["node"]],
+				ParentCode: v[index["pid"]],
+				Left:       int32(left),
+				Right:      int32(right),
+			}
+			if depth < 1 || depth > len(path)+1 {
+				return nil, dataErrorf("line %d: %s at depth %d after a node at depth %d", stmt.line, area.Code, depth, len(path))
+			}
+
+			path = append(path[:depth-1], area)
+			if depth == 1 {
+				if area.ParentCode != "0" {
+					return nil, dataErrorf("line %d: root %s with pid %s", stmt.line, area.Code, area.ParentCode)
+				}
+				trees = append(trees, area)
+				continue
+			}
+			parent := path[depth-2]
+			if area.ParentCode != parent.Code {
+				return nil, dataErrorf("line %d: %s with pid %s follows %s", stmt.line, area.Code, area.ParentCode, parent.Code)
+			}
+			parent.SubAreas = append(parent.SubAreas, area)
+		}
+	}
+	return trees, nil
+}
+
+// compareTrees reports the first node which differs between want and got in preorder.
+// Names of want are compared as written to the sql file.
+func compareTrees(want, got []*Area) error {
+	for i := range want {
+		if i >= len(got) {
+			return fmt.Errorf("node %s missing", want[i].Code)
+		}
+		w, g := want[i], got[i]
+		switch {
+		case w.Code != g.Code:
+			return fmt.Errorf("node %s expected, got %s", w.Code, g.Code)
+		case nodeName(w) != g.Name:
+			return fmt.Errorf("node %s: name %q expected, got %q", w.Code, nodeName(w), g.Name)
+		case w.ParentCode != g.ParentCode:
+			return fmt.Errorf("node %s: pid %s expected, got %s", w.Code, w.ParentCode, g.ParentCode)
+		case w.Left != g.Left || w.Right != g.Right:
+			return fmt.Errorf("node %s: keys [%d, %d] expected, got [%d, %d]", w.Code, w.Left, w.Right, g.Left, g.Right)
+		}
+		err := compareTrees(w.SubAreas, g.SubAreas)
+		if err != nil {
+			return err
+		}
+	}
+	if len(got) > len(want) {
+		return fmt.Errorf("unexpected node %s", got[len(want)].Code)
+	}
+	return nil
+}
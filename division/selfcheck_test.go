@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// loadFixture builds keyed trees from a fixture directory
+func loadFixture(t *testing.T, dir string) []*Area {
+	usePaths(t, dir)
+	if err := loadAddress(); err != nil {
+		t.Fatal(err)
+	}
+	trees, err := buildTrees()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assignKeys(trees)
+	return trees
+}
+
+func TestSelfCheck(t *testing.T) {
+	trees := loadFixture(t, "./testdata/mini")
+	var sql bytes.Buffer
+	for _, p := range trees {
+		if err := genSQL(&sql, []*Area{p}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	name := filepath.Join(t.TempDir(), "division.sql")
+	if err := ioutil.WriteFile(name, sql.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkSQLFile(name, trees); err != nil {
+		t.Fatal(err)
+	}
+
+	trees[1].SubAreas[0].SubAreas[0].Right++
+	err := checkSQLFile(name, trees)
+	if err == nil || exitCode(err) != exitInternal || !strings.Contains(err.Error(), "node 130102: keys") {
+		t.Error(err)
+	}
+}
+
+func TestTreeFromSQLErrors(t *testing.T) {
+	for _, sql := range []string{
+		"INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(2, 'b', 1, 2, 2, 3);",
+		"INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(1, 'a', 0, 2, 1, 2);",
+		"INSERT INTO nested(id, node, pid, depth, lft) VALUES(1, 'a', 0, 1, 1);",
+	} {
+		stmts, err := parseSQL(strings.NewReader(sql))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = treeFromSQL(stmts); err == nil {
+			t.Error("no error:", sql)
+		}
+	}
+}
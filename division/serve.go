@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// divisionJSON is a node as the server returns it
+type divisionJSON struct {
+	Code       string          `json:"code"`
+	Name       string          `json:"name"`
+	FullName   string          `json:"full_name"`
+	ParentCode string          `json:"parent_code,omitempty"`
+	Depth      int             `json:"depth"`
+	Lft        int32           `json:"lft"`
+	Rgt        int32           `json:"rgt"`
+	Children   int             `json:"children"`
+	Initial    string          `json:"initial,omitempty"`
+	Ancestors  []*divisionJSON `json:"ancestors,omitempty"`
+	Rank       *int            `json:"rank,omitempty"`
+}
+
+func newDivisionJSON(path []*Area) *divisionJSON {
+	a := path[len(path)-1]
+	d := &divisionJSON{Code: a.Code, Name: nodeName(a), FullName: fullName(path, nodeName), Depth: len(path), Lft: a.Left,
+		Rgt: a.Right, Children: len(a.SubAreas)}
+	if len(path) > 1 {
+		d.ParentCode = path[len(path)-2].Code
+	}
+	return d
+}
+
+// newDivisionJSONAncestors is the node at the end of path with its ancestors from the root down
+func newDivisionJSONAncestors(path []*Area) *divisionJSON {
+	d := newDivisionJSON(path)
+	for i := range path[:len(path)-1] {
+		d.Ancestors = append(d.Ancestors, newDivisionJSON(path[:i+1]))
+	}
+	return d
+}
+
+// divisionServer answers REST requests from the index of the trees, which is read only once built, GraphQL
+// queries at /graphql if graphql is set, and points at /locate from the boundaries of locator if it is not nil.
+// Children have their initial with the initial column.
+type divisionServer struct {
+	index   *areaIndex
+	graphql bool
+	locator *locator
+	columns []column
+}
+
+func (s *divisionServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.graphql && strings.Trim(r.URL.Path, "/") == "graphql" {
+		s.serveGraphQL(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeJSONError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+		return
+	}
+	switch p := strings.Trim(r.URL.Path, "/"); {
+	case p == "divisions":
+		list := make([]*divisionJSON, len(s.index.roots))
+		for i, a := range s.index.roots {
+			list[i] = newDivisionJSON([]*Area{a})
+		}
+		writeJSON(w, list)
+	case strings.HasPrefix(p, "divisions/"):
+		parts := strings.Split(strings.TrimPrefix(p, "divisions/"), "/")
+		a := s.index.ByCode(parts[0])
+		switch {
+		case len(parts) > 2 || len(parts) == 2 && parts[1] != "children":
+			writeJSONError(w, http.StatusNotFound, "no such resource %s", r.URL.Path)
+		case a == nil:
+			writeJSONError(w, http.StatusNotFound, "division %s not found", parts[0])
+		case len(parts) == 2:
+			path := s.index.Path(a)
+			list := make([]*divisionJSON, len(a.SubAreas))
+			for i, sub := range a.SubAreas {
+				list[i] = newDivisionJSON(append(path[:len(path):len(path)], sub))
+				if hasColumn(s.columns, "initial") {
+					list[i].Initial = initial(nodeName(sub))
+				}
+			}
+			writeJSON(w, list)
+		default:
+			writeJSON(w, newDivisionJSONAncestors(s.index.Path(a)))
+		}
+	case p == "search":
+		q := r.URL.Query().Get("q")
+		limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		if r.URL.Query().Get("limit") == "" {
+			limit, err = 10, nil
+		}
+		if strings.TrimSpace(q) == "" || err != nil || limit < 0 {
+			writeJSONError(w, http.StatusBadRequest, "q of the terms and an optional limit of 0 or more expected")
+			return
+		}
+		list := []*divisionJSON{}
+		for _, result := range s.index.Search(q, limit) {
+			d := newDivisionJSON(result.path)
+			d.Rank = &result.rank
+			list = append(list, d)
+		}
+		writeJSON(w, list)
+	case p == "locate":
+		lng, err1 := strconv.ParseFloat(r.URL.Query().Get("lng"), 64)
+		lat, err2 := strconv.ParseFloat(r.URL.Query().Get("lat"), 64)
+		switch {
+		case s.locator == nil:
+			writeJSONError(w, http.StatusNotFound, "no boundaries to locate points in, serve with -boundaries")
+		case err1 != nil || err2 != nil || lng < -180 || lng > 180 || lat < -90 || lat > 90:
+			writeJSONError(w, http.StatusBadRequest, "lng and lat of a point expected")
+		default:
+			path := s.locator.locate(lng, lat)
+			if path == nil {
+				writeJSONError(w, http.StatusNotFound, "no division contains %v,%v", lng, lat)
+				return
+			}
+			writeJSON(w, newDivisionJSONAncestors(path))
+		}
+	default:
+		writeJSONError(w, http.StatusNotFound, "no such resource %s", r.URL.Path)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(map[string]string{"error": fmt.Sprintf(format, args...)})
+}
+
+// runServe loads the trees and serves them over HTTP until the process is stopped
+func runServe(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("division serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	from := fs.String("from", dataDir, "data `directory` or generated sql file")
+	addr := fs.String("addr", "localhost:8080", "`address` to listen on")
+	graphql := fs.Bool("graphql", false, "also answer GraphQL queries at /graphql")
+	bounds := fs.String("boundaries", "", "GeoJSON `file or directory` of boundaries with codes in properties, to answer /locate from")
+	list := fs.String("columns", "", "comma separated optional columns of children: initial")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: division serve [-from dir|file] [-addr host:port] [-graphql] [-boundaries file|dir] [-columns initial]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	cols, err := parseColumns(*list)
+	if err == nil {
+		for _, c := range cols {
+			if c.name != "initial" {
+				err = fmt.Errorf("column %s is not served, only initial", c.name)
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "division serve:", err)
+		return exitUsage
+	}
+
+	trees, err := loadTrees(*from)
+	if err != nil {
+		fmt.Fprintln(stderr, "division serve:", err)
+		return exitCode(err)
+	}
+	handler := &divisionServer{index: newAreaIndex(trees), graphql: *graphql, columns: cols}
+	if *bounds != "" {
+		if handler.locator, err = loadLocator(trees, *bounds); err != nil {
+			fmt.Fprintln(stderr, "division serve:", err)
+			return exitCode(err)
+		}
+	}
+	server := &http.Server{Addr: *addr, Handler: handler,
+		ReadHeaderTimeout: 10 * time.Second}
+	logger.Info("serving", "addr", *addr, "from", *from, "graphql", *graphql)
+	if err := server.ListenAndServe(); err != nil {
+		fmt.Fprintln(stderr, "division serve:", err)
+		return exitIO
+	}
+	return exitOK
+}
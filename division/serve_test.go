@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServe(t *testing.T) {
+	trees, err := loadTrees("./testdata/mini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(&divisionServer{index: newAreaIndex(trees)})
+	defer server.Close()
+
+	get := func(path string, status int, v interface{}) {
+		t.Helper()
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("%s: %s", path, resp.Status)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Errorf("%s: %v", path, err)
+		}
+	}
+
+	var d divisionJSON
+	get("/divisions/110101", http.StatusOK, &d)
+	if d.Name != "东城区" || d.FullName != "北京市市辖区东城区" || d.ParentCode != "110100" || d.Depth != 3 || d.Lft != 3 ||
+		d.Rgt != 8 || d.Children != 2 || len(d.Ancestors) != 2 || d.Ancestors[0].Code != "110000" || d.Ancestors[1].Name != "市辖区" {
+		t.Errorf("%+v", d)
+	}
+
+	var list []divisionJSON
+	get("/divisions", http.StatusOK, &list)
+	if len(list) != 2 || list[0].Code != "110000" || list[1].Code != "130000" || list[1].ParentCode != "" {
+		t.Errorf("%+v", list)
+	}
+	get("/divisions/110101/children", http.StatusOK, &list)
+	if len(list) != 2 || list[0].Code != "110101001000" || list[1].FullName != "北京市市辖区东城区景山街道办事处" ||
+		list[1].ParentCode != "110101" || list[1].Depth != 4 {
+		t.Errorf("%+v", list)
+	}
+	if list[0].Initial != "" {
+		t.Error("initial without the column:", list[0].Initial)
+	}
+	get("/search?q="+strings.Replace("河北 长安", " ", "+", -1), http.StatusOK, &list)
+	if len(list) != 1 || list[0].Code != "130102" || list[0].Rank == nil {
+		t.Errorf("%+v", list)
+	}
+	get("/search?q=%E8%A1%97%E9%81%93&limit=2", http.StatusOK, &list) // 街道
+	if len(list) != 2 {
+		t.Errorf("%+v", list)
+	}
+	get("/search?q=shanghai", http.StatusOK, &list)
+	if list == nil || len(list) != 0 {
+		t.Errorf("%+v", list)
+	}
+
+	var e map[string]string
+	for path, status := range map[string]int{
+		"/divisions/120000":            http.StatusNotFound,
+		"/divisions/110101/parent":     http.StatusNotFound,
+		"/divisions/110101/children/1": http.StatusNotFound,
+		"/regions":                     http.StatusNotFound,
+		"/search":                      http.StatusBadRequest,
+		"/search?q=x&limit=-1":         http.StatusBadRequest,
+		"/locate?lng=13&lat=2":         http.StatusNotFound, // served without -boundaries
+	} {
+		e = nil
+		get(path, status, &e)
+		if e["error"] == "" {
+			t.Error(path, "without error")
+		}
+	}
+	resp, err := http.Post(server.URL+"/divisions", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Error("POST:", resp.Status)
+	}
+}
+
+func TestServeLocate(t *testing.T) {
+	trees, err := loadTrees("./testdata/mini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := loadLocator(trees, "./testdata/locate/boundaries.geojson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(&divisionServer{index: newAreaIndex(trees), locator: l})
+	defer server.Close()
+
+	for path, want := range map[string]string{
+		"/locate?lng=13&lat=2":    "130102 130000 130100",
+		"/locate?lat=3.2&lng=3.5": "110101 110000 110100",
+		"/locate?lng=10&lat=0":    "110000", // on the border of 110000 and 130000
+	} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var d divisionJSON
+		err = json.NewDecoder(resp.Body).Decode(&d)
+		resp.Body.Close()
+		if err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatal(path, resp.Status, err)
+		}
+		codes := []string{d.Code}
+		for _, a := range d.Ancestors {
+			codes = append(codes, a.Code)
+		}
+		if got := strings.Join(codes, " "); got != want {
+			t.Errorf("%s: %s, want %s", path, got, want)
+		}
+	}
+	for path, status := range map[string]int{
+		"/locate?lng=30&lat=30": http.StatusNotFound,
+		"/locate?lng=13":        http.StatusBadRequest,
+		"/locate?lng=x&lat=2":   http.StatusBadRequest,
+		"/locate?lng=13&lat=91": http.StatusBadRequest,
+	} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var e map[string]string
+		json.NewDecoder(resp.Body).Decode(&e)
+		resp.Body.Close()
+		if resp.StatusCode != status || e["error"] == "" {
+			t.Error(path, resp.Status, e)
+		}
+	}
+}
+
+func TestServeInitial(t *testing.T) {
+	trees, err := loadTrees("./testdata/mini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cols, err := parseColumns("initial")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(&divisionServer{index: newAreaIndex(trees), columns: cols})
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/divisions/110101/children")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var list []divisionJSON
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].Initial != "D" || list[1].Initial != "J" {
+		t.Errorf("%+v", list)
+	}
+
+	var stderr bytes.Buffer
+	if code := run([]string{"serve", "-from", "./testdata/mini", "-columns", "pinyin"}, &stderr); code != exitUsage ||
+		!strings.Contains(stderr.String(), "only initial") {
+		t.Error("exit code of a column not served:", code, stderr.String())
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// suffixRules are administrative suffixes stripped for short names, the first matching one applies so longer
+// suffixes come first. Ethnic names before autonomous suffixes are stripped too, e.g. 广西壮族自治区 → 广西.
+var suffixRules = []struct {
+	suffix     string
+	autonomous bool
+}{
+	{"特别行政区", false},
+	{"街道办事处", false},
+	{"自治区", true},
+	{"自治州", true},
+	{"自治县", true},
+	{"自治旗", true},
+	{"街道办", false},
+	{"办事处", false},
+	{"街道", false},
+	{"地区", false},
+	{"新区", false},
+	{"林区", false},
+	{"苏木", false},
+	{"省", false},
+	{"市", false},
+	{"区", false},
+	{"县", false},
+	{"盟", false},
+	{"旗", false},
+	{"镇", false},
+	{"乡", false},
+}
+
+// protectedSuffixes are kept, stripping them would leave a name which is not a place, e.g. 经济技术开发区
+var protectedSuffixes = []string{
+	"市辖区", "行政区划", "开发区", "工业园区", "产业园区", "科技园区", "物流园区", "管理区", "工业区", "示范区", "试验区", "实验区",
+	"风景区", "旅游区", "保护区", "矿区", "垦区",
+}
+
+// ethnicNames are stripped before autonomous suffixes, and with 族 before any suffix, e.g. 民族乡
+var ethnicNames = []string{
+	"蒙古", "回", "藏", "维吾尔", "苗", "彝", "壮", "布依", "朝鲜", "满", "侗", "瑶", "白", "土家", "哈尼", "哈萨克",
+	"傣", "黎", "傈僳", "佤", "畲", "高山", "拉祜", "水", "东乡", "纳西", "景颇", "柯尔克孜", "土", "达斡尔", "仫佬",
+	"羌", "布朗", "撒拉", "毛南", "仡佬", "锡伯", "阿昌", "普米", "塔吉克", "怒", "乌孜别克", "俄罗斯", "鄂温克", "德昂",
+	"保安", "裕固", "京", "塔塔尔", "独龙", "鄂伦春", "赫哲", "门巴", "珞巴", "基诺", "汉", "民", "各",
+}
+
+// shortName strips the administrative suffix of name, e.g. 北京市 → 北京, 恩施土家族苗族自治州 → 恩施.
+// A rule which would leave less than two characters is skipped for the next matching one, e.g. 西林区 → 西林
+// rather than 西 for 林区, and names without such rule are kept, e.g. 沙县.
+func shortName(name string) string {
+	for _, p := range protectedSuffixes {
+		if strings.HasSuffix(name, p) {
+			return name
+		}
+	}
+	for _, r := range suffixRules {
+		if !strings.HasSuffix(name, r.suffix) {
+			continue
+		}
+		short := stripEthnic(strings.TrimSuffix(name, r.suffix), r.autonomous)
+		if utf8.RuneCountInString(short) >= 2 {
+			return short
+		}
+	}
+	return name
+}
+
+// stripEthnic strips trailing ethnic names, e.g. 恩施土家族苗族 → 恩施. Names without 族 are stripped
+// only before autonomous suffixes, e.g. 新疆维吾尔自治区.
+func stripEthnic(name string, autonomous bool) string {
+	name, _ = splitEthnic(name, autonomous)
+	return name
+}
+
+// splitEthnic is stripEthnic which also returns the stripped ethnic names in order, e.g. 土家 and 苗
+func splitEthnic(name string, autonomous bool) (string, []string) {
+	var groups []string
+	for {
+		stripped, group := name, ""
+		for _, e := range ethnicNames {
+			if strings.HasSuffix(name, e+"族") {
+				stripped, group = strings.TrimSuffix(name, e+"族"), e
+				break
+			}
+			if autonomous && len(e) > 3 && strings.HasSuffix(name, e) {
+				stripped, group = strings.TrimSuffix(name, e), e
+				break
+			}
+		}
+		if stripped == name || utf8.RuneCountInString(stripped) < 2 {
+			return name, groups
+		}
+		name = stripped
+		groups = append([]string{group}, groups...)
+	}
+}
+
+// shortenNames assigns short names, siblings whose short names would collide keep their full names
+func shortenNames(areas []*Area) {
+	counts := make(map[string]int, len(areas))
+	for _, a := range areas {
+		a.ShortName = shortName(nodeName(a))
+		counts[a.ShortName]++
+	}
+	for _, a := range areas {
+		if counts[a.ShortName] > 1 {
+			a.ShortName = nodeName(a)
+		}
+		shortenNames(a.SubAreas)
+	}
+}
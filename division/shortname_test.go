@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestShortName(t *testing.T) {
+	for name, want := range map[string]string{
+		"北京市":        "北京",
+		"河北省":        "河北",
+		"内蒙古自治区":     "内蒙古",
+		"广西壮族自治区":    "广西",
+		"新疆维吾尔自治区":   "新疆",
+		"宁夏回族自治区":    "宁夏",
+		"香港特别行政区":    "香港",
+		"恩施土家族苗族自治州": "恩施",
+		"巴音郭楞蒙古自治州":  "巴音郭楞",
+		"伊犁哈萨克自治州":   "伊犁",
+		"大兴安岭地区":     "大兴安岭",
+		"锡林郭勒盟":      "锡林郭勒",
+		"市辖区":        "市辖区",
+		"县":          "县",
+		"省直辖县级行政区划":  "省直辖县级行政区划",
+		"朝阳区":        "朝阳",
+		"浦东新区":       "浦东",
+		"沙县":         "沙县",
+		"城区":         "城区",
+		"长阳土家族自治县":   "长阳",
+		"达尔罕茂明安联合旗":  "达尔罕茂明安联合",
+		"东华门街道办事处":   "东华门",
+		"太平镇":        "太平",
+		"石门彝族乡":      "石门",
+		"城关回族区":      "城关",
+		"经济技术开发区":    "经济技术开发区",
+		"白云矿区":       "白云矿区",
+		"下花园区":       "下花园",
+		"西林区":        "西林",
+		"神农架林区":      "神农架",
+	} {
+		if s := shortName(name); s != want {
+			t.Error(name, s)
+		}
+	}
+}
+
+func TestShortenNamesCollision(t *testing.T) {
+	city := &Area{Code: "110100", Name: "某市", SubAreas: []*Area{
+		{Code: "110101", Name: "朝阳区"},
+		{Code: "110102", Name: "朝阳县"},
+		{Code: "110103", Name: "海淀区"},
+	}}
+	shortenNames([]*Area{city})
+	if city.ShortName != "某市" {
+		t.Error(city.ShortName)
+	}
+	for i, want := range []string{"朝阳区", "朝阳县", "海淀"} {
+		if s := city.SubAreas[i].ShortName; s != want {
+			t.Error(i, s)
+		}
+	}
+}
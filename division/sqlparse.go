@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// insertStmt is a parsed INSERT statement
+type insertStmt struct {
+	line    int
+	table   string
+	columns []string
+	values  [][]string // rows of unquoted values, NULL as ""
+}
+
+// parseSQL parses the INSERT statements of a generated sql file, one statement per line, and the COPY blocks
+// of -copy, each as a statement of its rows. Empty lines, comments and the statements around inserts of
+// isControlStmt are skipped.
+func parseSQL(r io.Reader) ([]insertStmt, error) {
+	var stmts []insertStmt
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "--") || isControlStmt(text) {
+			continue
+		}
+		if p := (&sqlScanner{s: text}); p.keyword("COPY") {
+			stmt, err := parseCopy(text)
+			if err != nil {
+				return nil, dataErrorf("line %d: %v", line, err)
+			}
+			stmt.line = line
+			for {
+				if !scanner.Scan() {
+					return nil, dataErrorf("line %d: COPY without %s", stmt.line, copyEnd)
+				}
+				line++
+				if scanner.Text() == copyEnd {
+					break
+				}
+				values := strings.Split(scanner.Text(), "\t")
+				if len(values) != len(stmt.columns) {
+					return nil, dataErrorf("line %d: %d values for %d columns", line, len(values), len(stmt.columns))
+				}
+				for i, v := range values {
+					values[i] = unescapeCopy(v)
+				}
+				stmt.values = append(stmt.values, values)
+			}
+			stmts = append(stmts, stmt)
+			continue
+		}
+		stmt, err := parseInsert(text)
+		if err != nil {
+			return nil, dataErrorf("line %d: %v", line, err)
+		}
+		stmt.line = line
+		stmts = append(stmts, stmt)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return stmts, nil
+}
+
+// controlKeywords start the statements around inserts, which the parser skips: pragmas, settings, one-line
+// DDL, statements emptying the table and transaction statements, as of SQLite seed scripts, sqlplus, goose
+// migrations and the -with-schema and -clean of the dialects
+var controlKeywords = []string{"PRAGMA", "SET", "CREATE", "DROP", "IF", "TRUNCATE", "DELETE", "BEGIN", "START", "COMMIT"}
+
+// isControlStmt tells statements around inserts, and the GO batch separators of SQL Server
+func isControlStmt(text string) bool {
+	for _, kw := range controlKeywords {
+		p := &sqlScanner{s: text}
+		if p.keyword(kw) {
+			return true
+		}
+	}
+	return strings.EqualFold(text, "GO")
+}
+
+// parseInsert parses `INSERT INTO table(col, ...) VALUES(v, ...), (v, ...);`
+func parseInsert(text string) (insertStmt, error) {
+	var stmt insertStmt
+	p := &sqlScanner{s: text}
+	if !p.keyword("INSERT") || !p.keyword("INTO") {
+		return stmt, p.errorf("INSERT INTO expected")
+	}
+	// names in double quotes or brackets tell the dialects whose literals take backslashes as they are
+	p.skipSpace()
+	p.plainBackslash = p.pos < len(p.s) && (p.s[p.pos] == '"' || p.s[p.pos] == '[')
+	stmt.table = p.ident()
+	if stmt.table == "" {
+		return stmt, p.errorf("table name expected")
+	}
+	if !p.char('(') {
+		return stmt, p.errorf("column list expected")
+	}
+	for {
+		col := p.ident()
+		if col == "" {
+			return stmt, p.errorf("column name expected")
+		}
+		stmt.columns = append(stmt.columns, col)
+		if p.char(')') {
+			break
+		}
+		if !p.char(',') {
+			return stmt, p.errorf("',' or ')' expected")
+		}
+	}
+	if !p.keyword("VALUES") {
+		return stmt, p.errorf("VALUES expected")
+	}
+	for {
+		values, err := p.tuple()
+		if err != nil {
+			return stmt, err
+		}
+		if len(values) != len(stmt.columns) {
+			return stmt, p.errorf("%d values for %d columns", len(values), len(stmt.columns))
+		}
+		stmt.values = append(stmt.values, values)
+		if !p.char(',') {
+			break
+		}
+	}
+	// conflict clauses of upserts leave the rows as they are
+	if p.keyword("ON") {
+		if !p.keyword("CONFLICT") && !p.keyword("DUPLICATE") {
+			return stmt, p.errorf("ON CONFLICT or ON DUPLICATE KEY expected")
+		}
+		p.pos = len(strings.TrimRight(p.s, "; \t"))
+	}
+	p.char(';')
+	if p.skipSpace(); p.pos < len(p.s) {
+		return stmt, p.errorf("unexpected %q", p.s[p.pos:])
+	}
+	return stmt, nil
+}
+
+// parseCopy parses `COPY table (col, ...) FROM stdin;`, the rows following it are read by parseSQL
+func parseCopy(text string) (insertStmt, error) {
+	var stmt insertStmt
+	p := &sqlScanner{s: text}
+	p.keyword("COPY")
+	stmt.table = p.ident()
+	if stmt.table == "" {
+		return stmt, p.errorf("table name expected")
+	}
+	if !p.char('(') {
+		return stmt, p.errorf("column list expected")
+	}
+	for {
+		col := p.ident()
+		if col == "" {
+			return stmt, p.errorf("column name expected")
+		}
+		stmt.columns = append(stmt.columns, col)
+		if p.char(')') {
+			break
+		}
+		if !p.char(',') {
+			return stmt, p.errorf("',' or ')' expected")
+		}
+	}
+	if !p.keyword("FROM") || !p.keyword("stdin") {
+		return stmt, p.errorf("FROM stdin expected")
+	}
+	p.char(';')
+	if p.skipSpace(); p.pos < len(p.s) {
+		return stmt, p.errorf("unexpected %q", p.s[p.pos:])
+	}
+	return stmt, nil
+}
+
+// unescapeCopy unquotes a value of the text format of COPY, with NULL as ""
+func unescapeCopy(v string) string {
+	if v == copyNull {
+		return ""
+	}
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		if v[i] == '\\' && i+1 < len(v) {
+			i++
+			b.WriteByte(unescape(v[i]))
+		} else {
+			b.WriteByte(v[i])
+		}
+	}
+	return b.String()
+}
+
+// sqlScanner reads tokens of a single statement
+type sqlScanner struct {
+	s              string
+	pos            int
+	plainBackslash bool // backslashes in literals are no escapes
+}
+
+func (p *sqlScanner) errorf(format string, args ...interface{}) error {
+	return dataErrorf("col %d: "+format, append([]interface{}{p.pos + 1}, args...)...)
+}
+
+func (p *sqlScanner) skipSpace() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *sqlScanner) char(c byte) bool {
+	p.skipSpace()
+	if p.pos < len(p.s) && p.s[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *sqlScanner) keyword(kw string) bool {
+	p.skipSpace()
+	if len(p.s)-p.pos >= len(kw) && strings.EqualFold(p.s[p.pos:p.pos+len(kw)], kw) {
+		p.pos += len(kw)
+		return true
+	}
+	return false
+}
+
+// ident reads an identifier, unquoting `x`, "x" and [x], and qualified ones like "schema"."table"
+func (p *sqlScanner) ident() string {
+	id := p.identPart()
+	for id != "" && p.pos < len(p.s) && p.s[p.pos] == '.' {
+		p.pos++
+		part := p.identPart()
+		if part == "" {
+			return ""
+		}
+		id += "." + part
+	}
+	return id
+}
+
+func (p *sqlScanner) identPart() string {
+	p.skipSpace()
+	if p.pos < len(p.s) && (p.s[p.pos] == '`' || p.s[p.pos] == '"' || p.s[p.pos] == '[') {
+		closing := p.s[p.pos]
+		if closing == '[' {
+			closing = ']'
+		}
+		end := strings.IndexByte(p.s[p.pos+1:], closing)
+		if end < 0 {
+			return ""
+		}
+		id := p.s[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return id
+	}
+	start := p.pos
+	for p.pos < len(p.s) && isIdentChar(p.s[p.pos]) {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// tuple reads `(v, ...)`
+func (p *sqlScanner) tuple() ([]string, error) {
+	if !p.char('(') {
+		return nil, p.errorf("'(' expected")
+	}
+	var values []string
+	for {
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+		if p.char(')') {
+			return values, nil
+		}
+		if !p.char(',') {
+			return nil, p.errorf("',' or ')' expected")
+		}
+	}
+}
+
+// value reads a number, NULL, a quoted string, with quotes escaped by doubling or by backslash unless
+// plainBackslash and an N in front for SQL Server, or a function call like ST_GeomFromGeoJSON('...'), which is
+// returned as written
+func (p *sqlScanner) value() (string, error) {
+	p.skipSpace()
+	if p.pos >= len(p.s) {
+		return "", p.errorf("value expected")
+	}
+	if (p.s[p.pos] == 'N' || p.s[p.pos] == 'n') && p.pos+1 < len(p.s) && p.s[p.pos+1] == '\'' {
+		p.pos++ // N'...' Unicode literal of SQL Server
+	}
+	if p.s[p.pos] != '\'' {
+		start := p.pos
+		for p.pos < len(p.s) && p.s[p.pos] != ',' && p.s[p.pos] != ')' && p.s[p.pos] != ' ' && p.s[p.pos] != '(' {
+			p.pos++
+		}
+		v := p.s[start:p.pos]
+		if v == "" {
+			return "", p.errorf("value expected")
+		}
+		if p.pos < len(p.s) && p.s[p.pos] == '(' {
+			if _, err := p.tuple(); err != nil {
+				return "", err
+			}
+			return p.s[start:p.pos], nil
+		}
+		if strings.EqualFold(v, "NULL") {
+			return "", nil
+		}
+		return v, nil
+	}
+
+	var b strings.Builder
+	for p.pos++; p.pos < len(p.s); p.pos++ {
+		c := p.s[p.pos]
+		switch {
+		case c == '\\' && !p.plainBackslash && p.pos+1 < len(p.s):
+			p.pos++
+			b.WriteByte(unescape(p.s[p.pos]))
+		case c == '\'' && p.pos+1 < len(p.s) && p.s[p.pos+1] == '\'':
+			p.pos++
+			b.WriteByte('\'')
+		case c == '\'':
+			p.pos++
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func unescape(c byte) byte {
+	switch c {
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	case '0':
+		return 0
+	}
+	return c
+}
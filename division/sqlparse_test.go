@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseInsert(t *testing.T) {
+	stmt, err := parseInsert("INSERT INTO `nested`(id, node, pid) VALUES(1, 'It''s', NULL), (2, 'a\\'b', 1);")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stmt.table != "nested" || len(stmt.columns) != 3 || len(stmt.values) != 2 {
+		t.Fatal(stmt)
+	}
+	if stmt.values[0][1] != "It's" || stmt.values[0][2] != "" || stmt.values[1][1] != "a'b" {
+		t.Error(stmt.values)
+	}
+}
+
+func TestParseInsertFunction(t *testing.T) {
+	stmt, err := parseInsert("INSERT INTO nested(id, g) VALUES(1, ST_SetSRID(ST_GeomFromGeoJSON('{\"a\":\"(,)\"}'), 4326));")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := stmt.values[0][1]; v != `ST_SetSRID(ST_GeomFromGeoJSON('{"a":"(,)"}'), 4326)` {
+		t.Error(v)
+	}
+}
+
+func TestParseInsertErrors(t *testing.T) {
+	for _, text := range []string{
+		"UPDATE nested SET node='x'",
+		"INSERT INTO nested(id, node) VALUES(1);",
+		"INSERT INTO nested(id, node) VALUES(1, 'x);",
+		"INSERT INTO nested(id) VALUES(1) garbage",
+	} {
+		if _, err := parseInsert(text); err == nil {
+			t.Error("no error:", text)
+		}
+	}
+}
+
+func TestParseSQL(t *testing.T) {
+	stmts, err := parseSQL(strings.NewReader("-- comment\n\nINSERT INTO nested(id) VALUES(1);\nINSERT INTO nested(id) VALUES(2);\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 2 || stmts[1].line != 4 {
+		t.Error(stmts)
+	}
+}
+
+func TestParseInsertPostgres(t *testing.T) {
+	stmt, err := parseInsert(`INSERT INTO "geo"."nested"("id", "node") VALUES(1, 'a') ON CONFLICT ("id") DO NOTHING;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stmt.table != "geo.nested" || stmt.columns[1] != "node" || stmt.values[0][1] != "a" {
+		t.Error(stmt)
+	}
+}
+
+func TestParseSQLCopy(t *testing.T) {
+	stmts, err := parseSQL(strings.NewReader("BEGIN;\nCOPY \"nested\" (\"id\", \"node\", \"lng\") FROM stdin;\n1\ta\\tb\t\\N\n2\tc\\\\d\t1.5\n\\.\nCOMMIT;\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 1 || stmts[0].line != 2 || stmts[0].table != "nested" || len(stmts[0].values) != 2 {
+		t.Fatal(stmts)
+	}
+	if v := stmts[0].values; v[0][1] != "a\tb" || v[0][2] != "" || v[1][1] != `c\d` || v[1][2] != "1.5" {
+		t.Error(v)
+	}
+
+	for _, sql := range []string{"COPY nested (id) FROM stdin;\n1\n", "COPY nested (id, node) FROM stdin;\n1\n\\.\n"} {
+		if _, err := parseSQL(strings.NewReader(sql)); err == nil {
+			t.Error("no error:", sql)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// histogram buckets of children counts by their upper bounds, the last one is open
+var childBuckets = []struct {
+	max   int
+	label string
+}{{0, "0"}, {1, "1"}, {4, "2-4"}, {9, "5-9"}, {19, "10-19"}, {49, "20-49"}, {99, "50-99"}, {-1, "100+"}}
+
+type histBucket struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+// levelStats tell how many nodes a level has and how many children they have
+type levelStats struct {
+	Depth       int          `json:"depth"`
+	Nodes       int          `json:"nodes"`
+	MinChildren int          `json:"min_children"`
+	Median      int          `json:"median_children"`
+	MaxChildren int          `json:"max_children"`
+	Histogram   []histBucket `json:"histogram"`
+}
+
+// subtreeStats is a node with the size of its subtree
+type subtreeStats struct {
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	Height      int    `json:"height"` // levels of the subtree, 1 for a leaf
+	Descendants int    `json:"descendants"`
+	Children    int    `json:"children"`
+}
+
+func (s subtreeStats) String() string {
+	return fmt.Sprintf("%s %s", s.Code, s.Name)
+}
+
+// datasetStats summarize a version of the trees
+type datasetStats struct {
+	Source      string         `json:"source"`
+	Nodes       int            `json:"nodes"`
+	Depth       int            `json:"depth"`
+	KeyMin      int32          `json:"key_min"`
+	KeyMax      int32          `json:"key_max"`
+	Fingerprint string         `json:"fingerprint"`
+	Levels      []levelStats   `json:"levels"`
+	Deepest     []subtreeStats `json:"deepest"` // roots by height, then by descendants
+	Widest      []subtreeStats `json:"widest"`  // nodes by children
+}
+
+// subtreesShown is how many deepest and widest subtrees are shown
+const subtreesShown = 3
+
+// fingerprint hashes depth, code, name and parent of the nodes in preorder, so two versions with the same
+// fingerprint hold the same trees
+func fingerprint(trees []*Area) string {
+	h := sha256.New()
+	var walk func(areas []*Area, depth int)
+	walk = func(areas []*Area, depth int) {
+		for _, a := range areas {
+			fmt.Fprintf(h, "%d\t%s\t%s\t%s\n", depth, a.Code, nodeName(a), a.ParentCode)
+			walk(a.SubAreas, depth+1)
+		}
+	}
+	walk(trees, 1)
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+func computeStats(source string, trees []*Area) *datasetStats {
+	s := &datasetStats{Source: source, Depth: treeDepth(trees), Fingerprint: fingerprint(trees), Levels: []levelStats{}}
+	if len(trees) > 0 {
+		s.KeyMin, s.KeyMax = trees[0].Left, trees[len(trees)-1].Right
+	}
+	children := make([][]int, s.Depth)
+	var nodes []subtreeStats
+	var walk func(a *Area, depth int) subtreeStats
+	walk = func(a *Area, depth int) subtreeStats {
+		st := subtreeStats{Code: a.Code, Name: nodeName(a), Height: 1, Children: len(a.SubAreas)}
+		for _, sub := range a.SubAreas {
+			subSt := walk(sub, depth+1)
+			st.Descendants += subSt.Descendants + 1
+			if subSt.Height+1 > st.Height {
+				st.Height = subSt.Height + 1
+			}
+		}
+		children[depth-1] = append(children[depth-1], len(a.SubAreas))
+		nodes = append(nodes, st)
+		return st
+	}
+	var roots []subtreeStats
+	for _, p := range trees {
+		roots = append(roots, walk(p, 1))
+	}
+	s.Nodes = len(nodes)
+
+	for i, counts := range children {
+		sort.Ints(counts)
+		l := levelStats{Depth: i + 1, Nodes: len(counts), MinChildren: counts[0], Median: counts[len(counts)/2],
+			MaxChildren: counts[len(counts)-1]}
+		for _, b := range childBuckets {
+			l.Histogram = append(l.Histogram, histBucket{Label: b.label})
+		}
+		for _, c := range counts {
+			j := 0
+			for childBuckets[j].max >= 0 && c > childBuckets[j].max {
+				j++
+			}
+			l.Histogram[j].Count++
+		}
+		s.Levels = append(s.Levels, l)
+	}
+
+	sort.SliceStable(roots, func(i, j int) bool {
+		if roots[i].Height != roots[j].Height {
+			return roots[i].Height > roots[j].Height
+		}
+		return roots[i].Descendants > roots[j].Descendants
+	})
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].Children > nodes[j].Children })
+	s.Deepest, s.Widest = []subtreeStats{}, []subtreeStats{}
+	for i := 0; i < subtreesShown; i++ {
+		if i < len(roots) {
+			s.Deepest = append(s.Deepest, roots[i])
+		}
+		if i < len(nodes) {
+			s.Widest = append(s.Widest, nodes[i])
+		}
+	}
+	return s
+}
+
+// statRow is a line of the stats table, numbers get deltas when two versions are compared
+type statRow struct {
+	order   int // of rows of both versions compared, as rows of one may be missing in the other
+	label   string
+	value   string
+	n       int
+	numeric bool
+	bar     int // length of a histogram bar
+}
+
+func numberRow(order int, label string, n int) statRow {
+	return statRow{order: order, label: label, value: strconv.Itoa(n), n: n, numeric: true}
+}
+
+// rows lists the stats in the order of the table
+func (s *datasetStats) rows() []statRow {
+	rows := []statRow{
+		numberRow(0, "nodes", s.Nodes),
+		numberRow(1, "depth", s.Depth),
+		{order: 2, label: "keys", value: fmt.Sprintf("%d to %d", s.KeyMin, s.KeyMax)},
+		{order: 3, label: "fingerprint", value: s.Fingerprint},
+	}
+	for _, l := range s.Levels {
+		order, prefix := 100*l.Depth, fmt.Sprintf("depth %d ", l.Depth)
+		rows = append(rows, numberRow(order, prefix+"nodes", l.Nodes), numberRow(order+1, prefix+"children min", l.MinChildren),
+			numberRow(order+2, prefix+"children median", l.Median), numberRow(order+3, prefix+"children max", l.MaxChildren))
+		for i, b := range l.Histogram {
+			if b.Count > 0 {
+				row := numberRow(order+4+i, prefix+"children "+b.Label, b.Count)
+				row.bar = (b.Count*20 + l.Nodes - 1) / l.Nodes
+				rows = append(rows, row)
+			}
+		}
+	}
+	for i, st := range s.Deepest {
+		rows = append(rows, statRow{order: 1e6 + i, label: fmt.Sprintf("deepest %d", i+1),
+			value: fmt.Sprintf("%s, %d levels, %d descendants", st, st.Height, st.Descendants)})
+	}
+	for i, st := range s.Widest {
+		rows = append(rows, statRow{order: 2e6 + i, label: fmt.Sprintf("widest %d", i+1),
+			value: fmt.Sprintf("%s, %d children", st, st.Children)})
+	}
+	return rows
+}
+
+func (s *datasetStats) writeText(w io.Writer) {
+	fmt.Fprintln(w, s.Source)
+	for _, r := range s.rows() {
+		fmt.Fprintf(w, "  %-28s %s", r.label, r.value)
+		if r.bar > 0 {
+			fmt.Fprintf(w, " %s", strings.Repeat("#", r.bar))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// writeCompared writes two versions side by side with deltas of numbers, other changed values are marked
+func writeCompared(w io.Writer, older, newer *datasetStats) {
+	oldRows, newRows := older.rows(), newer.rows()
+	byOrder := make(map[int][2]*statRow)
+	var orders []int
+	for v, rows := range [][]statRow{oldRows, newRows} {
+		for i := range rows {
+			r := &rows[i]
+			pair, ok := byOrder[r.order]
+			if !ok {
+				orders = append(orders, r.order)
+			}
+			pair[v] = r
+			byOrder[r.order] = pair
+		}
+	}
+	sort.Ints(orders)
+
+	width := 24
+	for _, r := range append(oldRows, newRows...) {
+		if n := displayWidth(r.value); n > width {
+			width = n
+		}
+	}
+	fmt.Fprintf(w, "  %-28s %s %s delta\n", "", pad(older.Source, width), pad(newer.Source, width))
+	for _, order := range orders {
+		pair := byOrder[order]
+		var label string
+		var values [2]string
+		var numbers [2]int
+		numeric := true
+		for v, r := range pair {
+			switch {
+			case r == nil:
+				values[v] = "-"
+			default:
+				label, values[v], numbers[v] = r.label, r.value, r.n
+				numeric = numeric && r.numeric
+			}
+		}
+		delta := ""
+		switch {
+		case numeric:
+			if d := numbers[1] - numbers[0]; d != 0 {
+				delta = fmt.Sprintf("%+d", d)
+			}
+		case values[0] != values[1]:
+			delta = "changed"
+		}
+		fmt.Fprintf(w, "  %-28s %s %s %s\n", label, pad(values[0], width), pad(values[1], width), delta)
+	}
+}
+
+// pad fills s with spaces to width columns of a terminal, where Chinese characters take two
+func pad(s string, width int) string {
+	if n := displayWidth(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+func displayWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		n++
+		if r >= 0x2E80 {
+			n++
+		}
+	}
+	return n
+}
+
+// runStats prints stats of one version of the division data, or of two side by side
+func runStats(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("division stats", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "text", "report as text or json")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: division stats [-format text|json] [source [new-source]]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() > 2 || (*format != "text" && *format != "json") {
+		fs.Usage()
+		return exitUsage
+	}
+	sources := fs.Args()
+	if len(sources) == 0 {
+		sources = []string{dataDir}
+	}
+
+	var stats []*datasetStats
+	for _, source := range sources {
+		trees, err := loadTrees(source)
+		if err != nil {
+			fmt.Fprintln(stderr, "division stats:", err)
+			return exitCode(err)
+		}
+		stats = append(stats, computeStats(source, trees))
+	}
+
+	switch {
+	case *format == "json":
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		var v interface{} = stats[0]
+		if len(stats) == 2 {
+			v = stats
+		}
+		if err := enc.Encode(v); err != nil {
+			fmt.Fprintln(stderr, "division stats:", err)
+			return exitIO
+		}
+	case len(stats) == 2:
+		writeCompared(stdout, stats[0], stats[1])
+	default:
+		stats[0].writeText(stdout)
+	}
+	return exitOK
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	out := useStdout(t)
+	var stderr bytes.Buffer
+	if code := run([]string{"stats", "./testdata/mini"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	for _, s := range []string{"  nodes                        9\n", "  keys                         1 to 18\n",
+		"  depth 3 children 1           1 ##########\n  depth 3 children 2-4         1 ##########\n",
+		"  deepest 1                    110000 北京市, 4 levels, 4 descendants\n",
+		"  widest 1                     110101 东城区, 2 children\n"} {
+		if !strings.Contains(out.String(), s) {
+			t.Error(s, "missing in", out.String())
+		}
+	}
+
+	// the same trees from the generated sql file have the same fingerprint
+	trees, err := loadTrees("./testdata/mini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromSQL, err := loadTrees(writeTreesSQL(t, trees))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := fingerprint(trees), fingerprint(fromSQL); a != b {
+		t.Error("fingerprints differ:", a, b)
+	}
+
+	out.Reset()
+	if code := run([]string{"stats", "./testdata/mini", "./testdata/mini2"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	// compare columns without their padding
+	var rows []string
+	for _, l := range strings.Split(out.String(), "\n") {
+		rows = append(rows, strings.Join(strings.Fields(l), " "))
+	}
+	compared := strings.Join(rows, "\n") + "\n"
+	for _, s := range []string{"\nnodes 9 9\n", "\nfingerprint ec03fccbcbae2eab c3a9682d4b4325ff changed\n",
+		"\ndepth 2 children median 1 2 +1\n", "\ndepth 3 children 0 - 2 +2\ndepth 3 children 1 1 - -1\n",
+		"\ndeepest 2 130000 河北省, 4 levels, 3 descendants 130000 河北省, 3 levels, 3 descendants changed\nwidest 1 "} {
+		if !strings.Contains(compared, s) {
+			t.Error(s, "missing in", out.String())
+		}
+	}
+
+	out.Reset()
+	if code := run([]string{"stats", "-format", "json", "./testdata/mini", "./testdata/mini2"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	var stats []datasetStats
+	if err := json.Unmarshal(out.Bytes(), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 2 || stats[1].Source != "./testdata/mini2" || stats[0].Levels[3].Histogram[0].Count != 3 ||
+		stats[1].Deepest[1].Height != 3 {
+		t.Error(out.String())
+	}
+}
+
+func TestStatsUsage(t *testing.T) {
+	useStdout(t)
+	var stderr bytes.Buffer
+	for _, args := range [][]string{{"stats", "-format", "csv"}, {"stats", "a", "b", "c"}} {
+		if code := run(args, &stderr); code != exitUsage {
+			t.Error(args, "exit code:", code)
+		}
+	}
+	if code := run([]string{"stats", "./testdata/missing"}, &stderr); code == exitOK {
+		t.Error("missing source accepted")
+	}
+}
@@ -0,0 +1,37 @@
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(220000, '吉林省', 0, 1, 1, 26);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(220600, '白山市', 220000, 2, 2, 15);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(220605, '江源区', 220600, 3, 3, 8);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(220605103000, '松树镇', 220605, 4, 4, 5);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(220605107000, '大石人镇', 220605, 4, 6, 7);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(220623, '长白朝鲜族自治县', 220600, 3, 9, 14);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(220623101000, '八道沟镇', 220623, 4, 10, 11);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(220623102000, '十四道沟镇', 220623, 4, 12, 13);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(222400, '延边朝鲜族自治州', 220000, 2, 16, 25);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(222402, '图们市', 222400, 3, 17, 22);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(222402002000, '新华街道办事处', 222402, 4, 18, 19);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(222402101000, '石岘镇', 222402, 4, 20, 21);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(222405, '龙井市', 222400, 3, 23, 24);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(310000, '上海市', 0, 1, 27, 42);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(310100, '市辖区', 310000, 2, 28, 41);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(310109, '虹口区', 310100, 3, 29, 34);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(310109010000, '曲阳路街道', 310109, 4, 30, 31);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(310109018000, '提篮桥街道', 310109, 4, 32, 33);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(310120, '奉贤区', 310100, 3, 35, 40);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(310120101000, '南桥镇', 310120, 4, 36, 37);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(310120123000, '海湾镇', 310120, 4, 38, 39);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(540000, '西藏自治区', 0, 1, 43, 72);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(540300, '昌都市', 540000, 2, 44, 57);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(540325, '察雅县', 540300, 3, 45, 50);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(540325101000, '吉塘镇', 540325, 4, 46, 47);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(540325209000, '察拉乡', 540325, 4, 48, 49);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(540330, '边坝县', 540300, 3, 51, 56);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(540330200000, '马武乡', 540330, 4, 52, 53);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(540330208000, '拉孜乡', 540330, 4, 54, 55);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(542400, '那曲地区', 540000, 2, 58, 71);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(542423, '比如县', 542400, 3, 59, 64);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(542423203000, '达塘乡', 542423, 4, 60, 61);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(542423207000, '恰则乡', 542423, 4, 62, 63);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(542427, '索县', 542400, 3, 65, 70);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(542427100000, '亚拉镇', 542427, 4, 66, 67);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(542427207000, '江达乡', 542427, 4, 68, 69);
+INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(710000, '台湾省', 0, 1, 73, 74);
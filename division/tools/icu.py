@@ -0,0 +1,56 @@
+"""ICU transliteration through ctypes, shared by the table generators in this directory."""
+import ctypes
+import ctypes.util
+import glob
+import json
+import re
+
+icu = ctypes.CDLL(ctypes.util.find_library("icui18n"))
+version = re.search(r"(\d+)$", ctypes.util.find_library("icui18n")).group(1)
+
+
+def fn(name):
+    for n in (name, "%s_%s" % (name, version)):
+        if hasattr(icu, n):
+            return getattr(icu, n)
+    raise AttributeError(name)
+
+
+def utf16(s):
+    data = s.encode("utf-16-le")
+    return (ctypes.c_uint16 * (len(data) // 2 + 1)).from_buffer_copy(data + b"\0\0"), len(data) // 2
+
+
+def transliterator(rules):
+    """Returns a function transliterating short strings by the ICU rules, e.g. "Hans-Hant"."""
+    ids, n = utf16(rules)
+    status = ctypes.c_int(0)
+    open_u = fn("utrans_openU")
+    open_u.restype = ctypes.c_void_p
+    trans = open_u(ids, n, 0, None, 0, None, ctypes.byref(status))
+    assert status.value <= 0, status.value
+    trans_uchars = fn("utrans_transUChars")
+
+    def transliterate(s):
+        buf = (ctypes.c_uint16 * 64)()
+        src, n = utf16(s)
+        ctypes.memmove(buf, src, n * 2)
+        length = ctypes.c_int32(n)
+        limit = ctypes.c_int32(n)
+        status = ctypes.c_int(0)
+        trans_uchars(ctypes.c_void_p(trans), buf, ctypes.byref(length), 64, 0, ctypes.byref(limit), ctypes.byref(status))
+        assert status.value <= 0, status.value
+        return bytes(buf)[: length.value * 2].decode("utf-16-le")
+
+    return transliterate
+
+
+def data_chars():
+    """Returns the Han characters of the names in the division data."""
+    chars = set()
+    for name in glob.glob("data/*.json"):
+        if name.endswith("address2.json") or name.endswith("address3.json") or name.endswith("address4.json"):
+            continue
+        for record in json.load(open(name, encoding="utf-8")):
+            chars.update(c for c in record["name"] if "一" <= c <= "鿿")
+    return chars
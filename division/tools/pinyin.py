@@ -0,0 +1,23 @@
+#!/usr/bin/env python3
+"""Generates pinyin.txt, the toneless pinyin of every Han character in the division data.
+
+Readings come from the ICU Han-Latin transliterator, which picks the most common reading of a
+character; place names with other readings are handled by overrides in pinyin.go.
+
+    $ cd division && python3 tools/pinyin.py
+"""
+import re
+
+from icu import data_chars, transliterator
+
+pinyin = transliterator("Han-Latin; Latin-ASCII; Lower")
+
+syllables = {}
+for ch in sorted(data_chars()):
+    py = pinyin(ch)
+    if re.fullmatch("[a-z]+", py):
+        syllables.setdefault(py, []).append(ch)
+
+with open("pinyin.txt", "w", encoding="utf-8") as f:
+    for py in sorted(syllables):
+        f.write("%s %s\n" % (py, "".join(syllables[py])))
@@ -0,0 +1,17 @@
+#!/usr/bin/env python3
+"""Generates traditional.txt, the traditional form of every simplified Han character in the division data.
+
+Characters are converted one by one with the ICU Hans-Hant transliterator, place names where the
+common conversion is wrong are handled by overrides in traditional.go.
+
+    $ cd division && python3 tools/traditional.py
+"""
+from icu import data_chars, transliterator
+
+hant = transliterator("Hans-Hant")
+
+with open("traditional.txt", "w", encoding="utf-8") as f:
+    for ch in sorted(data_chars()):
+        trad = hant(ch)
+        if trad != ch and len(trad) == 1:
+            f.write("%s %s\n" % (ch, trad))
@@ -0,0 +1,132 @@
+package main
+
+import (
+	_ "embed"
+	"strings"
+	"sync"
+)
+
+// traditionalTable lists simplified characters and their traditional form per line, generated by
+// tools/traditional.py. Characters which are the same in both are left out.
+//
+//go:embed traditional.txt
+var traditionalTable string
+
+var (
+	traditionalOnce  sync.Once
+	charTraditionals map[rune]rune
+)
+
+// charTraditionalOverrides are the forms of characters in place names, where the common conversion is
+// another character, e.g. 于 of 于家镇 is not 於, and surnames like 党 are kept
+var charTraditionalOverrides = map[rune]rune{
+	'于': '于', // 于家镇
+	'冲': '沖', // 腾冲
+	'斗': '斗', // 八斗镇
+	'干': '干', // 查干湖
+	'征': '征', // 长征镇
+	'范': '范', // 范县
+	'党': '党', // 党寨镇
+	'舍': '舍', // 杨舍镇
+	'钟': '鍾', // 钟祥
+}
+
+// wordTraditionals overrides conversion of words, which take precedence over characters
+var wordTraditionals = map[string]string{
+	"沈阳": "瀋陽",
+	"余姚": "餘姚",
+	"余杭": "餘杭",
+	"余江": "餘江",
+	"余干": "餘干",
+	"新余": "新餘",
+	"扶余": "扶餘",
+	"仪征": "儀徵",
+	"钟楼": "鐘樓",
+	"示范": "示範",
+}
+
+// maxTradWordLen is the length in characters of the longest word in wordTraditionals
+const maxTradWordLen = 2
+
+func loadTraditional() {
+	charTraditionals = make(map[rune]rune)
+	for _, line := range strings.Split(traditionalTable, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		charTraditionals[[]rune(fields[0])[0]] = []rune(fields[1])[0]
+	}
+	for c, t := range charTraditionalOverrides {
+		charTraditionals[c] = t
+	}
+}
+
+// traditional converts name to traditional characters, by words of wordTraditionals first and character by
+// character for the rest, e.g. 沈阳市 → 瀋陽市
+func traditional(name string) string {
+	traditionalOnce.Do(loadTraditional)
+	runes := []rune(name)
+	var b strings.Builder
+	for i := 0; i < len(runes); {
+		if word, n := matchTradWord(runes[i:]); n > 0 {
+			b.WriteString(word)
+			i += n
+			continue
+		}
+		if t, ok := charTraditionals[runes[i]]; ok {
+			b.WriteRune(t)
+		} else {
+			b.WriteRune(runes[i])
+		}
+		i++
+	}
+	return b.String()
+}
+
+// matchTradWord finds the longest word of wordTraditionals at the start of runes
+func matchTradWord(runes []rune) (string, int) {
+	for n := maxTradWordLen; n > 1; n-- {
+		if len(runes) < n {
+			continue
+		}
+		if word, ok := wordTraditionals[string(runes[:n])]; ok {
+			return word, n
+		}
+	}
+	return "", 0
+}
+
+// convertNames assigns traditional names to nodes without one from -trad-overrides, and logs how many of
+// each there are
+func convertNames(trees []*Area) {
+	overridden, converted := 0, 0
+	var walk func(areas []*Area)
+	walk = func(areas []*Area) {
+		for _, a := range areas {
+			if a.TradName != "" {
+				overridden++
+			} else {
+				a.TradName = traditional(nodeName(a))
+				converted++
+			}
+			walk(a.SubAreas)
+		}
+	}
+	walk(trees)
+	logger.Info("traditional names", "overridden", overridden, "converted", converted)
+}
+
+// loadTradOverrides attaches traditional names of -trad-overrides to the trees, which take precedence over
+// conversion
+func loadTradOverrides(trees []*Area) error {
+	mapping, err := readMapping(tradOverridesFile, "name_trad")
+	if err != nil {
+		return err
+	}
+	attach(trees, "traditional overrides", mappingCodes(mapping), func(area *Area) bool {
+		area.TradName = strings.TrimSpace(mapping[area.Code])
+		return area.TradName != ""
+	})
+	return nil
+}
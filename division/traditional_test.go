@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestTraditional(t *testing.T) {
+	cases := map[string]string{
+		"北京市":   "北京市",
+		"广东省":   "廣東省",
+		"东城区":   "東城區",
+		"乌鲁木齐市": "烏魯木齊市",
+		// characters of place names
+		"于家镇":  "于家鎮",
+		"腾冲市":  "騰沖市",
+		"查干湖镇": "查干湖鎮",
+		"钟祥市":  "鍾祥市",
+		// words take precedence over characters
+		"沈阳市": "瀋陽市",
+		"余姚市": "餘姚市",
+		"余干县": "餘干縣",
+		"钟楼区": "鐘樓區",
+		"仪征市": "儀徵市",
+	}
+	for name, want := range cases {
+		if got := traditional(name); got != want {
+			t.Errorf("%s: %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestTradNameColumn(t *testing.T) {
+	out := usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run([]string{"-trad-overrides", "./testdata/enrich/trad.csv"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"(id, node, pid, depth, lft, rgt, name_trad) VALUES(110000, '北京市', 0, 1, 1, 10, '北京市');",
+		// the override file takes precedence over conversion
+		"VALUES(110101, '东城区', 110100, 3, 3, 8, '東城');",
+		"VALUES(130102, '长安区', 130100, 3, 13, 16, '長安區');",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Error(want)
+		}
+	}
+}
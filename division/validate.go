@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// validation rules
+const (
+	ruleEmptyName  = "empty-name"
+	ruleWrongLevel = "wrong-level"
+	ruleBadUTF8    = "invalid-utf8"
+	ruleBadCode    = "bad-code"
+	ruleDuplicate  = "duplicate"
+	ruleOrphan     = "orphan"
+)
+
+// finding is a problem of one input record
+type finding struct {
+	rule string
+	file string
+	code string
+	msg  string
+}
+
+// reportFile is the file of -report, the findings of the run as JSON
+var reportFile string
+
+// reported are the findings of the run so far, for -report
+var reported []finding
+
+// lenient drops records which cannot be fixed instead of failing the run, with -lenient
+var lenient bool
+
+// rejectsFile is the file of -rejects, the records dropped as JSON lines
+var rejectsFile string
+
+// reject is a record dropped by validate and why
+type reject struct {
+	finding
+	node flatNode
+}
+
+// rejects are the records dropped in the run so far
+var rejects []reject
+
+// validate checks the loaded records. Invalid records fail the run with -strict, or are fixed where possible
+// and reported by default, where later records of a code already in the file are dropped. Records whose parent
+// is missing cannot be fixed and fail the run too, after all records are checked, unless -lenient drops them
+// along with records of bad codes. Dropped records are kept for -rejects.
+func validate() error {
+	var findings []finding
+	var above map[string]bool // codes of the level above
+	for i, l := range inputLevels() {
+		nodes := *l.nodes
+		kept := nodes[:0]
+		codes := make(map[string]bool, len(nodes))
+		for j := range nodes {
+			n := &nodes[j]
+			var found []finding
+			found = append(found, checkUTF8(l.file, n)...)
+			found = append(found, checkName(l.file, n)...)
+			found = append(found, checkCode(l.file, n)...)
+			found = append(found, checkLevel(l.file, i+1, n, above)...)
+			found = append(found, checkParent(l.file, i+1, n, above)...)
+			found = append(found, checkDuplicate(l.file, n, codes)...)
+			findings = append(findings, found...)
+			if f, ok := dropped(found); ok {
+				rejects = append(rejects, reject{f, *n})
+				continue
+			}
+			codes[n.Code] = true
+			kept = append(kept, *n)
+		}
+		*l.nodes = kept
+		above = codes
+	}
+	return report(findings)
+}
+
+// validateTree checks the built trees, every child must be exactly one level below its parent. Nodes are of
+// the level of their file where fileLevel takes it, such as a city repeated as its own area.
+func validateTree(trees []*Area) error {
+	var findings []finding
+	var walk func(parent *Area, parentLevel, depth int)
+	walk = func(parent *Area, parentLevel, depth int) {
+		for _, sub := range parent.SubAreas {
+			// nodes whose code does not match their file are reported by validate already
+			level := fileLevel(sub.Code, depth+1, sub.Code == parent.Code)
+			if level == depth+1 && parentLevel != 0 && level != parentLevel+1 {
+				findings = append(findings, finding{ruleWrongLevel, inputLevels()[depth].file, sub.Code,
+					fmt.Sprintf("level %d node under %s of level %d", level, parent.Code, parentLevel)})
+			}
+			walk(sub, level, depth+1)
+		}
+	}
+	for _, p := range trees {
+		walk(p, fileLevel(p.Code, 1, false), 1)
+	}
+	return report(findings)
+}
+
+// report logs findings, which fail the run in strict mode
+func report(findings []finding) error {
+	reported = append(reported, findings...)
+	for _, f := range findings {
+		logger.Warn("invalid record", "code", f.code, "file", f.file, "rule", f.rule, "problem", f.msg)
+	}
+	if len(findings) > 0 {
+		if strict || !lenient && hasUnfixable(findings) {
+			return dataErrorf("%d invalid records, %s", len(findings), summarize(findings))
+		}
+		logger.Warn("invalid records reported", "count", len(findings), "rules", summarize(findings))
+	}
+	return nil
+}
+
+// dropped returns the finding a record is dropped for: a duplicate unless -strict, and with -lenient a bad
+// code or a missing parent
+func dropped(found []finding) (finding, bool) {
+	for _, f := range found {
+		switch {
+		case f.rule == ruleDuplicate && !strict:
+			return f, true
+		case (f.rule == ruleBadCode || f.rule == ruleOrphan) && lenient:
+			return f, true
+		}
+	}
+	return finding{}, false
+}
+
+// hasUnfixable tells whether there are findings which fail the run in lenient mode too
+func hasUnfixable(findings []finding) bool {
+	for _, f := range findings {
+		if f.rule == ruleOrphan {
+			return true
+		}
+	}
+	return false
+}
+
+// checkUTF8 rejects records with invalid UTF-8 in any field, which is already replaced with U+FFFD for
+// lenient mode
+func checkUTF8(file string, n *flatNode) []finding {
+	if len(n.badUTF8) == 0 {
+		return nil
+	}
+	return []finding{{ruleBadUTF8, file, n.Code, "invalid UTF-8 in " + strings.Join(n.badUTF8, ", ")}}
+}
+
+// checkName rejects empty or whitespace-only names, which are replaced with the code in lenient mode
+func checkName(file string, n *flatNode) []finding {
+	if strings.TrimSpace(n.Name) != "" {
+		return nil
+	}
+	if !strict {
+		n.Name = n.Code
+	}
+	return []finding{{ruleEmptyName, file, n.Code, "empty or whitespace-only name"}}
+}
+
+// checkCode rejects codes of no level of -code-levels, e.g. of letters or of the wrong width
+func checkCode(file string, n *flatNode) []finding {
+	if codeLevel(n.Code) != 0 {
+		return nil
+	}
+	return []finding{{ruleBadCode, file, n.Code, "not a division code"}}
+}
+
+// checkDuplicate rejects codes of earlier records of the file, which are dropped in lenient mode. A code may
+// be in two files, e.g. cities without areas are listed as areas of themselves.
+func checkDuplicate(file string, n *flatNode, seen map[string]bool) []finding {
+	if !seen[n.Code] {
+		return nil
+	}
+	return []finding{{ruleDuplicate, file, n.Code, "code of an earlier record"}}
+}
+
+// checkParent rejects records whose parent is not among the codes of the level above, which would be built
+// into no tree
+func checkParent(file string, level int, n *flatNode, above map[string]bool) []finding {
+	if level == 1 {
+		return nil
+	}
+	if parent := codePrefix(n.Code, level-1); !above[parent] {
+		return []finding{{ruleOrphan, file, n.Code, fmt.Sprintf("parent %s not in %s", parent, inputLevels()[level-2].file)}}
+	}
+	return nil
+}
+
+// checkLevel rejects records whose code structure belongs to another level than the file they came from, but
+// for those fileLevel takes for its level, among them cities of above repeated as their own areas
+func checkLevel(file string, level int, n *flatNode, above map[string]bool) []finding {
+	l := fileLevel(n.Code, level, above[n.Code])
+	if l == 0 || l == level {
+		return nil
+	}
+	return []finding{{ruleWrongLevel, file, n.Code, fmt.Sprintf("code belongs to %s (level %d)", levelName(inputLevels()[l-1]), l)}}
+}
+
+// summarize lists offending codes by rule, e.g. "empty-name: 110101, 110102"
+func summarize(findings []finding) string {
+	var rules []string
+	codes := make(map[string][]string)
+	for _, f := range findings {
+		if _, ok := codes[f.rule]; !ok {
+			rules = append(rules, f.rule)
+		}
+		codes[f.rule] = append(codes[f.rule], f.code)
+	}
+
+	parts := make([]string, 0, len(rules))
+	for _, r := range rules {
+		parts = append(parts, r+": "+strings.Join(codes[r], ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// writeReport writes the findings of the run to w as JSON, an object of each with its rule, file, code and
+// problem
+func writeReport(w io.Writer) error {
+	type item struct {
+		Rule    string `json:"rule"`
+		File    string `json:"file"`
+		Code    string `json:"code"`
+		Problem string `json:"problem"`
+	}
+	items := make([]item, len(reported))
+	for i, f := range reported {
+		items[i] = item{f.rule, f.file, f.code, f.msg}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(items)
+}
+
+// writeRejects writes the dropped records to w, a JSON object on each line with the file, rule and problem
+// and the record as read, so they could be fixed and fed again
+func writeRejects(w io.Writer) error {
+	type record struct {
+		Code       string `json:"code"`
+		Name       string `json:"name"`
+		ParentCode string `json:"parent_code,omitempty"`
+	}
+	type line struct {
+		File    string `json:"file"`
+		Rule    string `json:"rule"`
+		Problem string `json:"problem"`
+		Record  record `json:"record"`
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, r := range rejects {
+		err := enc.Encode(line{r.file, r.rule, r.msg, record{r.node.Code, r.node.Name, r.node.ParentCode}})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEmptyNameStrict(t *testing.T) {
+	usePaths(t, "./testdata/emptyname")
+	var stderr bytes.Buffer
+	if code := run([]string{"-strict"}, &stderr); code != exitData {
+		t.Error("exit code:", code)
+	}
+	if !strings.Contains(stderr.String(), "empty-name: 110101") {
+		t.Error(stderr.String())
+	}
+}
+
+func TestEmptyNameLenient(t *testing.T) {
+	out := usePaths(t, "./testdata/emptyname")
+	var stderr bytes.Buffer
+	if code := run(nil, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "VALUES(110101, '110101', 110100,") {
+		t.Error(string(data))
+	}
+}
+
+func TestNodeName(t *testing.T) {
+	if n := nodeName(&Area{Code: "110101", Name: "  "}); n != "110101" {
+		t.Error(n)
+	}
+	if n := nodeName(&Area{Code: "110101", Name: " 东城区 "}); n != "东城区" {
+		t.Error(n)
+	}
+}
+
+func TestCodeLevel(t *testing.T) {
+	for code, level := range map[string]int{
+		"110000":       1,
+		"110100":       2,
+		"110101":       3,
+		"110101001000": 4,
+		"000000":       0,
+		"11010":        0,
+		"11010a":       0,
+		"110101000000": 0,
+	} {
+		if l := codeLevel(code); l != level {
+			t.Error(code, l)
+		}
+	}
+}
+
+func TestFileLevel(t *testing.T) {
+	for _, c := range []struct {
+		code             string
+		level, fileLevel int
+		repeated         bool
+	}{
+		{"441900", 3, 3, true},
+		{"441900", 3, 2, false},
+		{"110000", 3, 1, true},
+		{"653130103001", 4, 4, false},
+		{"653130103001", 5, 5, false},
+		{"653130000001", 4, 5, false},
+		{"110101001000", 5, 4, false},
+	} {
+		if l := fileLevel(c.code, c.level, c.repeated); l != c.fileLevel {
+			t.Error(c.code, c.level, c.repeated, l)
+		}
+	}
+}
+
+// TestValidateData checks the shipped data, whose cities without areas are repeated as their own areas and
+// whose street 653130103001 has a code of village structure
+func TestValidateData(t *testing.T) {
+	strict, reported = true, nil
+	defer func() { strict, reported = false, nil }()
+	trees, err := loadTrees("./data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := validateTree(trees); err != nil {
+		t.Error(err)
+	}
+	if len(reported) != 0 {
+		t.Error(summarize(reported))
+	}
+}
+
+func TestWrongLevel(t *testing.T) {
+	usePaths(t, "./testdata/wronglevel")
+	var stderr bytes.Buffer
+	if code := run([]string{"-strict"}, &stderr); code != exitData {
+		t.Error("exit code:", code)
+	}
+	if !strings.Contains(stderr.String(), "wrong-level: 130102002000") {
+		t.Error(stderr.String())
+	}
+
+	stderr.Reset()
+	if code := run(nil, &stderr); code != exitOK {
+		t.Error("exit code:", code, stderr.String())
+	}
+}
+
+func TestWrongLevelInTree(t *testing.T) {
+	strict = true
+	defer func() { strict = false }()
+	street := &Area{Code: "110101001000"}
+	trees := []*Area{{Code: "110000", SubAreas: []*Area{{Code: "110100", SubAreas: []*Area{street}}}}}
+	if err := validateTree(trees); err != nil {
+		t.Error(err)
+	}
+
+	// a city code at area depth
+	trees[0].SubAreas[0].SubAreas = []*Area{{Code: "110200"}}
+	err := validateTree(trees)
+	if err != nil {
+		t.Error(err)
+	}
+	// a street under that city code
+	trees[0].SubAreas[0].SubAreas[0].SubAreas = []*Area{{Code: "110200001000"}}
+	err = validateTree(trees)
+	if err == nil || !strings.Contains(err.Error(), "wrong-level: 110200001000") {
+		t.Error(err)
+	}
+
+	// a street under a city repeated as its own area
+	city := &Area{Code: "441900", SubAreas: []*Area{{Code: "441900", SubAreas: []*Area{{Code: "441900003000"}}}}}
+	if err := validateTree([]*Area{{Code: "440000", SubAreas: []*Area{city}}}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBadUTF8(t *testing.T) {
+	out := usePaths(t, "./testdata/badutf8")
+	var stderr bytes.Buffer
+	if code := run([]string{"-strict"}, &stderr); code != exitData {
+		t.Error("exit code:", code)
+	}
+	if !strings.Contains(stderr.String(), "invalid-utf8: 110101") {
+		t.Error(stderr.String())
+	}
+
+	stderr.Reset()
+	if code := run(nil, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "VALUES(110101, '东\uFFFD\uFFFD', 110100,") {
+		t.Error(string(data))
+	}
+}
+
+func TestDuplicateLenient(t *testing.T) {
+	out := usePaths(t, "./testdata/duplicate")
+	report := filepath.Join(t.TempDir(), "report.json")
+	var stderr bytes.Buffer
+	if code := run([]string{"-report", report}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "重复") || !strings.Contains(string(data), "错码") {
+		t.Error(string(data))
+	}
+	data, err = ioutil.ReadFile(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var findings []map[string]string
+	if err = json.Unmarshal(data, &findings); err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 2 || findings[0]["rule"] != "duplicate" || findings[0]["file"] != "cities.json" ||
+		findings[1]["rule"] != "bad-code" || findings[1]["code"] != "1101x0" {
+		t.Error(findings)
+	}
+
+	usePaths(t, "./testdata/duplicate")
+	if code := run([]string{"-strict"}, &stderr); code != exitData {
+		t.Error("exit code:", code)
+	}
+}
+
+func TestOrphanReport(t *testing.T) {
+	usePaths(t, "./testdata/orphan")
+	report := filepath.Join(t.TempDir(), "report.json")
+	var stderr bytes.Buffer
+	if code := run([]string{"-report", report}, &stderr); code != exitData {
+		t.Error("exit code:", code)
+	}
+	if !strings.Contains(stderr.String(), "orphan: 140105") {
+		t.Error(stderr.String())
+	}
+	data, err := ioutil.ReadFile(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"problem": "parent 140100 not in cities.json"`) {
+		t.Error(string(data))
+	}
+}
+
+func TestLenientRejects(t *testing.T) {
+	out := usePaths(t, "./testdata/orphan")
+	rejected := filepath.Join(t.TempDir(), "rejects.jsonl")
+	var stderr bytes.Buffer
+	if code := run([]string{"-lenient", "-rejects", rejected}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(rejected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"file":"areas.json","rule":"orphan","problem":"parent 140100 not in cities.json","record":{"code":"140105","name":"小店区","parent_code":"140100"}}` + "\n"
+	if string(data) != want {
+		t.Error(string(data))
+	}
+	sql, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(sql), "小店区") || !strings.Contains(string(sql), "长安区") {
+		t.Error(string(sql))
+	}
+
+	if code := run([]string{"-strict", "-lenient"}, &stderr); code != exitUsage {
+		t.Error("exit code:", code)
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// sqlRow is a row inserted by a sql file, with the line of its statement
+type sqlRow struct {
+	line     int
+	id, pid  string
+	depth    int
+	lft, rgt int
+}
+
+// rowsFromSQL lists the rows inserted by parsed statements, in the order of the file
+func rowsFromSQL(stmts []insertStmt) ([]sqlRow, error) {
+	var rows []sqlRow
+	for _, stmt := range stmts {
+		index := make(map[string]int, len(stmt.columns))
+		for i, c := range stmt.columns {
+			index[c] = i
+		}
+		for _, col := range []string{"id", "pid", "depth", "lft", "rgt"} {
+			if _, ok := index[col]; !ok {
+				return nil, dataErrorf("line %d: column %s missing", stmt.line, col)
+			}
+		}
+		for _, v := range stmt.values {
+			depth, err1 := strconv.Atoi(v[index["depth"]])
+			left, err2 := strconv.Atoi(v[index["lft"]])
+			right, err3 := strconv.Atoi(v[index["rgt"]])
+			if err1 != nil || err2 != nil || err3 != nil {
+				return nil, dataErrorf("line %d: depth, lft and rgt must be integers", stmt.line)
+			}
+			rows = append(rows, sqlRow{line: stmt.line, id: v[index["id"]], pid: v[index["pid"]], depth: depth, lft: left, rgt: right})
+		}
+	}
+	return rows, nil
+}
+
+// problem is a broken invariant found at a line of a sql file
+type problem struct {
+	line int
+	msg  string
+}
+
+// verifyRows checks that rows form nested sets: ids are unique, keys are 1 to twice the number of rows with
+// each used once, or multiples of -key-step up to that many steps, intervals of rows nest without overlapping,
+// and pid and depth of every row agree with the smallest interval containing it. Problems are ordered by line.
+func verifyRows(rows []sqlRow) []problem {
+	var problems []problem
+	add := func(line int, format string, args ...interface{}) {
+		problems = append(problems, problem{line, fmt.Sprintf(format, args...)})
+	}
+
+	step := int(keyStep)
+	ids := make(map[string]int)
+	keys := make(map[int]int) // line by key
+	for _, r := range rows {
+		if line, ok := ids[r.id]; ok {
+			add(r.line, "duplicate id %s, first at line %d", r.id, line)
+		} else {
+			ids[r.id] = r.line
+		}
+		if r.lft >= r.rgt || (r.rgt-r.lft)%(2*step) != step {
+			add(r.line, "%s: interval [%d, %d] is empty or holds half a node", r.id, r.lft, r.rgt)
+		}
+		rowKeys := []int{r.lft, r.rgt}
+		if r.rgt == r.lft {
+			rowKeys = rowKeys[:1] // reported as an empty interval, not as a key used twice
+		}
+		for _, k := range rowKeys {
+			switch line, ok := keys[k]; {
+			case k < step || k > 2*len(rows)*step:
+				add(r.line, "%s: key %d out of %d to %d", r.id, k, step, 2*len(rows)*step)
+			case k%step != 0:
+				add(r.line, "%s: key %d is no multiple of the key step %d", r.id, k, step)
+			case ok:
+				add(r.line, "%s: key %d already used at line %d", r.id, k, line)
+			default:
+				keys[k] = r.line
+			}
+		}
+	}
+	var missing []int
+	for k := step; k <= 2*len(rows)*step; k += step {
+		if _, ok := keys[k]; !ok {
+			missing = append(missing, k)
+		}
+	}
+	if len(missing) > 0 {
+		add(0, "%d keys are unused, the first is %d", len(missing), missing[0])
+	}
+
+	// rows by lft, each nested in the innermost open interval
+	sorted := make([]sqlRow, len(rows))
+	copy(sorted, rows)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].lft < sorted[j].lft })
+	var open []sqlRow
+	for _, r := range sorted {
+		for len(open) > 0 && open[len(open)-1].rgt < r.lft {
+			open = open[:len(open)-1]
+		}
+		pid, depth := "0", len(open)+1
+		if len(open) > 0 {
+			parent := open[len(open)-1]
+			pid = parent.id
+			if r.rgt > parent.rgt {
+				add(r.line, "%s: interval [%d, %d] overlaps [%d, %d] of %s at line %d", r.id, r.lft, r.rgt,
+					parent.lft, parent.rgt, parent.id, parent.line)
+			}
+		}
+		if r.pid != pid {
+			add(r.line, "%s: pid %s, but its interval is nested in %s", r.id, r.pid, pid)
+		}
+		if r.depth != depth {
+			add(r.line, "%s: depth %d, but its interval is nested %d deep", r.id, r.depth, depth)
+		}
+		open = append(open, r)
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].line < problems[j].line })
+	return problems
+}
+
+// rowsFromDB lists the rows of the table in the database of dsn, numbered in the order of their ids in place of
+// lines. NULL pids are taken for roots.
+func rowsFromDB(source string) ([]sqlRow, error) {
+	db, err := sql.Open(driverNames[dialect], source)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	rows, err := db.Query("SELECT " + strings.Join([]string{quoteIdent("id"), quoteIdent("pid"), quoteIdent("depth"),
+		quoteIdent("lft"), quoteIdent("rgt")}, ", ") + " FROM " + tableRef() + " ORDER BY " + quoteIdent("id"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var found []sqlRow
+	for rows.Next() {
+		r := sqlRow{line: len(found) + 1}
+		var pid sql.NullString
+		if err := rows.Scan(&r.id, &pid, &r.depth, &r.lft, &r.rgt); err != nil {
+			return nil, err
+		}
+		r.pid = "0"
+		if pid.Valid {
+			r.pid = pid.String
+		}
+		found = append(found, r)
+	}
+	return found, rows.Err()
+}
+
+// runVerify checks a generated, or hand-edited, sql file without a database, or the table of a database with
+// -dsn. Problems are listed on stdout, the exit code tells a clean file from one which does not parse and one
+// which breaks the nested sets.
+func runVerify(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("division verify", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	defer func(table, d, schema string, step int32) {
+		tblName, dialect, dbSchema, keyStep = table, d, schema, step
+	}(tblName, dialect, dbSchema, keyStep)
+	step := fs.Int("key-step", 1, "distance of the keys, as generated with -key-step")
+	source := fs.String("dsn", "", "`data source` of a database to check the table of, instead of a file")
+	fs.Var(tableFlag{}, "table", "`name` of the table to check with -dsn")
+	fs.StringVar(&dialect, "dialect", "mysql", "database of -dsn, "+strings.Join(dialects, " or "))
+	fs.StringVar(&dbSchema, "db-schema", "", "`schema` of the table to check with -dsn")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: division verify [-key-step n] file\n"+
+			"       division verify [-key-step n] [-table name] [-dialect name] [-db-schema schema] -dsn source")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *source == "" && fs.NArg() != 1 || *source != "" && fs.NArg() != 0 || *step < 1 || *step > math.MaxInt32/2 ||
+		!isDialect(dialect) || dbSchema != "" && !tableName.MatchString(dbSchema) {
+		fs.Usage()
+		return exitUsage
+	}
+	keyStep = int32(*step)
+
+	var name string
+	var rows []sqlRow
+	if *source != "" {
+		if !hasDriver() {
+			fmt.Fprintf(stderr, "division verify: no %s driver built in, build with -tags %s\n", driverNames[dialect], dialect)
+			return exitUsage
+		}
+		name = tableRef()
+		var err error
+		if rows, err = rowsFromDB(*source); err != nil {
+			fmt.Fprintf(stderr, "division verify: %s: %v\n", name, err)
+			return exitIO
+		}
+	} else {
+		name = fs.Arg(0)
+		var code int
+		if rows, code = rowsFromFile(name, stderr); code != exitOK {
+			return code
+		}
+	}
+
+	problems := verifyRows(rows)
+	for _, p := range problems {
+		if p.line == 0 {
+			fmt.Fprintf(stdout, "%s: %s\n", name, p.msg)
+			continue
+		}
+		fmt.Fprintf(stdout, "%s:%d: %s\n", name, p.line, p.msg)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(stderr, "division verify: %s: %d problems in %d rows\n", name, len(problems), len(rows))
+		return exitInvalid
+	}
+	return exitOK
+}
+
+// rowsFromFile parses the rows of a sql file, failures are reported on stderr with the exit code
+func rowsFromFile(name string, stderr io.Writer) ([]sqlRow, int) {
+	f, err := os.Open(name)
+	if err != nil {
+		fmt.Fprintln(stderr, "division verify:", err)
+		return nil, exitIO
+	}
+	defer f.Close()
+	stmts, err := parseSQL(f)
+	if err != nil {
+		fmt.Fprintf(stderr, "division verify: %s: %v\n", name, err)
+		return nil, exitCode(err)
+	}
+	rows, err := rowsFromSQL(stmts)
+	if err != nil {
+		fmt.Fprintf(stderr, "division verify: %s: %v\n", name, err)
+		return nil, exitCode(err)
+	}
+	return rows, exitOK
+}
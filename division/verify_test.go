@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifyRows(t *testing.T) {
+	row := func(line int, id, pid string, depth, lft, rgt int) sqlRow {
+		return sqlRow{line: line, id: id, pid: pid, depth: depth, lft: lft, rgt: rgt}
+	}
+	tests := []struct {
+		name string
+		rows []sqlRow
+		want []string
+	}{
+		{"clean", []sqlRow{row(1, "1", "0", 1, 1, 6), row(2, "11", "1", 2, 2, 3), row(3, "12", "1", 2, 4, 5), row(4, "2", "0", 1, 7, 8)}, nil},
+		{"duplicate id", []sqlRow{row(1, "1", "0", 1, 1, 4), row(2, "1", "1", 2, 2, 3)}, []string{
+			"2: duplicate id 1, first at line 1"}},
+		{"gap", []sqlRow{row(1, "1", "0", 1, 1, 4), row(2, "11", "1", 2, 2, 3), row(3, "2", "0", 1, 6, 7)}, []string{
+			"0: 1 keys are unused, the first is 5",
+			"3: 2: key 7 out of 1 to 6"}},
+		{"empty interval", []sqlRow{row(1, "1", "0", 1, 2, 1)}, []string{
+			"1: 1: interval [2, 1] is empty or holds half a node"}},
+		{"same keys", []sqlRow{row(1, "1", "0", 1, 1, 1)}, []string{
+			"0: 1 keys are unused, the first is 2",
+			"1: 1: interval [1, 1] is empty or holds half a node"}},
+		{"overlap", []sqlRow{row(1, "1", "0", 1, 1, 4), row(2, "11", "1", 2, 2, 5), row(3, "2", "0", 1, 3, 6)}, []string{
+			"2: 11: interval [2, 5] overlaps [1, 4] of 1 at line 1",
+			"3: 2: interval [3, 6] overlaps [2, 5] of 11 at line 2",
+			"3: 2: pid 0, but its interval is nested in 11",
+			"3: 2: depth 1, but its interval is nested 3 deep"}},
+		{"wrong pid and depth", []sqlRow{row(1, "1", "0", 1, 1, 6), row(2, "11", "1", 2, 2, 5), row(3, "111", "1", 2, 3, 4)}, []string{
+			"3: 111: pid 1, but its interval is nested in 11",
+			"3: 111: depth 2, but its interval is nested 3 deep"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, p := range verifyRows(tt.rows) {
+			got = append(got, itoa(int32(p.line))+": "+p.msg)
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s: got\n%s", tt.name, strings.Join(got, "\n"))
+		}
+	}
+}
+
+func TestRunVerify(t *testing.T) {
+	out := useStdout(t)
+	var stderr bytes.Buffer
+	dir := t.TempDir()
+	write := func(sql string) string {
+		name := filepath.Join(dir, "division.sql")
+		if err := os.WriteFile(name, []byte(sql), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return name
+	}
+	if code := run([]string{"verify", sqlFileOf(t, "./testdata/mini")}, &stderr); code != exitOK || out.Len() > 0 {
+		t.Error("exit code:", code, out.String())
+	}
+
+	name := write("INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(1, 'a', 0, 1, 1, 4);\n" +
+		"-- hand edited\n" +
+		"INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(11, 'b', 0, 2, 2, 3);\n")
+	if code := run([]string{"verify", name}, &stderr); code != exitInvalid {
+		t.Error("exit code:", code)
+	}
+	if want := name + ":3: 11: pid 0, but its interval is nested in 1\n"; out.String() != want {
+		t.Error(out.String())
+	}
+
+	name = write("INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(1, 'a, 0, 1, 1, 2);\n")
+	if code := run([]string{"verify", name}, &stderr); code != exitData {
+		t.Error("exit code:", code)
+	}
+	if code := run([]string{"verify", filepath.Join(dir, "none.sql")}, &stderr); code != exitIO {
+		t.Error("exit code:", code)
+	}
+	if code := run([]string{"verify"}, &stderr); code != exitUsage {
+		t.Error("exit code:", code)
+	}
+}
+
+func TestVerifyDB(t *testing.T) {
+	useRecorder(t)
+	out := useStdout(t)
+	var stderr bytes.Buffer
+	testDB.rows = [][]driver.Value{
+		{int64(1), nil, int64(1), int64(1), int64(6)},
+		{int64(2), int64(0), int64(1), int64(7), int64(8)},
+		{int64(11), int64(1), int64(2), int64(2), int64(3)},
+		{int64(12), int64(2), int64(2), int64(4), int64(5)},
+	}
+	args := []string{"verify", "-dialect", "sqlite", "-db-schema", "geo", "-dsn", "test"}
+	if code := run(args, &stderr); code != exitInvalid {
+		t.Error("exit code:", code, stderr.String())
+	}
+	if want := `"geo"."nested":4: 12: pid 2, but its interval is nested in 1` + "\n"; out.String() != want {
+		t.Error(out.String())
+	}
+	if want := `query SELECT "id", "pid", "depth", "lft", "rgt" FROM "geo"."nested" ORDER BY "id"`; len(testDB.log) != 1 || testDB.log[0] != want {
+		t.Error(testDB.log)
+	}
+
+	if code := run([]string{"verify", "-dialect", "sqlite", "-dsn", "test", "division.sql"}, &stderr); code != exitUsage {
+		t.Error("exit code:", code)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// genYAMLFile writes the trees into the -out file as YAML, a sequence of the provinces with the fields of the
+// inserts and their children nested under children
+func genYAMLFile(trees []*Area) error {
+	return writeFileAtomic(sqlFile, func(w io.Writer) error {
+		return writeYAMLTree(w, trees)
+	}, nil)
+}
+
+func writeYAMLTree(w io.Writer, trees []*Area) error {
+	bw := bufio.NewWriterSize(w, sqlBufferSize)
+	for _, p := range trees {
+		if err := writeYAMLNode(bw, []*Area{p}); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// writeYAMLNode writes the node at the end of path as an item of a sequence indented by its depth, text as
+// double-quoted scalars, which take the escapes of JSON strings, and empty values of nullable columns as null
+func writeYAMLNode(w *bufio.Writer, path []*Area) error {
+	area := path[len(path)-1]
+	indent := strings.Repeat("    ", len(path)-1)
+	name, err := json.Marshal(nodeName(area))
+	if err != nil {
+		return err
+	}
+	w.WriteString(indent + "- id: " + area.Code + "\n")
+	indent += "  "
+	w.WriteString(indent + "node: " + string(name) + "\n")
+	w.WriteString(indent + "pid: " + area.ParentCode + "\n")
+	w.WriteString(indent + "depth: " + itoa(int32(len(path))) + "\n")
+	w.WriteString(indent + "lft: " + itoa(area.Left) + "\n")
+	w.WriteString(indent + "rgt: " + itoa(area.Right) + "\n")
+	for _, c := range columns {
+		v, err := c.get(path)
+		if err != nil {
+			return err
+		}
+		switch {
+		case v == "" && c.null:
+			v = "null"
+		case c.text:
+			q, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			v = string(q)
+		}
+		w.WriteString(indent + c.name + ": " + v + "\n")
+	}
+	if len(area.SubAreas) == 0 {
+		_, err = w.WriteString(indent + "children: []\n")
+		return err
+	}
+	if _, err = w.WriteString(indent + "children:\n"); err != nil {
+		return err
+	}
+	for _, sub := range area.SubAreas {
+		if err := writeYAMLNode(w, append(path, sub)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
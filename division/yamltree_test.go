@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestFormatYAML(t *testing.T) {
+	out := usePaths(t, "./testdata/mini")
+	var stderr bytes.Buffer
+	if code := run([]string{"-format", "yaml", "-columns", "lng,short_name"}, &stderr); code != exitOK {
+		t.Fatal("exit code:", code, stderr.String())
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `- id: 110000
+  node: "北京市"
+  pid: 0
+  depth: 1
+  lft: 1
+  rgt: 10
+  lng: null
+  short_name: "北京"
+  children:
+    - id: 110100
+      node: "市辖区"
+`
+	if !strings.HasPrefix(string(data), want) {
+		t.Error(string(data))
+	}
+	leaf := `
+            - id: 110101002000
+              node: "景山街道办事处"
+              pid: 110101
+              depth: 4
+              lft: 6
+              rgt: 7
+              lng: null
+              short_name: "景山"
+              children: []
+- id: 130000
+`
+	if !strings.Contains(string(data), leaf) {
+		t.Error(string(data))
+	}
+}
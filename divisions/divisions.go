@@ -0,0 +1,58 @@
+// Package divisions embeds the tree of Chinese divisions as written by the division command with -format json,
+// so programs get the hierarchy at run time without data files or a database.
+package divisions
+
+//go:generate go run ../division -data-dir ../division/data -format json -out divisions.json
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/BionStt/nested"
+)
+
+//go:embed divisions.json
+var data []byte
+
+// node is a node of the embedded JSON, keys are assigned again by nested.Build
+type node struct {
+	Code     string  `json:"code"`
+	Name     string  `json:"name"`
+	Children []*node `json:"children"`
+}
+
+// Load builds the tree of the embedded divisions, with the codes as ids. Cities without districts, such as
+// 东莞市 441900, are listed in the data also as their own district, which is folded into the city so ids are
+// unique; keys are assigned again after. Each call decodes the data anew, so callers may change their tree; keep
+// it rather than loading it again.
+func Load() (*nested.Tree, error) {
+	var roots []*node
+	if err := json.Unmarshal(data, &roots); err != nil {
+		return nil, err
+	}
+	var records []nested.Record
+	var add func(n *node, pid int64) error
+	add = func(n *node, pid int64) error {
+		id, err := strconv.ParseInt(n.Code, 10, 64)
+		if err != nil {
+			return fmt.Errorf("code %q: %v", n.Code, err)
+		}
+		if id != pid {
+			records = append(records, nested.Record{ID: id, Node: n.Name, ParentID: pid})
+		}
+		for _, child := range n.Children {
+			if err := add(child, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, root := range roots {
+		if err := add(root, 0); err != nil {
+			return nil, err
+		}
+	}
+	return nested.Build(records)
+}
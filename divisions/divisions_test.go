@@ -0,0 +1,41 @@
+package divisions
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/BionStt/nested"
+)
+
+func TestLoad(t *testing.T) {
+	tree, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree.Roots) != 34 {
+		t.Error("provinces:", len(tree.Roots))
+	}
+	n := tree.Find(110101)
+	if n == nil || n.Node != "东城区" || n.ParentID != 110100 || n.Depth != 3 {
+		t.Fatal(n)
+	}
+	if n := tree.Find(441900); n == nil || n.Depth != 2 || len(n.Children) != 35 || n.Children[0].ParentID != 441900 {
+		t.Error("city without districts not folded:", n)
+	}
+
+	// the data has a node more for each folded district
+	var roots []struct {
+		Right int32 `json:"rgt"`
+	}
+	if err := json.Unmarshal(data, &roots); err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	tree.Walk(func(*nested.TreeNode) error {
+		count++
+		return nil
+	})
+	if last := tree.Roots[len(tree.Roots)-1]; int32(count) != last.Right/2 || count != int(roots[len(roots)-1].Right/2)-2 {
+		t.Error("nodes:", count, last.Right)
+	}
+}
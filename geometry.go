@@ -0,0 +1,108 @@
+package nested
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// Ring is a closed ring of lng, lat points
+type Ring [][2]float64
+
+// Polygon is an outer ring followed by its holes, as in GeoJSON
+type Polygon []Ring
+
+// Contains tells whether the point is inside the outer ring and not inside a hole. Points on the outer ring are
+// inside, so a point on the border of neighbors is in both of them.
+func (p Polygon) Contains(lng, lat float64) bool {
+	if len(p) == 0 || !inRing(p[0], lng, lat, true) {
+		return false
+	}
+	for _, hole := range p[1:] {
+		if inRing(hole, lng, lat, false) {
+			return false
+		}
+	}
+	return true
+}
+
+// inRing tests the point by ray casting, points on the ring are inside it if onRing is set
+func inRing(points Ring, x, y float64, onRing bool) bool {
+	in := false
+	for i, j := 0, len(points)-1; i < len(points); j, i = i, i+1 {
+		xi, yi := points[i][0], points[i][1]
+		xj, yj := points[j][0], points[j][1]
+		if onSegment(xi, yi, xj, yj, x, y) {
+			return onRing
+		}
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			in = !in
+		}
+	}
+	return in
+}
+
+func onSegment(x1, y1, x2, y2, x, y float64) bool {
+	cross := (x2-x1)*(y-y1) - (y2-y1)*(x-x1)
+	return cross == 0 && x >= math.Min(x1, x2) && x <= math.Max(x1, x2) && y >= math.Min(y1, y2) && y <= math.Max(y1, y2)
+}
+
+// ParseGeometry reads the polygons of a GeoJSON Polygon or MultiPolygon geometry, other geometries and null have
+// none
+func ParseGeometry(geometry []byte) ([]Polygon, error) {
+	if len(geometry) == 0 || string(geometry) == "null" {
+		return nil, nil
+	}
+	var g struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	}
+	if err := json.Unmarshal(geometry, &g); err != nil {
+		return nil, err
+	}
+	switch g.Type {
+	case "Polygon":
+		var polygon Polygon
+		err := json.Unmarshal(g.Coordinates, &polygon)
+		return []Polygon{polygon}, err
+	case "MultiPolygon":
+		var polygons []Polygon
+		err := json.Unmarshal(g.Coordinates, &polygons)
+		return polygons, err
+	}
+	return nil, nil
+}
+
+// Region is the polygons of a boundary with their bounding box, which points are checked against first and
+// indexes are built on
+type Region struct {
+	Polygons               []Polygon
+	MinX, MinY, MaxX, MaxY float64
+}
+
+// NewRegion computes the bounding box of the outer rings of polygons
+func NewRegion(polygons []Polygon) *Region {
+	r := &Region{Polygons: polygons, MinX: math.Inf(1), MinY: math.Inf(1), MaxX: math.Inf(-1), MaxY: math.Inf(-1)}
+	for _, p := range polygons {
+		if len(p) == 0 {
+			continue
+		}
+		for _, point := range p[0] {
+			r.MinX, r.MaxX = math.Min(r.MinX, point[0]), math.Max(r.MaxX, point[0])
+			r.MinY, r.MaxY = math.Min(r.MinY, point[1]), math.Max(r.MaxY, point[1])
+		}
+	}
+	return r
+}
+
+// Contains tells whether the point is in one of the polygons
+func (r *Region) Contains(lng, lat float64) bool {
+	if lng < r.MinX || lng > r.MaxX || lat < r.MinY || lat > r.MaxY {
+		return false
+	}
+	for _, p := range r.Polygons {
+		if p.Contains(lng, lat) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package nested
+
+import (
+	"testing"
+)
+
+func TestRegion(t *testing.T) {
+	polygons, err := ParseGeometry([]byte(`{"type":"MultiPolygon","coordinates":[
+		[[[0,0],[5,0],[5,5],[0,5],[0,0]],[[1,1],[2,1],[2,2],[1,2],[1,1]]],
+		[[[6,6],[8,6],[7,9],[6,6]]]]}`))
+	if err != nil || len(polygons) != 2 {
+		t.Fatal(polygons, err)
+	}
+	r := NewRegion(polygons)
+	if r.MinX != 0 || r.MinY != 0 || r.MaxX != 8 || r.MaxY != 9 {
+		t.Error("bounding box:", r.MinX, r.MinY, r.MaxX, r.MaxY)
+	}
+	for _, c := range []struct {
+		lng, lat float64
+		want     bool
+	}{
+		{3, 3, true},
+		{0, 2, true},      // on the outer ring
+		{1.5, 1.5, false}, // in the hole
+		{1, 1.5, true},    // on the ring of the hole, a border too
+		{7, 7, true},
+		{7.5, 8.5, false}, // in the bounding box only
+		{9, 9, false},
+	} {
+		if got := r.Contains(c.lng, c.lat); got != c.want {
+			t.Errorf("%v, %v: %v", c.lng, c.lat, got)
+		}
+	}
+
+	polygons, err = ParseGeometry([]byte(`{"type":"Polygon","coordinates":[[[10,0],[20,0],[15,10],[10,0]]]}`))
+	if err != nil || len(polygons) != 1 || !polygons[0].Contains(15, 5) || polygons[0].Contains(11, 9) {
+		t.Error(polygons, err)
+	}
+	for _, geometry := range []string{"", "null", `{"type":"Point","coordinates":[1,2]}`} {
+		if polygons, err := ParseGeometry([]byte(geometry)); err != nil || polygons != nil {
+			t.Errorf("%q: %v %v", geometry, polygons, err)
+		}
+	}
+	if _, err := ParseGeometry([]byte(`{"type":"Polygon","coordinates":[1,2]}`)); err == nil {
+		t.Error("bad coordinates parsed")
+	}
+}
@@ -0,0 +1,42 @@
+package nested
+
+import "fmt"
+
+// SetBoundary attaches the polygons of the boundary of node id, which Locate finds points in. Nil polygons
+// remove the boundary.
+func (t *Tree) SetBoundary(id int64, polygons []Polygon) error {
+	if t.nodes[id] == nil {
+		return fmt.Errorf("id %d does not exist", id)
+	}
+	if t.boundaries == nil {
+		t.boundaries = make(map[int64]*Region)
+	}
+	if polygons == nil {
+		delete(t.boundaries, id)
+		return nil
+	}
+	t.boundaries[id] = NewRegion(polygons)
+	return nil
+}
+
+// Locate returns the nodes containing a GPS point of longitude lng and latitude lat, in the order of GeoJSON,
+// from a root down to the deepest one, e.g. a province, a city and a district, or nil if no node does. Only the
+// children of a node containing the point are searched, the first one containing it is taken on borders. A
+// node without boundary, such as a 市辖区 placeholder, contains the point when one of its descendants does.
+func (t *Tree) Locate(lng, lat float64) []*TreeNode {
+	return t.locate(t.Roots, lng, lat)
+}
+
+func (t *Tree) locate(nodes []*TreeNode, lng, lat float64) []*TreeNode {
+	for _, n := range nodes {
+		r, ok := t.boundaries[n.ID]
+		if ok && !r.Contains(lng, lat) {
+			continue
+		}
+		below := t.locate(n.Children, lng, lat)
+		if ok || len(below) > 0 {
+			return append([]*TreeNode{n}, below...)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package nested
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLocate(t *testing.T) {
+	tree, err := Build([]Record{
+		{110000, "北京市", 0}, {110100, "市辖区", 110000}, {110101, "东城区", 110100}, {110102, "西城区", 110100},
+		{130000, "河北省", 0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, geometry := range map[int64]string{
+		110000: `{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,10],[0,0]]]}`,
+		// a hole of 110102 inside
+		110101: `{"type":"Polygon","coordinates":[[[0,0],[5,0],[5,5],[0,5],[0,0]],[[1,1],[2,1],[2,2],[1,2],[1,1]]]}`,
+		110102: `{"type":"MultiPolygon","coordinates":[[[[1,1],[2,1],[2,2],[1,2],[1,1]]],[[[5,0],[10,0],[10,5],[5,5],[5,0]]]]}`,
+		130000: `{"type":"Polygon","coordinates":[[[10,0],[20,0],[20,10],[10,10],[10,0]]]}`,
+	} {
+		polygons, err := ParseGeometry([]byte(geometry))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tree.SetBoundary(id, polygons); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, c := range []struct {
+		lng, lat float64
+		want     string
+	}{
+		{3, 3, "北京市 市辖区 东城区"},
+		{1.5, 1.5, "北京市 市辖区 西城区"},
+		{8, 2, "北京市 市辖区 西城区"},
+		{8, 8, "北京市"},
+		{15, 5, "河北省"},
+		{10, 5, "北京市 市辖区 西城区"}, // on the border of 北京市 and 河北省
+		{30, 5, ""},
+	} {
+		var names []string
+		for _, n := range tree.Locate(c.lng, c.lat) {
+			names = append(names, n.Node)
+		}
+		if got := strings.Join(names, " "); got != c.want {
+			t.Errorf("%v, %v: %q, want %q", c.lng, c.lat, got, c.want)
+		}
+	}
+
+	if err := tree.SetBoundary(110000, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := tree.Locate(8, 8); got != nil {
+		t.Error("located without the boundary:", got[0].Node)
+	}
+	if err := tree.SetBoundary(999, nil); err == nil {
+		t.Error("boundary of a missing node")
+	}
+}
@@ -0,0 +1,95 @@
+package nested
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Position is where a node is in the table, as read from its row
+type Position struct {
+	ID    int64
+	Depth int32
+	Left  int32
+	Right int32
+}
+
+// GetPosition reads the position of a node, to generate the statements changing the table around it. It returns
+// nil if the node does not exist.
+func GetPosition(db *sql.DB, id int64) (*Position, error) {
+	rows, err := query(db, "SELECT id, depth, lft, rgt FROM "+tblName+" WHERE id=?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) < 1 {
+		return nil, nil
+	}
+	r := rows[0]
+	return &Position{ID: atoi64(r["id"]), Depth: atoi(r["depth"]), Left: atoi(r["lft"]), Right: atoi(r["rgt"])}, nil
+}
+
+// The statements below are MySQL, with values written out, so that changes such as the yearly updates of the
+// divisions are reviewed and applied as a script, in a transaction, without a full reload. Positions must be
+// read right before, each change moves the keys of nodes on its right.
+
+// AddNodeSQL returns the statements adding a node as the last child of parent: keys from the right key of the
+// parent on move right by 2, and the node takes the two keys freed.
+func AddNodeSQL(id int64, name string, parent Position) []string {
+	p := parent.Right
+	return []string{
+		fmt.Sprintf("UPDATE %s SET lft=CASE WHEN lft>=%d THEN lft+2 ELSE lft END, rgt=rgt+2 WHERE rgt>=%d;", tblName, p, p),
+		fmt.Sprintf("INSERT INTO %s(id, node, pid, depth, lft, rgt) VALUES(%d, %s, %d, %d, %d, %d);", tblName, id,
+			quoteString(name), parent.ID, parent.Depth+1, p, p+1),
+	}
+}
+
+// RemoveSubtreeSQL returns the statements removing node and its descendants and moving the keys on their right
+// left by the width of the subtree
+func RemoveSubtreeSQL(node Position) []string {
+	l, r := node.Left, node.Right
+	width := r - l + 1
+	return []string{
+		fmt.Sprintf("DELETE FROM %s WHERE lft BETWEEN %d AND %d;", tblName, l, r),
+		fmt.Sprintf("UPDATE %s SET lft=CASE WHEN lft>%d THEN lft-%d ELSE lft END, rgt=rgt-%d WHERE rgt>%d;", tblName, r,
+			width, width, r),
+	}
+}
+
+// MoveSubtreeSQL returns the statements moving node and its descendants to the end of the children of parent.
+// Keys between the old and the new place shift by the width of the subtree the other way, nodes outside them
+// keep theirs. Depth is set first as MySQL sees values set before in the same statement.
+func MoveSubtreeSQL(node, parent Position) ([]string, error) {
+	l, r, p := node.Left, node.Right, parent.Right
+	if parent.Left >= l && parent.Right <= r {
+		return nil, errors.New("nested: moving node into its own subtree")
+	}
+	width := r - l + 1
+	// keys in [lo, hi] change, the subtree by delta and the others by -width or width
+	lo, hi, delta, others := l, p-1, p-1-r, -width
+	if p < l {
+		lo, hi, delta, others = p, r, p-l, width
+	}
+	shift := func(col string) string {
+		return fmt.Sprintf("%s=CASE WHEN %s BETWEEN %d AND %d THEN %s%s WHEN %s BETWEEN %d AND %d THEN %s%s ELSE %s END",
+			col, col, l, r, col, signed(delta), col, lo, hi, col, signed(others), col)
+	}
+	return []string{
+		fmt.Sprintf("UPDATE %s SET pid=%d WHERE id=%d;", tblName, parent.ID, node.ID),
+		fmt.Sprintf("UPDATE %s SET depth=CASE WHEN lft BETWEEN %d AND %d THEN depth%s ELSE depth END, %s, %s WHERE rgt>=%d AND lft<=%d;",
+			tblName, l, r, signed(parent.Depth+1-node.Depth), shift("lft"), shift("rgt"), lo, hi),
+	}, nil
+}
+
+// signed formats a delta to add, e.g. +2, -2 or +0
+func signed(delta int32) string {
+	if delta < 0 {
+		return itoa(delta)
+	}
+	return "+" + itoa(delta)
+}
+
+// quoteString quotes text for MySQL, with quotes doubled and backslashes escaped
+func quoteString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `''`).Replace(s) + "'"
+}
@@ -0,0 +1,56 @@
+package nested
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMaintenanceSQL(t *testing.T) {
+	defer SetTableName(tblName)
+	SetTableName("nested")
+
+	// the tree of the wikipedia article: Clothing [1, 22] with Women's [2, 13] and Men's [14, 21] holding Suits [15, 20]
+	clothing, women := Position{1, 1, 1, 22}, Position{2, 2, 2, 13}
+	suits := Position{4, 3, 15, 20}
+
+	got := strings.Join(AddNodeSQL(12, "Men's Shoes", Position{3, 2, 14, 21}), "\n")
+	want := "UPDATE nested SET lft=CASE WHEN lft>=21 THEN lft+2 ELSE lft END, rgt=rgt+2 WHERE rgt>=21;\n" +
+		"INSERT INTO nested(id, node, pid, depth, lft, rgt) VALUES(12, 'Men''s Shoes', 3, 3, 21, 22);"
+	if got != want {
+		t.Error(got)
+	}
+
+	got = strings.Join(RemoveSubtreeSQL(suits), "\n")
+	want = "DELETE FROM nested WHERE lft BETWEEN 15 AND 20;\n" +
+		"UPDATE nested SET lft=CASE WHEN lft>20 THEN lft-6 ELSE lft END, rgt=rgt-6 WHERE rgt>20;"
+	if got != want {
+		t.Error(got)
+	}
+
+	// Suits moves left to the end of Women's, whose right key and the left key of Men's shift right by its width
+	stmts, err := MoveSubtreeSQL(suits, women)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "UPDATE nested SET pid=2 WHERE id=4;\n" +
+		"UPDATE nested SET depth=CASE WHEN lft BETWEEN 15 AND 20 THEN depth+0 ELSE depth END, " +
+		"lft=CASE WHEN lft BETWEEN 15 AND 20 THEN lft-2 WHEN lft BETWEEN 13 AND 20 THEN lft+6 ELSE lft END, " +
+		"rgt=CASE WHEN rgt BETWEEN 15 AND 20 THEN rgt-2 WHEN rgt BETWEEN 13 AND 20 THEN rgt+6 ELSE rgt END " +
+		"WHERE rgt>=13 AND lft<=20;"
+	if got = strings.Join(stmts, "\n"); got != want {
+		t.Error(got)
+	}
+
+	// and right up to the top, after Men's
+	stmts, err = MoveSubtreeSQL(suits, clothing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got = stmts[1]; !strings.Contains(got, "THEN depth-1 ") || !strings.Contains(got, "THEN lft+1 WHEN lft BETWEEN 15 AND 21 THEN lft-6 ") {
+		t.Error(got)
+	}
+
+	if _, err := MoveSubtreeSQL(women, Position{7, 3, 3, 8}); err == nil {
+		t.Error("moved into its own subtree")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
 )
 
 var (
@@ -64,6 +66,53 @@ func GetNodeDetail(db *sql.DB, id int64) (*Node, error) {
 	return node, nil
 }
 
+// GetNodeColumns returns values of columns of node by name, e.g. short_name as generated with -columns of
+// division, "" for NULL. Names must be plain lowercase identifiers, which are written into the query as they
+// are. It returns nil if the node does not exist.
+func GetNodeColumns(db *sql.DB, id int64, columns ...string) (map[string]string, error) {
+	var names []string
+	seen := make(map[string]bool, len(columns))
+	for _, c := range columns {
+		if !isColumnName(c) {
+			return nil, fmt.Errorf("nested: bad column name %q", c)
+		}
+		if !seen[c] {
+			seen[c] = true
+			names = append(names, c)
+		}
+	}
+	selected := names
+	if len(selected) == 0 {
+		// the row tells whether the node exists
+		selected = []string{"id"}
+	}
+	rows, err := query(db, "SELECT "+strings.Join(selected, ", ")+" FROM "+tblName+" WHERE id=?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) < 1 {
+		return nil, nil
+	}
+	values := make(map[string]string, len(names))
+	for _, c := range names {
+		values[c] = rows[0][c]
+	}
+	return values, nil
+}
+
+// isColumnName tells whether s is a lowercase identifier of at most 64 characters, the limit of MySQL
+func isColumnName(s string) bool {
+	if s == "" || len(s) > 64 {
+		return false
+	}
+	for i, c := range s {
+		if !(c == '_' || 'a' <= c && c <= 'z' || i > 0 && '0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
 // GetChildren returns all immediate children of node
 func GetChildren(db *sql.DB, id int64) ([]Node, error) {
 	var sql bytes.Buffer
@@ -1,3 +1,8 @@
+//go:build db
+
+// The tests on the database need a table of createtable.sql and its driver registered as driverName, run them with
+// go test -tags db.
+
 package nested
 
 import (
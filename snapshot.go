@@ -0,0 +1,88 @@
+package nested
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+)
+
+// snapshotVersion is the version of the snapshot format, snapshots of other versions are not loaded
+const snapshotVersion = 1
+
+// snapshot is a tree as SaveSnapshot encodes it: the columns of the nodes in preorder, which gob writes as
+// plain slices much faster than the nested nodes, and the boundaries
+type snapshot struct {
+	Version    int
+	IDs        []int64
+	Nodes      []string
+	ParentIDs  []int64
+	Depths     []int32
+	Lefts      []int32
+	Rights     []int32
+	Boundaries map[int64][]Polygon
+}
+
+// SaveSnapshot writes the tree to w with encoding/gob, nodes with their keys and the boundaries, for services to
+// load it with LoadSnapshot at startup instead of building it again
+func (t *Tree) SaveSnapshot(w io.Writer) error {
+	s := snapshot{Version: snapshotVersion}
+	for _, n := range t.order {
+		s.IDs = append(s.IDs, n.ID)
+		s.Nodes = append(s.Nodes, n.Node)
+		s.ParentIDs = append(s.ParentIDs, n.ParentID)
+		s.Depths = append(s.Depths, n.Depth)
+		s.Lefts = append(s.Lefts, n.Left)
+		s.Rights = append(s.Rights, n.Right)
+	}
+	if len(t.boundaries) > 0 {
+		s.Boundaries = make(map[int64][]Polygon, len(t.boundaries))
+		for id, r := range t.boundaries {
+			s.Boundaries[id] = r.Polygons
+		}
+	}
+	return gob.NewEncoder(w).Encode(&s)
+}
+
+// LoadSnapshot reads a tree written by SaveSnapshot. The keys are those saved, which are not assigned again, and
+// children keep their order. Snapshots which are not of a tree, such as truncated ones, are errors.
+func LoadSnapshot(r io.Reader) (*Tree, error) {
+	var s snapshot
+	if err := gob.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("snapshot: %v", err)
+	}
+	if s.Version != snapshotVersion {
+		return nil, fmt.Errorf("snapshot: version %d, %d expected", s.Version, snapshotVersion)
+	}
+	count := len(s.IDs)
+	if len(s.Nodes) != count || len(s.ParentIDs) != count || len(s.Depths) != count || len(s.Lefts) != count ||
+		len(s.Rights) != count {
+		return nil, fmt.Errorf("snapshot: columns of different lengths")
+	}
+
+	t := &Tree{nodes: make(map[int64]*TreeNode, count), order: make([]*TreeNode, count)}
+	nodes := make([]TreeNode, count)
+	for i := range nodes {
+		n := &nodes[i]
+		*n = TreeNode{ID: s.IDs[i], Node: s.Nodes[i], ParentID: s.ParentIDs[i], Depth: s.Depths[i], Left: s.Lefts[i],
+			Right: s.Rights[i]}
+		if _, ok := t.nodes[n.ID]; ok {
+			return nil, fmt.Errorf("snapshot: duplicate id %d", n.ID)
+		}
+		// in preorder parents come before their children
+		if n.ParentID == 0 {
+			t.Roots = append(t.Roots, n)
+		} else if parent := t.nodes[n.ParentID]; parent != nil {
+			parent.Children = append(parent.Children, n)
+		} else {
+			return nil, fmt.Errorf("snapshot: id %d: parent %d not before it", n.ID, n.ParentID)
+		}
+		t.nodes[n.ID] = n
+		t.order[i] = n
+	}
+	for id, polygons := range s.Boundaries {
+		if err := t.SetBoundary(id, polygons); err != nil {
+			return nil, fmt.Errorf("snapshot: boundary: %v", err)
+		}
+	}
+	return t, nil
+}
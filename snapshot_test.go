@@ -0,0 +1,80 @@
+package nested
+
+import (
+	"bytes"
+	"encoding/gob"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	tree, err := Build([]Record{
+		{3, "Women's", 1}, {1, "Clothing", 0}, {2, "Men's", 1}, {4, "Suits", 2}, {5, "Slacks", 4}, {12, "Shoes", 0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// saved keys are kept, spaced ones too
+	roots := make([]Nester, len(tree.Roots))
+	for i, n := range tree.Roots {
+		roots[i] = n
+	}
+	AssignKeysSpaced(roots, 10)
+	square := Polygon{Ring{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}}
+	if err := tree.SetBoundary(1, []Polygon{square}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := tree.SaveSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	loaded, err := LoadSnapshot(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Roots, tree.Roots) {
+		t.Error("roots differ")
+	}
+	var got []string
+	loaded.Walk(func(n *TreeNode) error {
+		got = append(got, n.Node+" "+itoa(n.Depth)+" "+itoa(n.Left)+" "+itoa(n.Right))
+		return nil
+	})
+	want := "Clothing 1 10 100,Women's 2 20 30,Men's 2 40 90,Suits 3 50 80,Slacks 4 60 70,Shoes 1 110 120"
+	if strings.Join(got, ",") != want {
+		t.Error(got)
+	}
+	if n := loaded.Find(4); n == nil || n.ParentID != 2 || len(n.Children) != 1 {
+		t.Error(n)
+	}
+	if d := loaded.Descendants(1); len(d) != 4 || d[3].Node != "Slacks" {
+		t.Error(d)
+	}
+	if a := loaded.Ancestors(5); len(a) != 3 || a[0].ID != 1 {
+		t.Error(a)
+	}
+	if path := loaded.Locate(5, 5); len(path) != 1 || path[0].ID != 1 {
+		t.Error(path)
+	}
+
+	if _, err := LoadSnapshot(bytes.NewReader(data[:len(data)/2])); err == nil {
+		t.Error("truncated snapshot loaded")
+	}
+	for _, s := range []snapshot{
+		{Version: 2},
+		{Version: 1, IDs: []int64{1}},
+		{Version: 1, IDs: []int64{1, 2}, Nodes: []string{"a", "b"}, ParentIDs: []int64{2, 0}, Depths: []int32{2, 1},
+			Lefts: []int32{2, 1}, Rights: []int32{3, 4}},
+		{Version: 1, IDs: []int64{1}, Nodes: []string{"a"}, ParentIDs: []int64{0}, Depths: []int32{1}, Lefts: []int32{1},
+			Rights: []int32{2}, Boundaries: map[int64][]Polygon{9: {square}}},
+	} {
+		buf.Reset()
+		gob.NewEncoder(&buf).Encode(&s)
+		if _, err := LoadSnapshot(&buf); err == nil {
+			t.Errorf("%+v loaded", s)
+		}
+	}
+}
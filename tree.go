@@ -0,0 +1,180 @@
+package nested
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Record is a node to build a tree of, ParentID is 0 for roots
+type Record struct {
+	ID       int64
+	Node     string
+	ParentID int64
+}
+
+// TreeNode is a node of a tree built in memory, with the columns of its row in the table
+type TreeNode struct {
+	ID       int64
+	Node     string
+	ParentID int64
+	Depth    int32
+	Left     int32
+	Right    int32
+	Children []*TreeNode
+}
+
+// NumChildren implements Nester
+func (n *TreeNode) NumChildren() int {
+	return len(n.Children)
+}
+
+// Child implements Nester
+func (n *TreeNode) Child(i int) Nester {
+	return n.Children[i]
+}
+
+// SetKeys implements Nester
+func (n *TreeNode) SetKeys(left, right int32) {
+	n.Left, n.Right = left, right
+}
+
+// Tree is the trees built of records, roots and children in the order of the records
+type Tree struct {
+	Roots      []*TreeNode
+	nodes      map[int64]*TreeNode
+	order      []*TreeNode       // in preorder, which is the order of left keys
+	boundaries map[int64]*Region // of SetBoundary
+}
+
+// Build builds trees of records, in any order of parents and children, and assigns their keys. Records with a
+// duplicate ID or a parent which is not among them are errors, and so are records in a cycle of parents.
+func Build(records []Record) (*Tree, error) {
+	t := &Tree{nodes: make(map[int64]*TreeNode, len(records))}
+	for _, r := range records {
+		if _, ok := t.nodes[r.ID]; ok {
+			return nil, fmt.Errorf("duplicate id %d", r.ID)
+		}
+		t.nodes[r.ID] = &TreeNode{ID: r.ID, Node: r.Node, ParentID: r.ParentID}
+	}
+	for _, r := range records {
+		n := t.nodes[r.ID]
+		if r.ParentID == 0 {
+			t.Roots = append(t.Roots, n)
+			continue
+		}
+		parent, ok := t.nodes[r.ParentID]
+		if !ok {
+			return nil, fmt.Errorf("id %d: parent %d does not exist", r.ID, r.ParentID)
+		}
+		parent.Children = append(parent.Children, n)
+	}
+
+	// nodes in a cycle are not reached from the roots
+	t.order = make([]*TreeNode, 0, len(records))
+	t.Walk(func(n *TreeNode) error {
+		t.order = append(t.order, n)
+		return nil
+	})
+	if len(t.order) < len(records) {
+		return nil, fmt.Errorf("%d nodes are in cycles of parents", len(records)-len(t.order))
+	}
+
+	t.setDepth(t.Roots, 1)
+	roots := make([]Nester, len(t.Roots))
+	for i, n := range t.Roots {
+		roots[i] = n
+	}
+	AssignKeys(roots)
+	return t, nil
+}
+
+func (t *Tree) setDepth(nodes []*TreeNode, depth int32) {
+	for _, n := range nodes {
+		n.Depth = depth
+		t.setDepth(n.Children, depth+1)
+	}
+}
+
+// Find returns the node of id, or nil
+func (t *Tree) Find(id int64) *TreeNode {
+	return t.nodes[id]
+}
+
+// Ancestors returns the ancestors of node id from its root down to its parent, none for a root, or nil if the
+// node does not exist
+func (t *Tree) Ancestors(id int64) []*TreeNode {
+	n := t.nodes[id]
+	if n == nil {
+		return nil
+	}
+	ancestors := make([]*TreeNode, n.Depth-1)
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		n = t.nodes[n.ParentID]
+		ancestors[i] = n
+	}
+	return ancestors
+}
+
+// Descendants returns the descendants of node id in preorder, the nodes whose left keys are between the keys of
+// the node, or nil if the node does not exist. They are found by binary search on the left keys, the slice
+// returned is shared by calls and must not be modified.
+func (t *Tree) Descendants(id int64) []*TreeNode {
+	n := t.nodes[id]
+	if n == nil {
+		return nil
+	}
+	i := sort.Search(len(t.order), func(i int) bool { return t.order[i].Left > n.Left })
+	j := sort.Search(len(t.order), func(i int) bool { return t.order[i].Left > n.Right })
+	return t.order[i:j:j]
+}
+
+// Walk visits the nodes in preorder, which is the order of their left keys, and stops at the first error
+func (t *Tree) Walk(visit func(n *TreeNode) error) error {
+	var walk func(nodes []*TreeNode) error
+	walk = func(nodes []*TreeNode) error {
+		for _, n := range nodes {
+			if err := visit(n); err != nil {
+				return err
+			}
+			if err := walk(n.Children); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(t.Roots)
+}
+
+// Nester is a node of a tree kept in a type of its own, whose keys are assigned by AssignKeys
+type Nester interface {
+	NumChildren() int
+	Child(i int) Nester
+	SetKeys(left, right int32)
+}
+
+// AssignKeys numbers the nodes of the trees in a traversal, the left key of a node when entering it and the
+// right key when leaving it, from 1 for the first root on
+func AssignKeys(roots []Nester) {
+	AssignKeysSpaced(roots, 1)
+}
+
+// AssignKeysSpaced numbers the nodes like AssignKeys with keys step apart, from step for the first root on, e.g.
+// 10, 20, 30 for a step of 10. The step-1 keys free after each key take nodes inserted later, a leaf in two of
+// them, without renumbering the rest of the trees. A node of n descendants spans (2n+1)*step.
+func AssignKeysSpaced(roots []Nester, step int32) {
+	start := int32(0)
+	for _, r := range roots {
+		start = indexTree(r, start, step)
+	}
+}
+
+func indexTree(root Nester, start, step int32) int32 {
+	start += step
+	left := start
+	for i := 0; i < root.NumChildren(); i++ {
+		start = indexTree(root.Child(i), start, step)
+	}
+	start += step
+	root.SetKeys(left, start)
+	return start
+}
@@ -0,0 +1,111 @@
+package nested
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuild(t *testing.T) {
+	// the tree of the wikipedia article, children before their parents
+	tree, err := Build([]Record{
+		{3, "Women's", 1}, {1, "Clothing", 0}, {2, "Men's", 1}, {4, "Suits", 2},
+		{5, "Slacks", 4}, {6, "Jackets", 4}, {7, "Dresses", 3}, {8, "Skirts", 3}, {9, "Blouses", 3},
+		{10, "Evening Gowns", 7}, {11, "Sun Dresses", 7},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	tree.Walk(func(n *TreeNode) error {
+		got = append(got, strings.Repeat(" ", int(n.Depth-1))+n.Node+" "+itoa(n.Left)+" "+itoa(n.Right))
+		return nil
+	})
+	want := []string{"Clothing 1 22", " Women's 2 13", "  Dresses 3 8", "   Evening Gowns 4 5", "   Sun Dresses 6 7",
+		"  Skirts 9 10", "  Blouses 11 12", " Men's 14 21", "  Suits 15 20", "   Slacks 16 17", "   Jackets 18 19"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Error(strings.Join(got, "\n"))
+	}
+	if n := tree.Find(4); n == nil || n.ParentID != 2 || len(n.Children) != 2 {
+		t.Error(n)
+	}
+	if tree.Find(12) != nil {
+		t.Error("found 12")
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	for _, records := range [][]Record{
+		{{1, "a", 0}, {1, "b", 0}},
+		{{1, "a", 0}, {2, "b", 3}},
+		{{1, "a", 0}, {2, "b", 3}, {3, "c", 2}},
+	} {
+		if _, err := Build(records); err == nil {
+			t.Error(records, "built")
+		}
+	}
+}
+
+func TestAssignKeysSpaced(t *testing.T) {
+	tree, err := Build([]Record{{1, "a", 0}, {2, "b", 1}, {3, "c", 1}, {4, "d", 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := make([]Nester, len(tree.Roots))
+	for i, n := range tree.Roots {
+		roots[i] = n
+	}
+	AssignKeysSpaced(roots, 10)
+	var got []string
+	tree.Walk(func(n *TreeNode) error {
+		got = append(got, n.Node+" "+itoa(n.Left)+" "+itoa(n.Right))
+		return nil
+	})
+	if want := "a 10 60,b 20 30,c 40 50,d 70 80"; strings.Join(got, ",") != want {
+		t.Error(strings.Join(got, ","))
+	}
+}
+
+func TestAncestorsDescendants(t *testing.T) {
+	tree, err := Build([]Record{
+		{1, "Clothing", 0}, {2, "Men's", 1}, {3, "Women's", 1}, {4, "Suits", 2}, {5, "Slacks", 4}, {6, "Jackets", 4},
+		{7, "Dresses", 3}, {8, "Skirts", 3}, {10, "Evening Gowns", 7}, {11, "Sun Dresses", 7}, {12, "Hats", 0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := func(nodes []*TreeNode) string {
+		var s []string
+		for _, n := range nodes {
+			s = append(s, n.Node)
+		}
+		return strings.Join(s, ",")
+	}
+	check := func() {
+		for id, want := range map[int64][2]string{
+			1:  {"", "Men's,Suits,Slacks,Jackets,Women's,Dresses,Evening Gowns,Sun Dresses,Skirts"},
+			3:  {"Clothing", "Dresses,Evening Gowns,Sun Dresses,Skirts"},
+			5:  {"Clothing,Men's,Suits", ""},
+			11: {"Clothing,Women's,Dresses", ""},
+			12: {"", ""},
+		} {
+			if got := names(tree.Ancestors(id)); got != want[0] {
+				t.Errorf("ancestors of %d: %s", id, got)
+			}
+			if got := names(tree.Descendants(id)); got != want[1] {
+				t.Errorf("descendants of %d: %s", id, got)
+			}
+		}
+	}
+	check()
+	if tree.Ancestors(9) != nil || tree.Descendants(9) != nil {
+		t.Error("found a missing node")
+	}
+
+	// keys spaced apart keep the order
+	roots := make([]Nester, len(tree.Roots))
+	for i, n := range tree.Roots {
+		roots[i] = n
+	}
+	AssignKeysSpaced(roots, 10)
+	check()
+}